@@ -0,0 +1,340 @@
+package ansible
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"server-manager/internal/server_manager"
+)
+
+var (
+	ErrApprovalNotPending = errors.New("execution is not pending approval")
+	ErrNotApprover        = errors.New("approver role required")
+	ErrSelfApproval       = errors.New("execution cannot be approved or rejected by the user who requested it")
+)
+
+// approverRole 可以批准或拒绝执行的角色，管理员同样可以审批
+const approverRole = "approver"
+
+const (
+	// defaultApprovalTimeout 未配置时等待审批的期限
+	defaultApprovalTimeout = 24 * time.Hour
+	// approvalPollInterval 检查审批是否过期的间隔
+	approvalPollInterval = time.Minute
+)
+
+// SetApprovalTimeout 设置等待审批的期限，不大于0时使用默认值
+func (s *AnsibleService) SetApprovalTimeout(timeout time.Duration) {
+	s.approvalTimeout = timeout
+}
+
+// approvalDeadline 返回新提交审批的执行的审批期限，时间以UTC保存
+func (s *AnsibleService) approvalDeadline() *time.Time {
+	timeout := s.approvalTimeout
+	if timeout <= 0 {
+		timeout = defaultApprovalTimeout
+	}
+	deadline := time.Now().UTC().Add(timeout)
+	return &deadline
+}
+
+// protectedTargets 返回执行涉及的受保护对象：实际执行的受保护playbook或项目、inventory，以及目标主机所在的受保护服务器组。
+// adhoc执行时playbook为nil。目标主机按inventory中的主机名或ansible_host与服务器名称或地址对应；无法确定目标主机时视为涉及所有受保护服务器组
+func (s *AnsibleService) protectedTargets(inventory *resolvedInventory, hosts, limit string, playbook *PlaybookExecution) ([]string, error) {
+	var targets []string
+
+	if playbook != nil && playbook.ProjectID != nil {
+		var project Project
+		if err := s.db.Select("name", "protected").First(&project, *playbook.ProjectID).Error; err != nil {
+			return nil, fmt.Errorf("get project failed: %v", err)
+		}
+		if project.Protected {
+			targets = append(targets, ProtectedProject+":"+project.Name)
+		}
+	} else if playbook != nil && playbook.PlaybookID != 0 {
+		var saved Playbook
+		if err := s.db.Select("name", "protected").First(&saved, playbook.PlaybookID).Error; err != nil {
+			return nil, fmt.Errorf("get playbook failed: %v", err)
+		}
+		if saved.Protected {
+			targets = append(targets, ProtectedPlaybook+":"+saved.Name)
+		}
+	}
+
+	if inventory.ID != nil {
+		var saved Inventory
+		if err := s.db.Select("name", "protected").First(&saved, *inventory.ID).Error; err != nil {
+			return nil, fmt.Errorf("get inventory failed: %v", err)
+		}
+		if saved.Protected {
+			targets = append(targets, ProtectedInventory+":"+saved.Name)
+		}
+	}
+
+	var groups []server_manager.ServerGroup
+	if err := s.db.Preload("Servers").Where("protected = ?", true).Order("name ASC").Find(&groups).Error; err != nil {
+		return nil, fmt.Errorf("load protected server groups failed: %v", err)
+	}
+	if len(groups) == 0 {
+		return targets, nil
+	}
+
+	parsed, matched, err := s.targetHosts(inventory, hosts, limit)
+	if err != nil {
+		for _, group := range groups {
+			targets = append(targets, ProtectedServerGroup+":"+group.Name)
+		}
+		return targets, nil
+	}
+
+	// 目标主机的名称和连接地址
+	names := make(map[string]bool)
+	for _, host := range matched {
+		names[host] = true
+		if parsed.Hosts[host] == nil {
+			continue
+		}
+		if address, ok := parsed.Hosts[host].Vars["ansible_host"].(string); ok {
+			names[address] = true
+		}
+	}
+	for _, group := range groups {
+		for _, server := range group.Servers {
+			if names[inventoryHostName(server.Name)] || names[server.Name] || names[server.Host] {
+				targets = append(targets, ProtectedServerGroup+":"+group.Name)
+				break
+			}
+		}
+	}
+	return targets, nil
+}
+
+// recordApproval 在事务中保存一条审批记录
+func recordApproval(tx *gorm.DB, kind string, id uint, action string, userID *uint, comment string) error {
+	approval := &ExecutionApproval{
+		ExecutionType: kind,
+		ExecutionID:   id,
+		Action:        action,
+		UserID:        userID,
+		Comment:       comment,
+	}
+	if err := tx.Create(approval).Error; err != nil {
+		return fmt.Errorf("create approval record failed: %v", err)
+	}
+	return nil
+}
+
+// checkApprover 检查用户是否可以审批执行
+func (s *AnsibleService) checkApprover(userID uint) error {
+	role, err := s.userRole(userID)
+	if err != nil {
+		return err
+	}
+	if role != approverRole && role != adminRole {
+		return ErrNotApprover
+	}
+	return nil
+}
+
+// ListPendingApprovals 按提交时间列出等待审批的执行，只有审批人可以查看
+func (s *AnsibleService) ListPendingApprovals(userID uint, offset, limit int) ([]ExecutionSummary, int64, error) {
+	if err := s.checkApprover(userID); err != nil {
+		return nil, 0, err
+	}
+	return s.ListExecutions(&ExecutionFilter{Statuses: []string{StatusPendingApproval}}, offset, limit)
+}
+
+// ApproveExecution 批准等待审批的执行，执行进入队列；审批人不能是提交执行的用户
+func (s *AnsibleService) ApproveExecution(kind string, id uint, userID uint, comment string) error {
+	if err := s.checkApprover(userID); err != nil {
+		return err
+	}
+	return s.closeApproval(kind, id, &userID, ApprovalApproved, comment)
+}
+
+// RejectExecution 拒绝等待审批的执行，执行不会运行；审批人不能是提交执行的用户
+func (s *AnsibleService) RejectExecution(kind string, id uint, userID uint, comment string) error {
+	if err := s.checkApprover(userID); err != nil {
+		return err
+	}
+	return s.closeApproval(kind, id, &userID, ApprovalRejected, comment)
+}
+
+// closeApproval 结束执行的审批：批准时放行队列任务，拒绝、过期或取消时结束执行并完成队列任务
+func (s *AnsibleService) closeApproval(kind string, id uint, userID *uint, action, comment string) error {
+	model, err := executionModel(kind)
+	if err != nil {
+		return err
+	}
+
+	var record struct {
+		Status            string
+		UserID            uint
+		ApprovalExpiresAt *time.Time
+	}
+	if err := s.db.Model(model).Where("id = ?", id).Take(&record).Error; err != nil {
+		return err
+	}
+	if record.Status != StatusPendingApproval {
+		return ErrApprovalNotPending
+	}
+	if (action == ApprovalApproved || action == ApprovalRejected) && userID != nil && *userID == record.UserID {
+		return ErrSelfApproval
+	}
+
+	now := time.Now()
+	if action == ApprovalApproved && record.ApprovalExpiresAt != nil && !now.Before(*record.ApprovalExpiresAt) {
+		// 已过期但尚未被清理，不能再批准
+		if err := s.closeApproval(kind, id, nil, ApprovalExpired, "approval period expired"); err != nil && !errors.Is(err, ErrApprovalNotPending) {
+			return err
+		}
+		return ErrApprovalNotPending
+	}
+
+	status := StatusRejected
+	updates := map[string]interface{}{"approval_status": action}
+	jobUpdates := map[string]interface{}{
+		"status":      JobStatusDone,
+		"finished_at": &now,
+		"payload":     "",
+	}
+	switch action {
+	case ApprovalApproved:
+		status = StatusPending
+		jobUpdates = map[string]interface{}{"status": JobStatusQueued}
+	case ApprovalCancelled:
+		status = StatusCancelled
+	}
+	updates["status"] = status
+	if status != StatusPending {
+		updates["end_time"] = &now
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 条件更新保证并发的审批只有一个生效
+		result := tx.Model(model).Where("id = ? AND status = ?", id, StatusPendingApproval).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrApprovalNotPending
+		}
+
+		if err := tx.Model(&ExecutionJob{}).Where("execution_type = ? AND execution_id = ? AND status = ?", kind, id, JobStatusHeld).
+			Updates(jobUpdates).Error; err != nil {
+			return fmt.Errorf("update execution job failed: %v", err)
+		}
+		return recordApproval(tx, kind, id, action, userID, comment)
+	})
+	if err != nil {
+		return err
+	}
+
+	if status == StatusPending {
+		s.notifyWorkers()
+	} else {
+		s.outputs.Close(outputKey(kind, id), status, 0)
+		s.notifyWorkflows()
+	}
+	return nil
+}
+
+// approvalExpirer 定期拒绝超过审批期限的执行
+func (s *AnsibleService) approvalExpirer() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(approvalPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.expireApprovals(time.Now())
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expireApprovals 将超过审批期限的执行标记为已拒绝
+func (s *AnsibleService) expireApprovals(now time.Time) {
+	kinds := map[string]interface{}{
+		ExecutionTypeAdhoc:    &AdhocExecution{},
+		ExecutionTypePlaybook: &PlaybookExecution{},
+	}
+	for kind, model := range kinds {
+		var ids []uint
+		// 时间以UTC保存，sqlite按字符串比较时间
+		err := s.db.Model(model).Where("status = ? AND approval_expires_at <= ?", StatusPendingApproval, now.UTC()).Pluck("id", &ids).Error
+		if err != nil {
+			log.Printf("Load expired approvals failed: %v", err)
+			continue
+		}
+
+		for _, id := range ids {
+			err := s.closeApproval(kind, id, nil, ApprovalExpired, "approval period expired")
+			if err != nil && !errors.Is(err, ErrApprovalNotPending) {
+				log.Printf("Expire approval of %s execution %d failed: %v", kind, id, err)
+			}
+		}
+	}
+}
+
+// SetProtectedTarget 设置服务器组、inventory、playbook或项目是否受保护
+func (s *AnsibleService) SetProtectedTarget(req *ProtectedTargetRequest) error {
+	var model interface{}
+	switch req.Type {
+	case ProtectedServerGroup:
+		model = &server_manager.ServerGroup{}
+	case ProtectedInventory:
+		model = &Inventory{}
+	case ProtectedPlaybook:
+		model = &Playbook{}
+	case ProtectedProject:
+		model = &Project{}
+	default:
+		return fmt.Errorf("unsupported protected target type: %s", req.Type)
+	}
+
+	result := s.db.Model(model).Where("id = ?", req.ID).Update("protected", req.Protected)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ListProtectedTargets 列出所有受保护的服务器组、inventory、playbook和项目
+func (s *AnsibleService) ListProtectedTargets() ([]ProtectedTarget, error) {
+	targets := []ProtectedTarget{}
+	tables := []struct {
+		kind  string
+		model interface{}
+	}{
+		{ProtectedServerGroup, &server_manager.ServerGroup{}},
+		{ProtectedInventory, &Inventory{}},
+		{ProtectedPlaybook, &Playbook{}},
+		{ProtectedProject, &Project{}},
+	}
+	for _, table := range tables {
+		var rows []ProtectedTarget
+		err := s.db.Model(table.model).Select("id", "name").Where("protected = ?", true).Order("name ASC").Find(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			row.Type = table.kind
+			targets = append(targets, row)
+		}
+	}
+	return targets, nil
+}
+
@@ -0,0 +1,121 @@
+# -*- coding: utf-8 -*-
+# server-manager结构化结果回调插件：将每个主机的任务结果、文件差异和汇总统计以JSON行写入事件文件，
+# 事件文件路径由环境变量SERVER_MANAGER_EVENTS_FILE指定，标准输出保持ansible默认格式。
+from __future__ import absolute_import, division, print_function
+__metaclass__ = type
+
+DOCUMENTATION = '''
+    name: server_manager_events
+    type: notification
+    short_description: write per-host task results as JSON lines for server-manager
+    description:
+      - Appends one JSON object per task result, per diff (in diff mode) and
+        per host recap to the file named by the SERVER_MANAGER_EVENTS_FILE
+        environment variable.
+      - Data aggregated with set_stats is written as a single artifacts event.
+    requirements:
+      - enable in configuration
+'''
+
+import json
+import os
+
+from ansible.plugins.callback import CallbackBase
+
+
+def _text(value):
+    if value is None:
+        return ''
+    if isinstance(value, str):
+        return value
+    return json.dumps(value, default=str)
+
+
+class CallbackModule(CallbackBase):
+    CALLBACK_VERSION = 2.0
+    CALLBACK_TYPE = 'notification'
+    CALLBACK_NAME = 'server_manager_events'
+    CALLBACK_NEEDS_ENABLED = True
+
+    def __init__(self, *args, **kwargs):
+        super(CallbackModule, self).__init__(*args, **kwargs)
+        self._path = os.environ.get('SERVER_MANAGER_EVENTS_FILE')
+
+    def _write(self, event):
+        if not self._path:
+            return
+        with open(self._path, 'a') as f:
+            f.write(json.dumps(event, default=str) + '\n')
+
+    def _result(self, status, result):
+        res = result._result
+        rc = res.get('rc')
+        self._write({
+            'event': 'result',
+            'host': result._host.get_name(),
+            'task': result._task.get_name(),
+            'status': status,
+            'changed': bool(res.get('changed', False)),
+            'rc': rc if isinstance(rc, int) else None,
+            'stdout': _text(res.get('stdout')),
+            'stderr': _text(res.get('stderr')),
+            'msg': _text(res.get('msg')),
+        })
+
+    def v2_runner_on_ok(self, result):
+        self._result('ok', result)
+
+    def v2_runner_on_failed(self, result, ignore_errors=False):
+        self._result('ignored' if ignore_errors else 'failed', result)
+
+    def v2_runner_on_unreachable(self, result):
+        self._result('unreachable', result)
+
+    def v2_runner_on_skipped(self, result):
+        self._result('skipped', result)
+
+    def v2_on_file_diff(self, result):
+        res = result._result
+        diffs = []
+        if 'results' in res:
+            for item in res['results']:
+                if isinstance(item, dict) and item.get('diff'):
+                    diffs.append(item['diff'])
+        elif res.get('diff'):
+            diffs.append(res['diff'])
+
+        for diff in diffs:
+            for d in diff if isinstance(diff, list) else [diff]:
+                if not isinstance(d, dict):
+                    continue
+                self._write({
+                    'event': 'diff',
+                    'host': result._host.get_name(),
+                    'task': result._task.get_name(),
+                    'before_header': _text(d.get('before_header')),
+                    'after_header': _text(d.get('after_header')),
+                    'before': _text(d.get('before')),
+                    'after': _text(d.get('after')),
+                    'prepared': _text(d.get('prepared')),
+                })
+
+    def v2_playbook_on_stats(self, stats):
+        for host in sorted(stats.processed.keys()):
+            summary = stats.summarize(host)
+            self._write({
+                'event': 'stats',
+                'host': host,
+                'ok': summary.get('ok', 0),
+                'changed': summary.get('changed', 0),
+                'failures': summary.get('failures', 0),
+                'unreachable': summary.get('unreachable', 0),
+                'skipped': summary.get('skipped', 0),
+                'rescued': summary.get('rescued', 0),
+                'ignored': summary.get('ignored', 0),
+            })
+
+        # set_stats(aggregate=yes)的数据，作为执行的产物传给工作流的后续节点
+        custom = getattr(stats, 'custom', None) or {}
+        artifacts = custom.get('_run')
+        if isinstance(artifacts, dict) and artifacts:
+            self._write({'event': 'artifacts', 'data': artifacts})
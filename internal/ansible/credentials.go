@@ -0,0 +1,180 @@
+package ansible
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// credentialsVarsFile 写入每个主机host_vars目录的连接凭据文件名
+const credentialsVarsFile = "server_manager_credentials.json"
+
+// HostCredential 受管服务器的连接凭据，只在执行期间以临时文件形式存在
+type HostCredential struct {
+	Password   string
+	PrivateKey string
+}
+
+// prepareRunDir 为单次执行创建仅当前用户可访问的临时目录
+func (e *DefaultCommandExecutor) prepareRunDir() (string, error) {
+	runDir, err := os.MkdirTemp(e.tempDir, "run_")
+	if err != nil {
+		return "", fmt.Errorf("create run dir failed: %v", err)
+	}
+	if err := os.Chmod(runDir, 0700); err != nil {
+		os.RemoveAll(runDir)
+		return "", fmt.Errorf("chmod run dir failed: %v", err)
+	}
+	
+	// host_vars中的私钥路径需要与ansible的工作目录无关
+	if abs, err := filepath.Abs(runDir); err == nil {
+		runDir = abs
+	}
+	return runDir, nil
+}
+
+// prepareCredentials 将主机凭据写入inventory旁的host_vars目录，私钥文件权限为0600
+func (e *DefaultCommandExecutor) prepareCredentials(runDir string, credentials map[string]HostCredential) error {
+	if len(credentials) == 0 {
+		return nil
+	}
+	
+	keyDir := filepath.Join(runDir, "keys")
+	if err := os.MkdirAll(keyDir, 0700); err != nil {
+		return fmt.Errorf("create key dir failed: %v", err)
+	}
+	
+	for host, credential := range credentials {
+		vars := make(map[string]string)
+	
+		if credential.PrivateKey != "" {
+			keyFile := filepath.Join(keyDir, host)
+			key := credential.PrivateKey
+			// ssh要求私钥以换行结尾
+			if !strings.HasSuffix(key, "\n") {
+				key += "\n"
+			}
+			if err := os.WriteFile(keyFile, []byte(key), 0600); err != nil {
+				return fmt.Errorf("write private key for %s failed: %v", host, err)
+			}
+			vars["ansible_ssh_private_key_file"] = keyFile
+		} else if credential.Password != "" {
+			// 密码只写入权限为0600的变量文件，不出现在命令行和inventory中
+			vars["ansible_password"] = credential.Password
+		}
+	
+		if len(vars) == 0 {
+			continue
+		}
+	
+		varsDir := filepath.Join(runDir, "host_vars", host)
+		if err := os.MkdirAll(varsDir, 0700); err != nil {
+			return fmt.Errorf("create host vars dir for %s failed: %v", host, err)
+		}
+	
+		data, err := json.Marshal(vars)
+		if err != nil {
+			return fmt.Errorf("marshal credentials for %s failed: %v", host, err)
+		}
+		if err := os.WriteFile(filepath.Join(varsDir, credentialsVarsFile), data, 0600); err != nil {
+			return fmt.Errorf("write credentials for %s failed: %v", host, err)
+		}
+	}
+	
+	return nil
+}
+
+// cleanupRunDir 用零覆盖执行目录中的文件后删除目录，避免凭据残留在磁盘上
+func (e *DefaultCommandExecutor) cleanupRunDir(runDir string) {
+	filepath.Walk(runDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		if err := os.WriteFile(path, make([]byte, info.Size()), 0600); err != nil {
+			log.Printf("Warning: wipe %s failed: %v", path, err)
+		}
+		return nil
+	})
+	
+	if err := os.RemoveAll(runDir); err != nil {
+		log.Printf("Warning: remove run dir failed: %v", err)
+	}
+}
+
+// prepareVaultPasswords 将vault密码写入执行目录中权限为0600的临时文件，返回--vault-id参数；
+// 文件随执行目录在执行结束后立即被覆盖并删除
+func (e *DefaultCommandExecutor) prepareVaultPasswords(runDir string, opts *RunOptions) ([]string, error) {
+	if opts == nil || len(opts.VaultPasswords) == 0 {
+		return nil, nil
+	}
+	
+	vaultDir := filepath.Join(runDir, "vault")
+	if err := os.MkdirAll(vaultDir, 0700); err != nil {
+		return nil, fmt.Errorf("create vault dir failed: %v", err)
+	}
+	
+	names := make([]string, 0, len(opts.VaultPasswords))
+	for name := range opts.VaultPasswords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	
+	var args []string
+	for _, name := range names {
+		// 不能有执行权限，否则ansible会把密码文件当作脚本执行
+		passwordFile := filepath.Join(vaultDir, name)
+		if err := os.WriteFile(passwordFile, []byte(opts.VaultPasswords[name]), 0600); err != nil {
+			return nil, fmt.Errorf("write vault password file failed: %v", err)
+		}
+		args = append(args, "--vault-id", name+"@"+passwordFile)
+	}
+	return args, nil
+}
+
+// runSecrets 返回本次执行中需要屏蔽的值，包括vault密码和保密的额外变量
+func runSecrets(opts *RunOptions) []string {
+	if opts == nil {
+		return nil
+	}
+	secrets := append([]string{}, opts.Secrets...)
+	for _, password := range opts.VaultPasswords {
+		secrets = append(secrets, password)
+	}
+	return secrets
+}
+
+// secretMasker 返回将输出中的密码替换为掩码的函数
+func secretMasker(secrets []string) func(string) string {
+	var replacements []string
+	for _, secret := range secrets {
+		if secret != "" {
+			replacements = append(replacements, secret, "********")
+		}
+	}
+	if len(replacements) == 0 {
+		return func(line string) string { return line }
+	}
+	
+	replacer := strings.NewReplacer(replacements...)
+	return replacer.Replace
+}
+
+// maskEvents 屏蔽结构化结果中的密码，结果会保存到数据库
+func maskEvents(events *callbackEvents, mask func(string) string) {
+	for i := range events.HostResults {
+		result := &events.HostResults[i]
+		result.Stdout = mask(result.Stdout)
+		result.Stderr = mask(result.Stderr)
+		result.Msg = mask(result.Msg)
+	}
+	for i := range events.Diffs {
+		diff := &events.Diffs[i]
+		diff.Before = mask(diff.Before)
+		diff.After = mask(diff.After)
+		diff.Prepared = mask(diff.Prepared)
+	}
+}
@@ -0,0 +1,240 @@
+package ansible
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"server-manager/internal/server_manager"
+)
+
+const (
+	// tagGroupPrefix 由服务器标签生成的组名前缀，避免与服务器组重名
+	tagGroupPrefix = "tag_"
+	// ungroupedGroup 不属于任何组的主机所在的组
+	ungroupedGroup = "ungrouped"
+)
+
+// DynamicInventory 根据受管服务器生成的inventory
+type DynamicInventory struct {
+	Hosts    []string                          // 主机名，按名称排序
+	HostVars map[string]map[string]interface{} // 主机变量
+	Groups   map[string][]string               // 组名到主机名的映射
+	
+	credentials map[string]HostCredential // 主机连接凭据，不包含在inventory内容中
+}
+
+// parseDynamicFilter 解析dynamic类型inventory内容中保存的过滤条件，内容为空表示全部服务器
+func parseDynamicFilter(content string) (*DynamicInventoryFilter, error) {
+	filter := &DynamicInventoryFilter{}
+	if strings.TrimSpace(content) == "" {
+		return filter, nil
+	}
+	
+	if err := json.Unmarshal([]byte(content), filter); err != nil {
+		return nil, fmt.Errorf("%w: dynamic inventory content must be a JSON filter: %v", ErrInvalidInventory, err)
+	}
+	return filter, nil
+}
+
+// BuildDynamicInventory 根据服务器和服务器组生成inventory，服务器组和标签都会成为ansible组
+func (s *AnsibleService) BuildDynamicInventory(filter *DynamicInventoryFilter) (*DynamicInventory, error) {
+	var servers []server_manager.Server
+	if err := s.db.Preload("Group").Order("name ASC").Find(&servers).Error; err != nil {
+		return nil, fmt.Errorf("load servers failed: %v", err)
+	}
+	
+	inventory := &DynamicInventory{
+		HostVars: make(map[string]map[string]interface{}),
+		Groups:   make(map[string][]string),
+		
+		credentials: make(map[string]HostCredential),
+	}
+	
+	for _, server := range servers {
+		groupName := ""
+		if server.Group != nil {
+			groupName = server.Group.Name
+		}
+		tags := splitTags(server.Tags)
+	
+		if filter != nil && !filter.matches(groupName, tags) {
+			continue
+		}
+	
+		host := inventoryHostName(server.Name)
+		inventory.Hosts = append(inventory.Hosts, host)
+	
+		vars := map[string]interface{}{
+			"ansible_host": server.Host,
+			"ansible_port": server.Port,
+			"ansible_user": server.Username,
+		}
+		if server.Port == 0 {
+			vars["ansible_port"] = 22
+		}
+		inventory.HostVars[host] = vars
+		
+		if server.PrivateKey != "" || server.Password != "" {
+			inventory.credentials[host] = HostCredential{
+				Password:   server.Password,
+				PrivateKey: server.PrivateKey,
+			}
+		}
+	
+		grouped := false
+		if groupName != "" {
+			name := inventoryGroupName(groupName)
+			inventory.Groups[name] = append(inventory.Groups[name], host)
+			grouped = true
+		}
+		for _, tag := range tags {
+			name := inventoryGroupName(tagGroupPrefix + tag)
+			inventory.Groups[name] = append(inventory.Groups[name], host)
+			grouped = true
+		}
+		if !grouped {
+			inventory.Groups[ungroupedGroup] = append(inventory.Groups[ungroupedGroup], host)
+		}
+	}
+	
+	return inventory, nil
+}
+
+// GetDynamicInventory 获取ansible-inventory --list格式的动态inventory
+func (s *AnsibleService) GetDynamicInventory(filter *DynamicInventoryFilter) (map[string]interface{}, error) {
+	inventory, err := s.BuildDynamicInventory(filter)
+	if err != nil {
+		return nil, err
+	}
+	return inventory.ListJSON(), nil
+}
+
+// renderDynamicInventory 生成执行时使用的INI格式动态inventory及对应主机的连接凭据
+func (s *AnsibleService) renderDynamicInventory(filter *DynamicInventoryFilter) (string, map[string]HostCredential, error) {
+	inventory, err := s.BuildDynamicInventory(filter)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(inventory.Hosts) == 0 {
+		return "", nil, fmt.Errorf("dynamic inventory matched no servers")
+	}
+	return inventory.INI(), inventory.credentials, nil
+}
+
+// groupNames 返回排序后的组名
+func (d *DynamicInventory) groupNames() []string {
+	names := make([]string, 0, len(d.Groups))
+	for name := range d.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ListJSON 转换为ansible-inventory --list的JSON结构
+func (d *DynamicInventory) ListJSON() map[string]interface{} {
+	hostVars := make(map[string]interface{}, len(d.HostVars))
+	for host, vars := range d.HostVars {
+		hostVars[host] = vars
+	}
+	
+	names := d.groupNames()
+	result := map[string]interface{}{
+		"_meta": map[string]interface{}{"hostvars": hostVars},
+		"all":   map[string]interface{}{"children": names},
+	}
+	for _, name := range names {
+		result[name] = map[string]interface{}{"hosts": d.Groups[name]}
+	}
+	
+	return result
+}
+
+// INI 转换为INI格式的inventory内容
+func (d *DynamicInventory) INI() string {
+	var b strings.Builder
+	
+	// 主机变量定义在顶层，组中只引用主机名
+	for _, host := range d.Hosts {
+		vars := d.HostVars[host]
+		fmt.Fprintf(&b, "%s ansible_host=%v ansible_port=%v ansible_user=%v\n",
+			host, vars["ansible_host"], vars["ansible_port"], vars["ansible_user"])
+	}
+	
+	for _, name := range d.groupNames() {
+		if name == ungroupedGroup {
+			continue
+		}
+		fmt.Fprintf(&b, "\n[%s]\n", name)
+		for _, host := range d.Groups[name] {
+			b.WriteString(host + "\n")
+		}
+	}
+	
+	return b.String()
+}
+
+// matches 判断服务器是否满足过滤条件：组和标签分别匹配其中任意一个，同时指定时需都满足
+func (f *DynamicInventoryFilter) matches(group string, tags []string) bool {
+	if len(f.Groups) > 0 && !containsString(f.Groups, group) {
+		return false
+	}
+	
+	if len(f.Tags) > 0 {
+		for _, tag := range tags {
+			if containsString(f.Tags, tag) {
+				return true
+			}
+		}
+		return false
+	}
+	
+	return true
+}
+
+// splitTags 解析逗号分隔的标签
+func splitTags(tags string) []string {
+	var result []string
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// containsString 判断字符串是否在列表中
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// inventoryGroupName 将名称转换为合法的ansible组名
+func inventoryGroupName(name string) string {
+	return sanitizeInventoryName(name, "")
+}
+
+// inventoryHostName 将服务器名称转换为合法的inventory主机名
+func inventoryHostName(name string) string {
+	return sanitizeInventoryName(name, ".-")
+}
+
+// sanitizeInventoryName 将字母、数字、下划线和extra之外的字符替换为下划线
+func sanitizeInventoryName(name, extra string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		case strings.ContainsRune(extra, r):
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -16,20 +18,51 @@ import (
 
 // CommandExecutor 定义命令执行接口
 type CommandExecutor interface {
-	ExecuteAdhoc(ctx context.Context, req *AdhocExecutionRequest) (*ExecutionResult, error)
-	ExecutePlaybook(ctx context.Context, req *PlaybookExecutionRequest) (*ExecutionResult, error)
+	ExecuteAdhoc(ctx context.Context, req *AdhocExecutionRequest, opts *RunOptions) (*ExecutionResult, error)
+	ExecutePlaybook(ctx context.Context, playbook *Playbook, req *PlaybookExecutionRequest, opts *RunOptions) (*ExecutionResult, error)
 	CheckAnsibleInstallation() error
+	SyntaxCheckPlaybook(ctx context.Context, content string) (string, error)
 }
 
+// OutputFunc 实时输出回调函数，stream为stdout或stderr
+type OutputFunc func(stream, line string)
+
+// RunOptions 单次执行的运行选项
+type RunOptions struct {
+	Output      OutputFunc                // 本次执行的实时输出回调
+	Credentials map[string]HostCredential // 受管服务器的连接凭据，键为inventory主机名
+	ProjectDir  string                    // 项目根目录，设置时playbook.FileName为项目中的入口playbook
+	ProjectCommit string                  // git项目检出的提交，设置时从项目仓库检出该提交而不是复制当前文件
+	VaultPasswords map[string]string      // vault密码，键为vault ID，只在执行期间写入临时文件
+	InventoryVars []InventoryVars         // inventory的group_vars和host_vars，写入inventory所在目录
+	Secrets     []string                  // 需要在输出和结果中屏蔽的值，如问卷中的密码
+}
+
+const (
+	// 未配置时使用的默认超时时间和最大超时时间
+	defaultExecutionTimeout    = 300 * time.Second
+	defaultMaxExecutionTimeout = time.Hour
+	// syntaxCheckTimeout playbook语法检查的超时时间
+	syntaxCheckTimeout = 30 * time.Second
+)
+
+// ErrExecutionTimedOut 执行超时
+var ErrExecutionTimedOut = errors.New("execution timed out")
+
 // ExecutionResult 表示命令执行结果
 type ExecutionResult struct {
 	Success     bool   `json:"success"`
+	TimedOut    bool   `json:"timed_out"`
 	Output      string `json:"output"`
 	ErrorOutput string `json:"error_output"`
 	ExitCode    int    `json:"exit_code"`
 	Duration    int    `json:"duration"`
 	StartTime   time.Time `json:"start_time"`
 	EndTime     time.Time `json:"end_time"`
+	HostResults []HostResult `json:"host_results"` // 每个主机每个任务的结果
+	Recap       []HostRecap  `json:"recap"`        // 每个主机的汇总统计
+	Diffs       []TaskDiff   `json:"diffs"`        // diff模式下的变更内容
+	Artifacts   map[string]interface{} `json:"artifacts"` // set_stats产生的执行产物
 }
 
 // DefaultCommandExecutor 默认命令执行器
@@ -37,7 +70,20 @@ type DefaultCommandExecutor struct {
 	workDir        string
 	tempDir        string
 	ansiblePath    string
-	outputCallback func(string) // 实时输出回调函数
+	playbookPath   string        // ansible-playbook命令路径
+	defaultTimeout time.Duration // 默认执行超时时间
+	maxTimeout     time.Duration // 最大执行超时时间
+	pluginDir      string        // 结构化结果回调插件目录
+	outputCallback func(string)  // 实时输出回调函数
+}
+
+// commandSpec 描述一次要执行的ansible命令
+type commandSpec struct {
+	command string
+	args    []string
+	env     []string      // 在当前进程环境变量基础上追加的变量
+	dir     string        // 工作目录，为空时使用当前目录
+	timeout time.Duration
 }
 
 // NewCommandExecutor 创建新的命令执行器
@@ -50,9 +96,13 @@ func NewCommandExecutor(workDir string) *DefaultCommandExecutor {
 	ansiblePath := detectAnsiblePath("")
 	
 	return &DefaultCommandExecutor{
-		workDir:     workDir,
-		tempDir:     tempDir,
-		ansiblePath: ansiblePath,
+		workDir:        workDir,
+		tempDir:        tempDir,
+		ansiblePath:    ansiblePath,
+		playbookPath:   detectPlaybookPath(ansiblePath),
+		defaultTimeout: defaultExecutionTimeout,
+		maxTimeout:     defaultMaxExecutionTimeout,
+		pluginDir:      installCallbackPlugin(tempDir),
 	}
 }
 
@@ -74,11 +124,39 @@ func NewCommandExecutorWithConfig(cfg *config.Config) *DefaultCommandExecutor {
 	// 使用配置中的路径或智能探测
 	ansiblePath := detectAnsiblePath(cfg.Ansible.Path)
 	
+	// 超时时间配置
+	defaultTimeout := defaultExecutionTimeout
+	if cfg.Ansible.Timeout > 0 {
+		defaultTimeout = time.Duration(cfg.Ansible.Timeout) * time.Second
+	}
+	maxTimeout := defaultMaxExecutionTimeout
+	if cfg.Ansible.MaxTimeout > 0 {
+		maxTimeout = time.Duration(cfg.Ansible.MaxTimeout) * time.Second
+	}
+	
 	return &DefaultCommandExecutor{
-		workDir:     workDir,
-		tempDir:     tempDir,
-		ansiblePath: ansiblePath,
+		workDir:        workDir,
+		tempDir:        tempDir,
+		ansiblePath:    ansiblePath,
+		playbookPath:   detectPlaybookPath(ansiblePath),
+		defaultTimeout: defaultTimeout,
+		maxTimeout:     maxTimeout,
+		pluginDir:      installCallbackPlugin(tempDir),
+	}
+}
+
+// resolveTimeout 计算本次执行的超时时间：优先使用请求值，否则使用默认值，且不超过最大值
+func (e *DefaultCommandExecutor) resolveTimeout(requestedSeconds int) time.Duration {
+	timeout := e.defaultTimeout
+	if requestedSeconds > 0 {
+		timeout = time.Duration(requestedSeconds) * time.Second
+	}
+	
+	if e.maxTimeout > 0 && timeout > e.maxTimeout {
+		timeout = e.maxTimeout
 	}
+	
+	return timeout
 }
 
 // detectAnsiblePath 智能探测ansible命令路径
@@ -121,6 +199,23 @@ func detectAnsiblePath(configPath string) string {
 	return "ansible"
 }
 
+// detectPlaybookPath 根据ansible路径推断ansible-playbook命令路径
+func detectPlaybookPath(ansiblePath string) string {
+	// ansible-playbook通常与ansible安装在同一目录
+	if filepath.IsAbs(ansiblePath) {
+		candidate := filepath.Join(filepath.Dir(ansiblePath), "ansible-playbook")
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	
+	if path, err := exec.LookPath("ansible-playbook"); err == nil {
+		return path
+	}
+	
+	return "ansible-playbook"
+}
+
 // SetOutputCallback 设置实时输出回调函数
 func (e *DefaultCommandExecutor) SetOutputCallback(callback func(string)) {
 	e.outputCallback = callback
@@ -137,9 +232,15 @@ func (e *DefaultCommandExecutor) CheckAnsibleInstallation() error {
 }
 
 // ExecuteAdhoc 执行adhoc命令
-func (e *DefaultCommandExecutor) ExecuteAdhoc(ctx context.Context, req *AdhocExecutionRequest) (*ExecutionResult, error) {
+func (e *DefaultCommandExecutor) ExecuteAdhoc(ctx context.Context, req *AdhocExecutionRequest, opts *RunOptions) (*ExecutionResult, error) {
 	startTime := time.Now()
 	
+	runDir, err := e.prepareRunDir()
+	if err != nil {
+		return nil, err
+	}
+	defer e.cleanupRunDir(runDir) // 清理临时文件和凭据
+	
 	// 构建ansible命令
 	args := []string{
 		req.Hosts,
@@ -152,29 +253,43 @@ func (e *DefaultCommandExecutor) ExecuteAdhoc(ctx context.Context, req *AdhocExe
 	}
 	
 	// 处理inventory
-	inventoryFile, err := e.prepareInventory(req.Inventory)
+	inventoryFile, err := e.prepareInventory(runDir, req.Inventory, opts)
 	if err != nil {
 		return nil, fmt.Errorf("prepare inventory failed: %v", err)
 	}
-	defer os.Remove(inventoryFile) // 清理临时文件
 	
 	args = append(args, "-i", inventoryFile)
+	if limit := strings.TrimSpace(req.Limit); limit != "" {
+		args = append(args, "--limit", limit)
+	}
 	
 	// 处理额外变量
 	if req.ExtraVars != nil && len(req.ExtraVars) > 0 {
-		extraVarsFile, err := e.prepareExtraVars(req.ExtraVars)
+		extraVarsFile, err := e.prepareExtraVars(runDir, req.ExtraVars)
 		if err != nil {
 			return nil, fmt.Errorf("prepare extra vars failed: %v", err)
 		}
-		defer os.Remove(extraVarsFile)
 		args = append(args, "-e", "@"+extraVarsFile)
 	}
 	
+	args = append(args, checkDiffArgs(req.Check, req.Diff)...)
+	
+	// 处理vault密码
+	vaultArgs, err := e.prepareVaultPasswords(runDir, opts)
+	if err != nil {
+		return nil, fmt.Errorf("prepare vault passwords failed: %v", err)
+	}
+	args = append(args, vaultArgs...)
+	
 	// 添加输出格式参数
 	args = append(args, "-v") // 详细输出
 	
 	// 执行命令
-	result, err := e.executeCommand(ctx, e.ansiblePath, args, startTime)
+	result, err := e.runAnsible(ctx, &commandSpec{
+		command: e.ansiblePath,
+		args:    args,
+		timeout: e.resolveTimeout(req.TimeoutSeconds),
+	}, startTime, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -183,31 +298,179 @@ func (e *DefaultCommandExecutor) ExecuteAdhoc(ctx context.Context, req *AdhocExe
 }
 
 // ExecutePlaybook 执行playbook
-func (e *DefaultCommandExecutor) ExecutePlaybook(ctx context.Context, req *PlaybookExecutionRequest) (*ExecutionResult, error) {
-	// 这里需要根据playbook ID获取playbook内容
-	// 暂时返回未实现错误，等待后续实现
-	return nil, fmt.Errorf("playbook execution not implemented yet")
+func (e *DefaultCommandExecutor) ExecutePlaybook(ctx context.Context, playbook *Playbook, req *PlaybookExecutionRequest, opts *RunOptions) (*ExecutionResult, error) {
+	startTime := time.Now()
+	
+	runDir, err := e.prepareRunDir()
+	if err != nil {
+		return nil, err
+	}
+	defer e.cleanupRunDir(runDir)
+	
+	// 项目执行时以项目根目录作为工作目录运行入口playbook，否则将playbook内容写入临时文件
+	var playbookFile, workDir string
+	if opts != nil && opts.ProjectDir != "" {
+		workDir, err = e.prepareProject(ctx, runDir, opts)
+		if err != nil {
+			return nil, fmt.Errorf("prepare project failed: %v", err)
+		}
+		playbookFile = filepath.Join(workDir, filepath.FromSlash(playbook.FileName))
+	} else {
+		playbookFile, err = e.preparePlaybook(runDir, playbook.Content)
+		if err != nil {
+			return nil, fmt.Errorf("prepare playbook failed: %v", err)
+		}
+	}
+	
+	args := []string{playbookFile}
+	
+	// 处理inventory
+	inventoryFile, err := e.prepareInventory(runDir, req.Inventory, opts)
+	if err != nil {
+		return nil, fmt.Errorf("prepare inventory failed: %v", err)
+	}
+	
+	args = append(args, "-i", inventoryFile)
+	if limit := strings.TrimSpace(req.Limit); limit != "" {
+		args = append(args, "--limit", limit)
+	}
+	
+	// 处理额外变量
+	if len(req.ExtraVars) > 0 {
+		extraVarsFile, err := e.prepareExtraVars(runDir, req.ExtraVars)
+		if err != nil {
+			return nil, fmt.Errorf("prepare extra vars failed: %v", err)
+		}
+		args = append(args, "-e", "@"+extraVarsFile)
+	}
+	
+	// 处理标签
+	if tags := strings.TrimSpace(req.Tags); tags != "" {
+		args = append(args, "--tags", tags)
+	}
+	if skipTags := strings.TrimSpace(req.SkipTags); skipTags != "" {
+		args = append(args, "--skip-tags", skipTags)
+	}
+	
+	args = append(args, checkDiffArgs(req.Check, req.Diff)...)
+	
+	// 处理vault密码
+	vaultArgs, err := e.prepareVaultPasswords(runDir, opts)
+	if err != nil {
+		return nil, fmt.Errorf("prepare vault passwords failed: %v", err)
+	}
+	args = append(args, vaultArgs...)
+	args = append(args, "-v")
+	
+	return e.runAnsible(ctx, &commandSpec{
+		command: e.playbookPath,
+		args:    args,
+		dir:     workDir,
+		timeout: e.resolveTimeout(req.TimeoutSeconds),
+	}, startTime, opts)
+}
+
+// SyntaxCheckPlaybook 使用ansible-playbook --syntax-check检查playbook内容，返回命令输出；
+// 未安装ansible时返回ErrSyntaxCheckUnavailable
+func (e *DefaultCommandExecutor) SyntaxCheckPlaybook(ctx context.Context, content string) (string, error) {
+	if _, err := exec.LookPath(e.playbookPath); err != nil {
+		return "", ErrSyntaxCheckUnavailable
+	}
+	
+	runDir, err := e.prepareRunDir()
+	if err != nil {
+		return "", err
+	}
+	defer e.cleanupRunDir(runDir)
+	
+	playbookFile, err := e.preparePlaybook(runDir, content)
+	if err != nil {
+		return "", fmt.Errorf("prepare playbook failed: %v", err)
+	}
+	
+	ctx, cancel := context.WithTimeout(ctx, syntaxCheckTimeout)
+	defer cancel()
+	
+	// 语法检查不连接主机，使用只包含localhost的inventory
+	cmd := exec.CommandContext(ctx, e.playbookPath, "--syntax-check", "-i", "localhost,", playbookFile)
+	cmd.Dir = runDir
+	cmd.Env = append(os.Environ(), "ANSIBLE_NOCOLOR=1")
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+// checkDiffArgs 构建试运行和差异模式参数
+func checkDiffArgs(check, diff bool) []string {
+	var args []string
+	if check {
+		args = append(args, "--check")
+	}
+	if diff {
+		args = append(args, "--diff")
+	}
+	return args
 }
 
-// prepareInventory 准备inventory文件
-func (e *DefaultCommandExecutor) prepareInventory(inventory string) (string, error) {
-	if inventory == "" {
-		inventory = "localhost ansible_connection=local"
+// preparePlaybook 准备playbook文件
+func (e *DefaultCommandExecutor) preparePlaybook(runDir, content string) (string, error) {
+	if strings.TrimSpace(content) == "" {
+		return "", fmt.Errorf("playbook content is empty")
+	}
+	
+	tempFile := filepath.Join(runDir, "playbook.yml")
+	
+	err := os.WriteFile(tempFile, []byte(content), 0600)
+	if err != nil {
+		return "", fmt.Errorf("write playbook file failed: %v", err)
+	}
+	
+	return tempFile, nil
+}
+
+// prepareProject 将项目文件复制到执行目录，执行期间项目被修改或同步不影响本次执行；
+// git项目从仓库中检出执行时确定的提交
+func (e *DefaultCommandExecutor) prepareProject(ctx context.Context, runDir string, opts *RunOptions) (string, error) {
+	workDir := filepath.Join(runDir, "project")
+	if opts.ProjectCommit != "" {
+		if err := checkoutGitCommit(ctx, opts.ProjectDir, opts.ProjectCommit, workDir, filepath.Join(runDir, "git-index")); err != nil {
+			return "", err
+		}
+		return workDir, nil
+	}
+	if err := copyProjectTree(opts.ProjectDir, workDir); err != nil {
+		return "", err
+	}
+	return workDir, nil
+}
+
+// prepareInventory 准备inventory文件，受管服务器的凭据写入同目录的host_vars
+func (e *DefaultCommandExecutor) prepareInventory(runDir, inventory string, opts *RunOptions) (string, error) {
+	if strings.TrimSpace(inventory) == "" {
+		return "", fmt.Errorf("inventory is empty")
 	}
 	
 	// 创建临时inventory文件
-	tempFile := filepath.Join(e.tempDir, fmt.Sprintf("inventory_%d", time.Now().UnixNano()))
+	tempFile := filepath.Join(runDir, "inventory")
 	
-	err := os.WriteFile(tempFile, []byte(inventory), 0644)
+	err := os.WriteFile(tempFile, []byte(inventory), 0600)
 	if err != nil {
 		return "", fmt.Errorf("write inventory file failed: %v", err)
 	}
 	
+	if opts != nil {
+		if err := e.prepareInventoryVars(runDir, opts.InventoryVars); err != nil {
+			return "", err
+		}
+		if err := e.prepareCredentials(runDir, opts.Credentials); err != nil {
+			return "", err
+		}
+	}
+	
 	return tempFile, nil
 }
 
 // prepareExtraVars 准备额外变量文件
-func (e *DefaultCommandExecutor) prepareExtraVars(extraVars map[string]interface{}) (string, error) {
+func (e *DefaultCommandExecutor) prepareExtraVars(runDir string, extraVars map[string]interface{}) (string, error) {
 	// 将额外变量转换为JSON
 	varsJSON, err := json.Marshal(extraVars)
 	if err != nil {
@@ -215,9 +478,9 @@ func (e *DefaultCommandExecutor) prepareExtraVars(extraVars map[string]interface
 	}
 	
 	// 创建临时变量文件
-	tempFile := filepath.Join(e.tempDir, fmt.Sprintf("extravars_%d.json", time.Now().UnixNano()))
+	tempFile := filepath.Join(runDir, "extravars.json")
 	
-	err = os.WriteFile(tempFile, varsJSON, 0644)
+	err = os.WriteFile(tempFile, varsJSON, 0600)
 	if err != nil {
 		return "", fmt.Errorf("write extra vars file failed: %v", err)
 	}
@@ -225,24 +488,68 @@ func (e *DefaultCommandExecutor) prepareExtraVars(extraVars map[string]interface
 	return tempFile, nil
 }
 
-// executeCommand 执行命令并收集输出
-func (e *DefaultCommandExecutor) executeCommand(ctx context.Context, command string, args []string, startTime time.Time) (*ExecutionResult, error) {
-	// 创建一个新的上下文，设置30秒超时
-	cmdCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-	
-	cmd := exec.CommandContext(cmdCtx, command, args...)
-	
-	// 创建管道来收集输出
-	stdout, err := cmd.StdoutPipe()
+// runAnsible 启用结构化结果回调插件执行ansible命令，并解析每个主机的执行结果
+func (e *DefaultCommandExecutor) runAnsible(ctx context.Context, spec *commandSpec, startTime time.Time, opts *RunOptions) (*ExecutionResult, error) {
+	eventsFile := filepath.Join(e.tempDir, fmt.Sprintf("events_%d.jsonl", time.Now().UnixNano()))
+	defer os.Remove(eventsFile)
+	
+	spec.env = append(spec.env,
+		appendEnvList("ANSIBLE_CALLBACK_PLUGINS", ":", e.pluginDir),
+		appendEnvList("ANSIBLE_CALLBACKS_ENABLED", ",", eventsCallbackName),
+		// 兼容ansible 2.11之前的版本
+		appendEnvList("ANSIBLE_CALLBACK_WHITELIST", ",", eventsCallbackName),
+		// adhoc命令默认不加载回调插件
+		"ANSIBLE_LOAD_CALLBACK_PLUGINS=1",
+		eventsFileEnv+"="+eventsFile,
+	)
+	
+	result, err := e.executeCommand(ctx, spec, startTime, opts)
 	if err != nil {
-		return nil, fmt.Errorf("create stdout pipe failed: %v", err)
+		return nil, err
 	}
 	
-	stderr, err := cmd.StderrPipe()
+	events, err := parseEventsFile(eventsFile)
 	if err != nil {
-		return nil, fmt.Errorf("create stderr pipe failed: %v", err)
+		// 结构化结果解析失败不影响执行结果，原始输出仍然可用
+		log.Printf("Warning: parse ansible events failed: %v", err)
 	}
+	maskEvents(events, secretMasker(runSecrets(opts)))
+	result.HostResults = events.HostResults
+	result.Recap = events.Recap
+	result.Diffs = events.Diffs
+	result.Artifacts = events.Artifacts
+	
+	return result, nil
+}
+
+// appendEnvList 将值追加到列表型环境变量已有的值之后
+func appendEnvList(key, sep, value string) string {
+	if existing := os.Getenv(key); existing != "" {
+		return key + "=" + existing + sep + value
+	}
+	return key + "=" + value
+}
+
+// executeCommand 执行命令并收集输出
+func (e *DefaultCommandExecutor) executeCommand(ctx context.Context, spec *commandSpec, startTime time.Time, opts *RunOptions) (*ExecutionResult, error) {
+	// 基于调用方上下文设置超时，调用方取消时同样终止命令
+	cmdCtx, cancel := context.WithTimeoutCause(ctx, spec.timeout, ErrExecutionTimedOut)
+	defer cancel()
+	
+	cmd := exec.CommandContext(cmdCtx, spec.command, spec.args...)
+	if len(spec.env) > 0 {
+		cmd.Env = append(os.Environ(), spec.env...)
+	}
+	cmd.Dir = spec.dir
+	setProcessGroup(cmd)
+	// 进程被终止后最多等待5秒关闭输出管道
+	cmd.WaitDelay = 5 * time.Second
+	
+	// 创建管道来收集输出，由exec负责复制输出，Wait会等待复制完成(最多WaitDelay)
+	stdout, stdoutWriter := io.Pipe()
+	stderr, stderrWriter := io.Pipe()
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
 	
 	// 启动命令
 	if err := cmd.Start(); err != nil {
@@ -257,11 +564,19 @@ func (e *DefaultCommandExecutor) executeCommand(ctx context.Context, command str
 	outputDone := make(chan bool)
 	errorDone := make(chan bool)
 	
-	go e.readOutput(stdout, &outputLines, outputDone)
-	go e.readOutput(stderr, &errorLines, errorDone)
+	var output OutputFunc
+	if opts != nil {
+		output = opts.Output
+	}
+	mask := secretMasker(runSecrets(opts))
+	
+	go e.readOutput(stdout, "stdout", &outputLines, outputDone, output, mask)
+	go e.readOutput(stderr, "stderr", &errorLines, errorDone, output, mask)
 	
-	// 等待命令执行完成
-	err = cmd.Wait()
+	// 等待命令执行完成，之后关闭管道使读取结束
+	err := cmd.Wait()
+	stdoutWriter.Close()
+	stderrWriter.Close()
 	
 	// 等待输出读取完成
 	<-outputDone
@@ -273,6 +588,7 @@ func (e *DefaultCommandExecutor) executeCommand(ctx context.Context, command str
 	// 构建结果
 	result := &ExecutionResult{
 		Success:     err == nil,
+		TimedOut:    err != nil && errors.Is(context.Cause(cmdCtx), ErrExecutionTimedOut),
 		Output:      strings.Join(outputLines, "\n"),
 		ErrorOutput: strings.Join(errorLines, "\n"),
 		ExitCode:    cmd.ProcessState.ExitCode(),
@@ -285,18 +601,29 @@ func (e *DefaultCommandExecutor) executeCommand(ctx context.Context, command str
 }
 
 // readOutput 读取命令输出
-func (e *DefaultCommandExecutor) readOutput(reader io.Reader, lines *[]string, done chan bool) {
+func (e *DefaultCommandExecutor) readOutput(reader io.Reader, stream string, lines *[]string, done chan bool, output OutputFunc, mask func(string) string) {
 	defer close(done)
 	
 	scanner := bufio.NewScanner(reader)
+	// -v输出中的模块结果可能是很长的单行JSON
+	scanner.Buffer(make([]byte, 64*1024), maxEventLineSize)
 	for scanner.Scan() {
-		line := scanner.Text()
+		line := mask(scanner.Text())
 		*lines = append(*lines, line)
-		
+	
 		// 如果设置了回调函数，实时输出
 		if e.outputCallback != nil {
 			e.outputCallback(line)
 		}
+		if output != nil {
+			output(stream, line)
+		}
+	}
+	
+	// 读取出错时继续丢弃剩余输出，避免命令因管道写满而阻塞
+	if err := scanner.Err(); err != nil {
+		*lines = append(*lines, fmt.Sprintf("[output truncated: %v]", err))
+		io.Copy(io.Discard, reader)
 	}
 }
 
@@ -310,57 +637,10 @@ func ValidateAdhocRequest(req *AdhocExecutionRequest) error {
 		return fmt.Errorf("hosts is required")
 	}
 	
-	// 验证模块名称是否有效
-	validModules := map[string]bool{
-		"shell":    true,
-		"command":  true,
-		"copy":     true,
-		"file":     true,
-		"service":  true,
-		"package":  true,
-		"yum":      true,
-		"apt":      true,
-		"ping":     true,
-		"setup":    true,
-		"debug":    true,
-		"template": true,
-		"lineinfile": true,
-		"replace":  true,
-		"user":     true,
-		"group":    true,
-		"cron":     true,
-		"mount":    true,
-		"git":      true,
-	}
-	
-	if !validModules[req.Module] {
-		return fmt.Errorf("unsupported module: %s", req.Module)
+	// 只校验名称格式，是否允许使用由模块允许列表决定
+	if err := validateModuleName(req.Module); err != nil {
+		return err
 	}
 	
 	return nil
 }
-
-// GetCommonModules 获取常用ansible模块列表
-func GetCommonModules() []map[string]string {
-	return []map[string]string{
-		{"name": "shell", "description": "Execute shell commands"},
-		{"name": "command", "description": "Execute commands without shell"},
-		{"name": "copy", "description": "Copy files to remote locations"},
-		{"name": "file", "description": "Manage files and file properties"},
-		{"name": "service", "description": "Manage services"},
-		{"name": "package", "description": "Manage packages"},
-		{"name": "yum", "description": "Manage packages with yum"},
-		{"name": "apt", "description": "Manage packages with apt"},
-		{"name": "ping", "description": "Test connection to hosts"},
-		{"name": "setup", "description": "Gather facts about remote hosts"},
-		{"name": "debug", "description": "Print statements during execution"},
-		{"name": "template", "description": "Process Jinja2 templates"},
-		{"name": "lineinfile", "description": "Manage lines in text files"},
-		{"name": "replace", "description": "Replace text in files"},
-		{"name": "user", "description": "Manage user accounts"},
-		{"name": "group", "description": "Manage groups"},
-		{"name": "cron", "description": "Manage cron entries"},
-		{"name": "mount", "description": "Manage mounted filesystems"},
-		{"name": "git", "description": "Deploy software from git repositories"},
-	}
-}
\ No newline at end of file
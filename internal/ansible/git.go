@@ -0,0 +1,142 @@
+package ansible
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// scpLikeGitURL 匹配user@host:path形式的ssh地址
+	scpLikeGitURL = regexp.MustCompile(`^([A-Za-z0-9._-]+@)?[A-Za-z0-9.-]+:.+$`)
+	// gitCommitSHA 匹配完整或缩写的提交SHA
+	gitCommitSHA = regexp.MustCompile(`^[0-9a-fA-F]{4,64}$`)
+)
+
+// gitEnv 运行git命令的环境变量，禁止交互式提示，只允许本地和ssh协议
+func gitEnv(extra ...string) []string {
+	env := append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_ALLOW_PROTOCOL=file:ssh",
+		"GIT_CONFIG_NOSYSTEM=1",
+	)
+	return append(env, extra...)
+}
+
+// runGit 在指定目录中执行git命令，返回标准输出；失败时错误中包含标准错误输出
+func runGit(ctx context.Context, env []string, dir string, args ...string) (string, error) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		return "", fmt.Errorf("git not found: %v", err)
+	}
+	
+	command := strings.Join(args, " ")
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	cmd := exec.CommandContext(ctx, gitPath, args...)
+	cmd.Env = env
+	
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return "", fmt.Errorf("git %s failed: %s", command, message)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// validateGitURL 校验仓库地址，只支持本地路径、file://和ssh地址
+func validateGitURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case raw == "":
+		return "", fmt.Errorf("%w: git url is required", ErrInvalidRepository)
+	case strings.HasPrefix(raw, "-"), strings.Contains(raw, "::"), strings.ContainsAny(raw, "\n\r\x00"):
+		return "", fmt.Errorf("%w: invalid git url", ErrInvalidRepository)
+	}
+	
+	if strings.Contains(raw, "://") {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return "", fmt.Errorf("%w: invalid git url: %v", ErrInvalidRepository, err)
+		}
+		switch u.Scheme {
+		case "file":
+			if u.Path == "" {
+				return "", fmt.Errorf("%w: file url must contain a path", ErrInvalidRepository)
+			}
+		case "ssh":
+			if u.Host == "" || u.Path == "" {
+				return "", fmt.Errorf("%w: ssh url must contain a host and a path", ErrInvalidRepository)
+			}
+		default:
+			return "", fmt.Errorf("%w: unsupported git url scheme: %s", ErrInvalidRepository, u.Scheme)
+		}
+		return raw, nil
+	}
+	
+	if filepath.IsAbs(raw) || scpLikeGitURL.MatchString(raw) {
+		return raw, nil
+	}
+	return "", fmt.Errorf("%w: git url must be an absolute local path, a file:// url or an ssh url", ErrInvalidRepository)
+}
+
+// validateGitRef 校验分支或标签名称，为空表示使用远端默认分支
+func validateGitRef(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(ref, "-") || strings.Contains(ref, "..") || strings.ContainsAny(ref, " ~^:?*[\\\t\n\r\x00") {
+		return "", fmt.Errorf("%w: invalid git ref: %s", ErrInvalidRepository, ref)
+	}
+	return ref, nil
+}
+
+// resolveGitCommit 将提交SHA解析为仓库中存在的完整提交SHA
+func resolveGitCommit(ctx context.Context, repoDir, commit string) (string, error) {
+	if !gitCommitSHA.MatchString(commit) {
+		return "", fmt.Errorf("%w: invalid commit sha: %s", ErrInvalidRepository, commit)
+	}
+	sha, err := runGit(ctx, gitEnv(), repoDir, "rev-parse", "--verify", "--quiet", commit+"^{commit}")
+	if err != nil || sha == "" {
+		return "", fmt.Errorf("%w: commit %s not found", ErrProjectFileNotFound, commit)
+	}
+	return sha, nil
+}
+
+// gitBlobExists 检查提交中是否存在指定的普通文件
+func gitBlobExists(ctx context.Context, repoDir, commit, filePath string) bool {
+	objectType, err := runGit(ctx, gitEnv(), repoDir, "cat-file", "-t", commit+":"+filePath)
+	return err == nil && objectType == "blob"
+}
+
+// checkoutGitCommit 将仓库中的提交检出到目标目录；使用临时索引文件，不修改仓库本身的工作区和索引
+func checkoutGitCommit(ctx context.Context, repoDir, commit, dest, indexFile string) error {
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return fmt.Errorf("create checkout dir failed: %v", err)
+	}
+	
+	gitDir := filepath.Join(repoDir, projectGitDir)
+	env := gitEnv("GIT_INDEX_FILE=" + indexFile)
+	if _, err := runGit(ctx, env, "", "--git-dir", gitDir, "--work-tree", dest, "read-tree", commit); err != nil {
+		return err
+	}
+	// 符号链接检出为包含链接目标的普通文件，避免执行时经由链接访问项目之外的文件
+	if _, err := runGit(ctx, env, "", "-c", "core.symlinks=false", "--git-dir", gitDir, "--work-tree", dest,
+		"checkout-index", "--all", "--force"); err != nil {
+		return err
+	}
+	return nil
+}
@@ -1,10 +1,15 @@
 package ansible
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"path/filepath"
 	"strconv"
 	"strings"
-	
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"server-manager/internal/common"
 )
@@ -40,6 +45,15 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 		inventory.PUT("/:id", h.UpdateInventory)
 		inventory.DELETE("/:id", h.DeleteInventory)
 		inventory.GET("/default", h.GetDefaultInventory)
+		inventory.GET("/dynamic", h.GetDynamicInventory)
+		inventory.GET("/:id/graph", h.GetInventoryGraph)
+		inventory.GET("/:id/vars", h.ListInventoryVars)
+		inventory.GET("/:id/group_vars/:name", h.GetInventoryVars)
+		inventory.PUT("/:id/group_vars/:name", h.SaveInventoryVars)
+		inventory.DELETE("/:id/group_vars/:name", h.DeleteInventoryVars)
+		inventory.GET("/:id/host_vars/:name", h.GetInventoryVars)
+		inventory.PUT("/:id/host_vars/:name", h.SaveInventoryVars)
+		inventory.DELETE("/:id/host_vars/:name", h.DeleteInventoryVars)
 	}
 	
 	// Playbook管理路由
@@ -50,6 +64,93 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 		playbook.GET("/:id", h.GetPlaybook)
 		playbook.PUT("/:id", h.UpdatePlaybook)
 		playbook.DELETE("/:id", h.DeletePlaybook)
+		playbook.POST("/:id/execute", h.ExecutePlaybook)
+		playbook.GET("/:id/revisions", h.ListPlaybookRevisions)
+		playbook.GET("/:id/revisions/:revision", h.GetPlaybookRevision)
+		playbook.POST("/:id/revisions/:revision/restore", h.RestorePlaybookRevision)
+		playbook.GET("/:id/diff", h.DiffPlaybookRevisions)
+		playbook.POST("/validate", h.ValidatePlaybook)
+		playbook.GET("/executions", h.ListPlaybookExecutions)
+		playbook.GET("/executions/:id", h.GetPlaybookExecution)
+	}
+	
+	// 项目路由
+	project := r.Group("/ansible/projects")
+	{
+		project.POST("", h.CreateProject)
+		project.GET("", h.ListProjects)
+		project.GET("/:id", h.GetProject)
+		project.PUT("/:id", h.UpdateProject)
+		project.DELETE("/:id", h.DeleteProject)
+		project.POST("/:id/upload", h.UploadProjectArchive)
+		project.GET("/:id/tree", h.GetProjectTree)
+		project.GET("/:id/playbooks", h.ListProjectPlaybooks)
+		project.GET("/:id/files/*path", h.GetProjectFile)
+		project.PUT("/:id/files/*path", h.WriteProjectFile)
+		project.DELETE("/:id/files/*path", h.DeleteProjectFile)
+		project.POST("/:id/execute", h.ExecuteProject)
+		project.POST("/:id/sync", h.SyncProject)
+	}
+	
+	// Vault路由
+	vault := r.Group("/ansible/vault")
+	{
+		vault.POST("/secrets", h.CreateVaultSecret)
+		vault.GET("/secrets", h.ListVaultSecrets)
+		vault.GET("/secrets/:id", h.GetVaultSecret)
+		vault.PUT("/secrets/:id", h.UpdateVaultSecret)
+		vault.DELETE("/secrets/:id", h.DeleteVaultSecret)
+		vault.POST("/encrypt", h.EncryptVaultString)
+		vault.POST("/decrypt", h.DecryptVaultString)
+		vault.POST("/encrypt-file", h.EncryptVaultFile)
+		vault.POST("/decrypt-file", h.DecryptVaultFile)
+	}
+	
+	// 定时任务路由
+	schedule := r.Group("/ansible/schedules")
+	{
+		schedule.POST("", h.CreateSchedule)
+		schedule.GET("", h.ListSchedules)
+		schedule.GET("/:id", h.GetSchedule)
+		schedule.PUT("/:id", h.UpdateSchedule)
+		schedule.DELETE("/:id", h.DeleteSchedule)
+	}
+	
+	// 作业模板路由
+	jobTemplate := r.Group("/ansible/job-templates")
+	{
+		jobTemplate.POST("", h.CreateJobTemplate)
+		jobTemplate.GET("", h.ListJobTemplates)
+		jobTemplate.GET("/:id", h.GetJobTemplate)
+		jobTemplate.PUT("/:id", h.UpdateJobTemplate)
+		jobTemplate.DELETE("/:id", h.DeleteJobTemplate)
+		jobTemplate.POST("/:id/launch", h.LaunchJobTemplate)
+	}
+	
+	// 工作流路由
+	workflow := r.Group("/ansible/workflows")
+	{
+		workflow.POST("", h.CreateWorkflow)
+		workflow.GET("", h.ListWorkflows)
+		workflow.GET("/:id", h.GetWorkflow)
+		workflow.PUT("/:id", h.UpdateWorkflow)
+		workflow.DELETE("/:id", h.DeleteWorkflow)
+		workflow.POST("/:id/launch", h.LaunchWorkflow)
+		workflow.GET("/:id/runs", h.ListWorkflowRuns)
+		workflow.GET("/runs/:id", h.GetWorkflowRun)
+		workflow.POST("/runs/:id/cancel", h.CancelWorkflowRun)
+	}
+	
+	// 执行记录通用路由，通过type查询参数区分adhoc和playbook
+	executions := r.Group("/ansible/executions")
+	{
+		executions.GET("", h.ListExecutions)
+		executions.GET("/export", h.ExportExecutions)
+		executions.GET("/:id/stream", h.StreamExecution)
+		executions.POST("/:id/cancel", h.CancelExecution)
+		executions.POST("/:id/relaunch", h.RelaunchExecution)
+		executions.POST("/:id/approve", h.ApproveExecution)
+		executions.POST("/:id/reject", h.RejectExecution)
 	}
 	
 	// 统计和系统信息路由
@@ -57,8 +158,40 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	{
 		system.GET("/stats", h.GetExecutionStats)
 		system.GET("/check", h.CheckAnsible)
-		system.GET("/modules", h.GetCommonModules)
+		system.GET("/modules", h.GetAllowedModules)
+	}
+	
+	// 模块允许列表路由，仅管理员
+	module := r.Group("/ansible/modules")
+	{
+		module.POST("", h.CreateModule)
+		module.GET("", h.ListModules)
+		module.PUT("/:id", h.UpdateModule)
+		module.DELETE("/:id", h.DeleteModule)
+	}
+	
+	// 模块授权路由，仅管理员
+	moduleGrant := r.Group("/ansible/module-grants")
+	{
+		moduleGrant.GET("", h.ListModuleGrants)
+		moduleGrant.PUT("", h.SaveModuleGrant)
+		moduleGrant.DELETE("/:id", h.DeleteModuleGrant)
+	}
+	
+	// 命令策略路由，仅管理员
+	policy := r.Group("/ansible/policy-rules")
+	{
+		policy.POST("", h.CreatePolicyRule)
+		policy.GET("", h.ListPolicyRules)
+		policy.PUT("/:id", h.UpdatePolicyRule)
+		policy.DELETE("/:id", h.DeletePolicyRule)
 	}
+	r.GET("/ansible/policy-audits", h.ListPolicyAudits)
+	
+	// 审批路由
+	r.GET("/ansible/approvals", h.ListPendingApprovals)
+	r.GET("/ansible/protected-targets", h.ListProtectedTargets)
+	r.PUT("/ansible/protected-targets", h.SetProtectedTarget)
 }
 
 // ExecuteAdhoc 执行adhoc命令
@@ -73,6 +206,25 @@ func (h *Handler) ExecuteAdhoc(c *gin.Context) {
 	
 	execution, err := h.service.ExecuteAdhocCommand(c.Request.Context(), userID, &req)
 	if err != nil {
+		if policyErrorResponse(c, err) {
+			return
+		}
+		if errors.Is(err, ErrModuleNotAllowed) {
+			c.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, ErrInventoryNotFound) {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Inventory not found"))
+			return
+		}
+		if errors.Is(err, ErrVaultSecretNotFound) {
+			c.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, ErrInvalidInventory) {
+			c.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Execute adhoc command failed"))
 		return
 	}
@@ -131,33 +283,110 @@ func (h *Handler) GetAdhocExecution(c *gin.Context) {
 	c.JSON(http.StatusOK, common.SuccessResponse("Execution retrieved successfully", execution))
 }
 
-// CreateInventory 创建inventory
-func (h *Handler) CreateInventory(c *gin.Context) {
-	userID := c.GetUint("user_id")
+// StreamExecution 以Server-Sent Events推送执行的实时输出
+func (h *Handler) StreamExecution(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid execution ID"))
+		return
+	}
 	
-	var req InventoryRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+	kind, ok := parseExecutionType(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid execution type"))
 		return
 	}
 	
-	inventory, err := h.service.CreateInventory(userID, &req)
+	subscription, err := h.service.StreamExecution(kind, uint(id))
 	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
-			c.JSON(http.StatusConflict, common.ErrorResponse("Inventory name already exists"))
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Execution not found"))
 			return
 		}
-		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Create inventory failed"))
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Stream execution failed"))
 		return
 	}
+	defer subscription.Close()
 	
-	c.JSON(http.StatusOK, common.SuccessResponse("Inventory created successfully", inventory))
+	// 输出流为长连接，不受服务器写超时限制
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	
+	// 先发送已产生的输出
+	for _, event := range subscription.History {
+		c.SSEvent(event.Type, event)
+	}
+	c.Writer.Flush()
+	
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+	
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			// status表示执行结束，dropped表示连接因消费过慢被断开，客户端可重新连接
+			return event.Type != OutputEventStatus && event.Type != OutputEventDropped
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
 }
 
-// ListInventories 列出inventory
-func (h *Handler) ListInventories(c *gin.Context) {
+// CancelExecution 取消执行
+func (h *Handler) CancelExecution(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid execution ID"))
+		return
+	}
+	
+	kind, ok := parseExecutionType(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid execution type"))
+		return
+	}
+	
+	err = h.service.CancelExecution(kind, uint(id), userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Execution not found"))
+			return
+		}
+		if errors.Is(err, ErrExecutionNotRunning) {
+			c.JSON(http.StatusConflict, common.ErrorResponse("Execution is not running"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Cancel execution failed"))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"id":   id,
+		"type": kind,
+	}
+	c.JSON(http.StatusOK, common.SuccessResponse("Execution cancellation requested", response))
+}
+
+// ListExecutions 按条件列出adhoc和playbook执行历史，非管理员只能查看自己的执行
+func (h *Handler) ListExecutions(c *gin.Context) {
+	filter, ok := parseExecutionFilter(c)
+	if !ok {
+		return
+	}
+	
 	// 解析分页参数
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
@@ -171,134 +400,126 @@ func (h *Handler) ListInventories(c *gin.Context) {
 	
 	offset := (page - 1) * pageSize
 	
-	inventories, total, err := h.service.ListInventories(userID, offset, pageSize)
+	executions, total, err := h.service.ListExecutions(filter, offset, pageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get inventories failed"))
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get executions failed"))
 		return
 	}
 	
 	response := map[string]interface{}{
-		"data":       inventories,
-		"total":      total,
-		"page":       page,
-		"page_size":  pageSize,
+		"data":        executions,
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
 		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
 	}
 	
-	c.JSON(http.StatusOK, common.SuccessResponse("Inventories retrieved successfully", response))
+	c.JSON(http.StatusOK, common.SuccessResponse("Executions retrieved successfully", response))
 }
 
-// GetInventory 获取inventory详情
-func (h *Handler) GetInventory(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid inventory ID"))
+// ExportExecutions 按条件导出执行历史，format为csv或json
+func (h *Handler) ExportExecutions(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid export format"))
 		return
 	}
 	
-	inventory, err := h.service.GetInventory(uint(id))
-	if err != nil {
-		c.JSON(http.StatusNotFound, common.ErrorResponse("Inventory not found"))
+	filter, ok := parseExecutionFilter(c)
+	if !ok {
 		return
 	}
 	
-	c.JSON(http.StatusOK, common.SuccessResponse("Inventory retrieved successfully", inventory))
-}
-
-// UpdateInventory 更新inventory
-func (h *Handler) UpdateInventory(c *gin.Context) {
-	userID := c.GetUint("user_id")
-	
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	executions, err := h.service.ExportExecutions(filter)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid inventory ID"))
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Export executions failed"))
 		return
 	}
 	
-	var req InventoryRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+	filename := fmt.Sprintf("executions-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if format == "json" {
+		c.JSON(http.StatusOK, executions)
 		return
 	}
 	
-	inventory, err := h.service.UpdateInventory(uint(id), userID, &req)
-	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			c.JSON(http.StatusNotFound, common.ErrorResponse("Inventory not found"))
-			return
-		}
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
-			c.JSON(http.StatusConflict, common.ErrorResponse("Inventory name already exists"))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Update inventory failed"))
-		return
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := writeExecutionsCSV(c.Writer, executions); err != nil {
+		c.Error(err)
 	}
-	
-	c.JSON(http.StatusOK, common.SuccessResponse("Inventory updated successfully", inventory))
 }
 
-// DeleteInventory 删除inventory
-func (h *Handler) DeleteInventory(c *gin.Context) {
-	userID := c.GetUint("user_id")
-	
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid inventory ID"))
-		return
+// parseExecutionFilter 解析执行历史的查询参数，参数无效时写入错误响应并返回false。
+// status可以逗号分隔多个值，from和to为RFC3339时间或日期，to为日期时包含当天
+func parseExecutionFilter(c *gin.Context) (*ExecutionFilter, bool) {
+	filter := &ExecutionFilter{
+		Type:   c.Query("type"),
+		Module: strings.TrimSpace(c.Query("module")),
+		Host:   strings.TrimSpace(c.Query("host")),
+		Search: strings.TrimSpace(c.Query("q")),
+		Sort:   c.Query("sort"),
+		Desc:   c.DefaultQuery("order", "desc") != "asc",
 	}
 	
-	err = h.service.DeleteInventory(uint(id), userID)
-	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			c.JSON(http.StatusNotFound, common.ErrorResponse("Inventory not found"))
-			return
+	if filter.Type != "" && filter.Type != ExecutionTypeAdhoc && filter.Type != ExecutionTypePlaybook {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid execution type"))
+		return nil, false
+	}
+	if !validExecutionSort(filter.Sort) {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid sort field"))
+		return nil, false
+	}
+	for _, status := range strings.Split(c.Query("status"), ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			filter.Statuses = append(filter.Statuses, status)
 		}
-		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Delete inventory failed"))
-		return
 	}
 	
-	c.JSON(http.StatusOK, common.SuccessResponse("Inventory deleted successfully", map[string]string{"message": "Inventory deleted successfully"}))
-}
-
-// GetDefaultInventory 获取默认inventory
-func (h *Handler) GetDefaultInventory(c *gin.Context) {
-	userID := c.GetUint("user_id")
-	
-	inventory, err := h.service.GetDefaultInventory(userID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, common.ErrorResponse("Default inventory not found"))
-		return
+	ids := map[string]*uint{
+		"playbook_id":  &filter.PlaybookID,
+		"project_id":   &filter.ProjectID,
+		"inventory_id": &filter.InventoryID,
+		"user_id":      &filter.UserID,
+	}
+	for name, target := range ids {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.ErrorResponse(fmt.Sprintf("Invalid %s", name)))
+			return nil, false
+		}
+		*target = uint(id)
 	}
 	
-	c.JSON(http.StatusOK, common.SuccessResponse("Default inventory retrieved successfully", inventory))
-}
-
-// CreatePlaybook 创建playbook
-func (h *Handler) CreatePlaybook(c *gin.Context) {
-	userID := c.GetUint("user_id")
-	
-	var req PlaybookRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
-		return
+	var err error
+	if filter.From, err = parseHistoryTime(c.Query("from"), false); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid from time"))
+		return nil, false
+	}
+	if filter.To, err = parseHistoryTime(c.Query("to"), true); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid to time"))
+		return nil, false
 	}
 	
-	playbook, err := h.service.CreatePlaybook(userID, &req)
-	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
-			c.JSON(http.StatusConflict, common.ErrorResponse("Playbook name already exists"))
-			return
+	// 管理员可以查看所有用户的执行，其他用户只能查看自己的
+	userID := c.GetUint("user_id")
+	if !isAdmin(c) {
+		if filter.UserID != 0 && filter.UserID != userID {
+			c.JSON(http.StatusForbidden, common.ErrorResponse("Insufficient permissions"))
+			return nil, false
 		}
-		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Create playbook failed"))
-		return
+		filter.UserID = userID
 	}
 	
-	c.JSON(http.StatusOK, common.SuccessResponse("Playbook created successfully", playbook))
+	return filter, true
 }
 
-// ListPlaybooks 列出playbook
-func (h *Handler) ListPlaybooks(c *gin.Context) {
+// ListPendingApprovals 列出等待审批的执行，只有审批人可以查看
+func (h *Handler) ListPendingApprovals(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	
 	// 解析分页参数
@@ -314,126 +535,2488 @@ func (h *Handler) ListPlaybooks(c *gin.Context) {
 	
 	offset := (page - 1) * pageSize
 	
-	playbooks, total, err := h.service.ListPlaybooks(userID, offset, pageSize)
+	executions, total, err := h.service.ListPendingApprovals(userID, offset, pageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get playbooks failed"))
+		if errors.Is(err, ErrNotApprover) {
+			c.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get pending approvals failed"))
 		return
 	}
 	
 	response := map[string]interface{}{
-		"data":       playbooks,
+		"data":       executions,
 		"total":      total,
 		"page":       page,
 		"page_size":  pageSize,
 		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
 	}
 	
-	c.JSON(http.StatusOK, common.SuccessResponse("Playbooks retrieved successfully", response))
+	c.JSON(http.StatusOK, common.SuccessResponse("Pending approvals retrieved successfully", response))
 }
 
-// GetPlaybook 获取playbook详情
-func (h *Handler) GetPlaybook(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid playbook ID"))
-		return
-	}
-	
-	playbook, err := h.service.GetPlaybook(uint(id))
-	if err != nil {
-		c.JSON(http.StatusNotFound, common.ErrorResponse("Playbook not found"))
-		return
-	}
-	
-	c.JSON(http.StatusOK, common.SuccessResponse("Playbook retrieved successfully", playbook))
+// ApproveExecution 批准等待审批的执行，type查询参数指定执行类型
+func (h *Handler) ApproveExecution(c *gin.Context) {
+	h.decideApproval(c, true)
 }
 
-// UpdatePlaybook 更新playbook
-func (h *Handler) UpdatePlaybook(c *gin.Context) {
+// RejectExecution 拒绝等待审批的执行，type查询参数指定执行类型
+func (h *Handler) RejectExecution(c *gin.Context) {
+	h.decideApproval(c, false)
+}
+
+// decideApproval 处理批准或拒绝请求
+func (h *Handler) decideApproval(c *gin.Context, approve bool) {
 	userID := c.GetUint("user_id")
 	
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid playbook ID"))
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid execution ID"))
 		return
 	}
 	
-	var req PlaybookRequest
+	kind, ok := parseExecutionType(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid execution type"))
+		return
+	}
+	
+	var req ApprovalDecisionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
 		return
 	}
 	
-	playbook, err := h.service.UpdatePlaybook(uint(id), userID, &req)
+	message := "Execution rejected successfully"
+	if approve {
+		err = h.service.ApproveExecution(kind, uint(id), userID, req.Comment)
+		message = "Execution approved successfully"
+	} else {
+		err = h.service.RejectExecution(kind, uint(id), userID, req.Comment)
+	}
 	if err != nil {
-		if strings.Contains(err.Error(), "record not found") {
-			c.JSON(http.StatusNotFound, common.ErrorResponse("Playbook not found"))
+		if errors.Is(err, ErrNotApprover) || errors.Is(err, ErrSelfApproval) {
+			c.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
 			return
 		}
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
-			c.JSON(http.StatusConflict, common.ErrorResponse("Playbook name already exists"))
+		if errors.Is(err, ErrApprovalNotPending) {
+			c.JSON(http.StatusConflict, common.ErrorResponse(err.Error()))
 			return
 		}
-		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Update playbook failed"))
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Execution not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Update approval failed"))
 		return
 	}
 	
-	c.JSON(http.StatusOK, common.SuccessResponse("Playbook updated successfully", playbook))
+	c.JSON(http.StatusOK, common.SuccessResponse(message, map[string]string{"message": message}))
 }
 
-// DeletePlaybook 删除playbook
-func (h *Handler) DeletePlaybook(c *gin.Context) {
-	userID := c.GetUint("user_id")
-	
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+// ListProtectedTargets 列出受保护的服务器组、inventory、playbook和项目
+func (h *Handler) ListProtectedTargets(c *gin.Context) {
+	targets, err := h.service.ListProtectedTargets()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid playbook ID"))
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get protected targets failed"))
 		return
 	}
 	
-	err = h.service.DeletePlaybook(uint(id), userID)
-	if err != nil {
+	c.JSON(http.StatusOK, common.SuccessResponse("Protected targets retrieved successfully", targets))
+}
+
+// SetProtectedTarget 设置服务器组、inventory、playbook或项目是否受保护，仅管理员
+func (h *Handler) SetProtectedTarget(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	
+	var req ProtectedTargetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	if err := h.service.SetProtectedTarget(&req); err != nil {
 		if strings.Contains(err.Error(), "record not found") {
-			c.JSON(http.StatusNotFound, common.ErrorResponse("Playbook not found"))
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Protected target not found"))
 			return
 		}
-		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Delete playbook failed"))
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Set protected target failed"))
 		return
 	}
 	
-	c.JSON(http.StatusOK, common.SuccessResponse("Playbook deleted successfully", map[string]string{"message": "Playbook deleted successfully"}))
+	c.JSON(http.StatusOK, common.SuccessResponse("Protected target updated successfully", req))
 }
 
-// GetExecutionStats 获取执行统计信息
-func (h *Handler) GetExecutionStats(c *gin.Context) {
-	userID := c.GetUint("user_id")
-	
+// parseHistoryTime 解析RFC3339时间或日期，作为上限的日期取次日零点
+func parseHistoryTime(value string, end bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// RelaunchExecution 重新执行，failed_hosts模式只对原执行中失败或不可达的主机执行
+func (h *Handler) RelaunchExecution(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid execution ID"))
+		return
+	}
+	
+	kind, ok := parseExecutionType(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid execution type"))
+		return
+	}
+	
+	// 使用默认模式时可以不提供请求体
+	var req ExecutionRelaunchRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	var execution interface{}
+	if kind == ExecutionTypePlaybook {
+		execution, err = h.service.RelaunchPlaybookExecution(c.Request.Context(), uint(id), userID, &req)
+	} else {
+		execution, err = h.service.RelaunchAdhocExecution(c.Request.Context(), uint(id), userID, &req)
+	}
+	if err != nil {
+		if policyErrorResponse(c, err) {
+			return
+		}
+		if errors.Is(err, ErrModuleNotAllowed) {
+			c.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, ErrExecutionNotFinished) || errors.Is(err, ErrNoFailedHosts) {
+			c.JSON(http.StatusConflict, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, ErrInventoryNotFound) {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Inventory not found"))
+			return
+		}
+		if errors.Is(err, ErrVaultSecretNotFound) || errors.Is(err, ErrRevisionNotFound) || errors.Is(err, ErrProjectFileNotFound) {
+			c.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, ErrRepositoryNotSynced) {
+			c.JSON(http.StatusConflict, common.ErrorResponse(err.Error()))
+			return
+		}
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Execution not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Relaunch execution failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Execution relaunched successfully", execution))
+}
+
+// parseExecutionType 解析type查询参数，默认为adhoc
+func parseExecutionType(c *gin.Context) (string, bool) {
+	kind := c.DefaultQuery("type", ExecutionTypeAdhoc)
+	switch kind {
+	case ExecutionTypeAdhoc, ExecutionTypePlaybook:
+		return kind, true
+	default:
+		return "", false
+	}
+}
+
+// CreateInventory 创建inventory
+func (h *Handler) CreateInventory(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	var req InventoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	inventory, err := h.service.CreateInventory(userID, &req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidInventory) {
+			c.JSON(http.StatusBadRequest, inventoryErrorResponse(err))
+			return
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			c.JSON(http.StatusConflict, common.ErrorResponse("Inventory name already exists"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Create inventory failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Inventory created successfully", inventory))
+}
+
+// ListInventories 列出inventory
+func (h *Handler) ListInventories(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	// 解析分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	
+	offset := (page - 1) * pageSize
+	
+	inventories, total, err := h.service.ListInventories(userID, offset, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get inventories failed"))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"data":       inventories,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Inventories retrieved successfully", response))
+}
+
+// GetInventory 获取inventory详情
+func (h *Handler) GetInventory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid inventory ID"))
+		return
+	}
+	
+	inventory, err := h.service.GetInventory(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.ErrorResponse("Inventory not found"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Inventory retrieved successfully", inventory))
+}
+
+// UpdateInventory 更新inventory
+func (h *Handler) UpdateInventory(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid inventory ID"))
+		return
+	}
+	
+	var req InventoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	inventory, err := h.service.UpdateInventory(uint(id), userID, &req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidInventory) {
+			c.JSON(http.StatusBadRequest, inventoryErrorResponse(err))
+			return
+		}
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Inventory not found"))
+			return
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			c.JSON(http.StatusConflict, common.ErrorResponse("Inventory name already exists"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Update inventory failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Inventory updated successfully", inventory))
+}
+
+// DeleteInventory 删除inventory
+func (h *Handler) DeleteInventory(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid inventory ID"))
+		return
+	}
+	
+	err = h.service.DeleteInventory(uint(id), userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Inventory not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Delete inventory failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Inventory deleted successfully", map[string]string{"message": "Inventory deleted successfully"}))
+}
+
+// GetDefaultInventory 获取默认inventory
+func (h *Handler) GetDefaultInventory(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	inventory, err := h.service.GetDefaultInventory(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.ErrorResponse("Default inventory not found"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Default inventory retrieved successfully", inventory))
+}
+
+// GetInventoryGraph 获取inventory解析后的组树和主机变量
+func (h *Handler) GetInventoryGraph(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid inventory ID"))
+		return
+	}
+	
+	graph, err := h.service.GetInventoryGraph(uint(id), userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Inventory not found"))
+			return
+		}
+		if errors.Is(err, ErrInvalidInventory) {
+			c.JSON(http.StatusUnprocessableEntity, inventoryErrorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get inventory graph failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Inventory graph retrieved successfully", graph))
+}
+
+// ListInventoryVars 列出inventory的group_vars和host_vars，可通过kind=group|host过滤
+func (h *Handler) ListInventoryVars(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid inventory ID"))
+		return
+	}
+	
+	kind := c.Query("kind")
+	if kind != "" && kind != InventoryVarsGroup && kind != InventoryVarsHost {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid vars kind"))
+		return
+	}
+	
+	vars, err := h.service.ListInventoryVars(uint(id), kind)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Inventory not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get inventory vars failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Inventory vars retrieved successfully", vars))
+}
+
+// GetInventoryVars 获取指定组或主机的变量
+func (h *Handler) GetInventoryVars(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid inventory ID"))
+		return
+	}
+	
+	vars, err := h.service.GetInventoryVars(uint(id), inventoryVarsKind(c), c.Param("name"))
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Inventory vars not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get inventory vars failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Inventory vars retrieved successfully", vars))
+}
+
+// SaveInventoryVars 创建或替换指定组或主机的变量
+func (h *Handler) SaveInventoryVars(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid inventory ID"))
+		return
+	}
+	
+	var req InventoryVarsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	vars, err := h.service.SaveInventoryVars(uint(id), userID, inventoryVarsKind(c), c.Param("name"), &req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidInventoryVars) {
+			response := common.ErrorResponse(err.Error())
+			var validationErr *InventoryVarsValidationError
+			if errors.As(err, &validationErr) {
+				response["errors"] = validationErr.Errors
+			}
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Inventory not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Save inventory vars failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Inventory vars saved successfully", vars))
+}
+
+// DeleteInventoryVars 删除指定组或主机的变量
+func (h *Handler) DeleteInventoryVars(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid inventory ID"))
+		return
+	}
+	
+	err = h.service.DeleteInventoryVars(uint(id), userID, inventoryVarsKind(c), c.Param("name"))
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Inventory vars not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Delete inventory vars failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Inventory vars deleted successfully", map[string]string{"message": "Inventory vars deleted successfully"}))
+}
+
+// inventoryVarsKind 根据路由判断操作的是group_vars还是host_vars
+func inventoryVarsKind(c *gin.Context) string {
+	if strings.Contains(c.FullPath(), "/host_vars/") {
+		return InventoryVarsHost
+	}
+	return InventoryVarsGroup
+}
+
+// inventoryErrorResponse 构建inventory校验失败的响应，包含每处错误的行号
+func inventoryErrorResponse(err error) map[string]interface{} {
+	response := common.ErrorResponse(err.Error())
+	
+	var validationErr *InventoryValidationError
+	if errors.As(err, &validationErr) {
+		response["errors"] = validationErr.Errors
+	}
+	return response
+}
+
+// GetDynamicInventory 获取根据受管服务器生成的inventory，直接返回ansible-inventory --list格式的JSON
+func (h *Handler) GetDynamicInventory(c *gin.Context) {
+	filter := &DynamicInventoryFilter{
+		Groups: queryList(c, "group"),
+		Tags:   queryList(c, "tag"),
+	}
+	
+	inventory, err := h.service.GetDynamicInventory(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get dynamic inventory failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, inventory)
+}
+
+// queryList 解析可重复且支持逗号分隔的查询参数
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, value := range c.QueryArray(key) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+// CreatePlaybook 创建playbook
+func (h *Handler) CreatePlaybook(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	var req PlaybookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	playbook, err := h.service.CreatePlaybook(userID, &req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidPlaybook) {
+			c.JSON(http.StatusBadRequest, playbookErrorResponse(err))
+			return
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			c.JSON(http.StatusConflict, common.ErrorResponse("Playbook name already exists"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Create playbook failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Playbook created successfully", playbook))
+}
+
+// ListPlaybooks 列出playbook
+func (h *Handler) ListPlaybooks(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	// 解析分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	
+	offset := (page - 1) * pageSize
+	
+	playbooks, total, err := h.service.ListPlaybooks(userID, offset, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get playbooks failed"))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"data":       playbooks,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Playbooks retrieved successfully", response))
+}
+
+// GetPlaybook 获取playbook详情
+func (h *Handler) GetPlaybook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid playbook ID"))
+		return
+	}
+	
+	playbook, err := h.service.GetPlaybook(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.ErrorResponse("Playbook not found"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Playbook retrieved successfully", playbook))
+}
+
+// UpdatePlaybook 更新playbook
+func (h *Handler) UpdatePlaybook(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid playbook ID"))
+		return
+	}
+	
+	var req PlaybookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	playbook, err := h.service.UpdatePlaybook(uint(id), userID, &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Playbook not found"))
+			return
+		}
+		if errors.Is(err, ErrInvalidPlaybook) {
+			c.JSON(http.StatusBadRequest, playbookErrorResponse(err))
+			return
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			c.JSON(http.StatusConflict, common.ErrorResponse("Playbook name already exists"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Update playbook failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Playbook updated successfully", playbook))
+}
+
+// DeletePlaybook 删除playbook
+func (h *Handler) DeletePlaybook(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid playbook ID"))
+		return
+	}
+	
+	err = h.service.DeletePlaybook(uint(id), userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Playbook not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Delete playbook failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Playbook deleted successfully", map[string]string{"message": "Playbook deleted successfully"}))
+}
+
+// ValidatePlaybook 校验playbook内容但不保存，供编辑器实时检查
+func (h *Handler) ValidatePlaybook(c *gin.Context) {
+	var req PlaybookValidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	result, err := h.service.ValidatePlaybook(c.Request.Context(), req.Content)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Validate playbook failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Playbook validated successfully", result))
+}
+
+// playbookErrorResponse 构建playbook校验失败的响应，包含每处错误的行号和列号
+func playbookErrorResponse(err error) map[string]interface{} {
+	response := common.ErrorResponse(err.Error())
+	
+	var validationErr *PlaybookValidationError
+	if errors.As(err, &validationErr) {
+		response["errors"] = validationErr.Errors
+	}
+	return response
+}
+
+// ListPlaybookRevisions 列出playbook的历史版本
+func (h *Handler) ListPlaybookRevisions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid playbook ID"))
+		return
+	}
+	
+	// 解析分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	
+	offset := (page - 1) * pageSize
+	
+	revisions, total, err := h.service.ListPlaybookRevisions(uint(id), offset, pageSize)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Playbook not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get playbook revisions failed"))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"data":       revisions,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Playbook revisions retrieved successfully", response))
+}
+
+// GetPlaybookRevision 获取playbook指定版本的内容
+func (h *Handler) GetPlaybookRevision(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid playbook ID"))
+		return
+	}
+	
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || revision < 1 {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid revision"))
+		return
+	}
+	
+	result, err := h.service.GetPlaybookRevision(uint(id), revision)
+	if err != nil {
+		revisionErrorResponse(c, err, "Get playbook revision failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Playbook revision retrieved successfully", result))
+}
+
+// DiffPlaybookRevisions 比较playbook的两个版本，默认比较当前版本和上一个版本
+func (h *Handler) DiffPlaybookRevisions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid playbook ID"))
+		return
+	}
+	
+	from, err := strconv.Atoi(c.DefaultQuery("from", "0"))
+	if err != nil || from < 0 {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid from revision"))
+		return
+	}
+	to, err := strconv.Atoi(c.DefaultQuery("to", "0"))
+	if err != nil || to < 0 {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid to revision"))
+		return
+	}
+	
+	diff, err := h.service.DiffPlaybookRevisions(uint(id), from, to)
+	if err != nil {
+		revisionErrorResponse(c, err, "Diff playbook revisions failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Playbook diff retrieved successfully", diff))
+}
+
+// RestorePlaybookRevision 将playbook恢复为指定的历史版本
+func (h *Handler) RestorePlaybookRevision(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid playbook ID"))
+		return
+	}
+	
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || revision < 1 {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid revision"))
+		return
+	}
+	
+	// 请求体可选
+	var req PlaybookRestoreRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+			return
+		}
+	}
+	
+	playbook, err := h.service.RestorePlaybookRevision(uint(id), revision, userID, req.Message)
+	if err != nil {
+		revisionErrorResponse(c, err, "Restore playbook revision failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Playbook revision restored successfully", playbook))
+}
+
+// revisionErrorResponse 将playbook版本相关的错误转换为响应
+func revisionErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrRevisionNotFound):
+		c.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+	case strings.Contains(err.Error(), "record not found"):
+		c.JSON(http.StatusNotFound, common.ErrorResponse("Playbook not found"))
+	default:
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse(message))
+	}
+}
+
+// ExecutePlaybook 执行playbook
+func (h *Handler) ExecutePlaybook(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid playbook ID"))
+		return
+	}
+	
+	var req PlaybookExecutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	execution, err := h.service.ExecutePlaybook(c.Request.Context(), userID, uint(id), &req)
+	if err != nil {
+		if errors.Is(err, ErrInventoryNotFound) {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Inventory not found"))
+			return
+		}
+		if errors.Is(err, ErrVaultSecretNotFound) {
+			c.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, ErrInvalidInventory) {
+			c.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, ErrRevisionNotFound) {
+			c.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Playbook not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Execute playbook failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Playbook executed successfully", execution))
+}
+
+// ListPlaybookExecutions 列出playbook执行记录
+func (h *Handler) ListPlaybookExecutions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	// 解析分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	
+	offset := (page - 1) * pageSize
+	
+	executions, total, err := h.service.ListPlaybookExecutions(userID, offset, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get executions failed"))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"data":       executions,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Executions retrieved successfully", response))
+}
+
+// GetPlaybookExecution 获取playbook执行记录详情
+func (h *Handler) GetPlaybookExecution(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid execution ID"))
+		return
+	}
+	
+	execution, err := h.service.GetPlaybookExecution(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.ErrorResponse("Execution not found"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Execution retrieved successfully", execution))
+}
+
+// CreateProject 创建项目
+func (h *Handler) CreateProject(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	var req ProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	project, err := h.service.CreateProject(userID, &req)
+	if err != nil {
+		projectErrorResponse(c, err, "Create project failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Project created successfully", project))
+}
+
+// ListProjects 列出项目
+func (h *Handler) ListProjects(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	// 解析分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	
+	offset := (page - 1) * pageSize
+	
+	projects, total, err := h.service.ListProjects(userID, offset, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get projects failed"))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"data":       projects,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Projects retrieved successfully", response))
+}
+
+// GetProject 获取项目详情
+func (h *Handler) GetProject(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid project ID"))
+		return
+	}
+	
+	project, err := h.service.GetProject(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.ErrorResponse("Project not found"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Project retrieved successfully", project))
+}
+
+// UpdateProject 更新项目
+func (h *Handler) UpdateProject(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid project ID"))
+		return
+	}
+	
+	var req ProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	project, err := h.service.UpdateProject(uint(id), userID, &req)
+	if err != nil {
+		projectErrorResponse(c, err, "Update project failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Project updated successfully", project))
+}
+
+// DeleteProject 删除项目
+func (h *Handler) DeleteProject(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid project ID"))
+		return
+	}
+	
+	err = h.service.DeleteProject(uint(id), userID)
+	if err != nil {
+		projectErrorResponse(c, err, "Delete project failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Project deleted successfully", map[string]string{"message": "Project deleted successfully"}))
+}
+
+// UploadProjectArchive 上传tar.gz或zip压缩包填充项目文件，replace=true时替换全部文件
+func (h *Handler) UploadProjectArchive(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid project ID"))
+		return
+	}
+	
+	// 为multipart表单的其他部分保留余量
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxProjectArchiveSize+1<<20)
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Archive file is required"))
+		return
+	}
+	defer file.Close()
+	
+	replace := c.Query("replace") == "true"
+	if err := h.service.UploadProjectArchive(uint(id), userID, file, replace); err != nil {
+		projectErrorResponse(c, err, "Upload project archive failed")
+		return
+	}
+	
+	tree, err := h.service.GetProjectTree(uint(id))
+	if err != nil {
+		projectErrorResponse(c, err, "Get project files failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Project archive uploaded successfully", tree))
+}
+
+// GetProjectTree 获取项目文件树
+func (h *Handler) GetProjectTree(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid project ID"))
+		return
+	}
+	
+	tree, err := h.service.GetProjectTree(uint(id))
+	if err != nil {
+		projectErrorResponse(c, err, "Get project files failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Project files retrieved successfully", tree))
+}
+
+// ListProjectPlaybooks 列出项目中可作为入口的playbook
+func (h *Handler) ListProjectPlaybooks(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid project ID"))
+		return
+	}
+	
+	playbooks, err := h.service.ListProjectPlaybooks(uint(id))
+	if err != nil {
+		projectErrorResponse(c, err, "Get project playbooks failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Project playbooks retrieved successfully", playbooks))
+}
+
+// GetProjectFile 读取项目文件内容
+func (h *Handler) GetProjectFile(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid project ID"))
+		return
+	}
+	
+	filePath := c.Param("path")
+	content, err := h.service.ReadProjectFile(uint(id), filePath)
+	if err != nil {
+		projectErrorResponse(c, err, "Read project file failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Project file retrieved successfully", map[string]string{
+		"path":    strings.TrimPrefix(filePath, "/"),
+		"content": content,
+	}))
+}
+
+// WriteProjectFile 创建或更新项目文件
+func (h *Handler) WriteProjectFile(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid project ID"))
+		return
+	}
+	
+	var req ProjectFileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	if err := h.service.WriteProjectFile(uint(id), userID, c.Param("path"), req.Content); err != nil {
+		projectErrorResponse(c, err, "Write project file failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Project file saved successfully", map[string]string{"path": strings.TrimPrefix(c.Param("path"), "/")}))
+}
+
+// DeleteProjectFile 删除项目文件或目录
+func (h *Handler) DeleteProjectFile(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid project ID"))
+		return
+	}
+	
+	if err := h.service.DeleteProjectFile(uint(id), userID, c.Param("path")); err != nil {
+		projectErrorResponse(c, err, "Delete project file failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Project file deleted successfully", map[string]string{"message": "Project file deleted successfully"}))
+}
+
+// ExecuteProject 执行项目中的入口playbook
+func (h *Handler) ExecuteProject(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid project ID"))
+		return
+	}
+	
+	var req PlaybookExecutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	execution, err := h.service.ExecuteProject(c.Request.Context(), userID, uint(id), &req)
+	if err != nil {
+		if errors.Is(err, ErrInventoryNotFound) {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Inventory not found"))
+			return
+		}
+		if errors.Is(err, ErrVaultSecretNotFound) {
+			c.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, ErrInvalidInventory) {
+			c.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		projectErrorResponse(c, err, "Execute project failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Project executed successfully", execution))
+}
+
+// SyncProject 立即同步git项目
+func (h *Handler) SyncProject(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid project ID"))
+		return
+	}
+	
+	project, err := h.service.SyncProject(uint(id), userID)
+	if err != nil {
+		projectErrorResponse(c, err, "Sync project failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Project synced successfully", project))
+}
+
+// projectErrorResponse 将项目相关的错误转换为响应
+func projectErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrInvalidProjectPath), errors.Is(err, ErrInvalidArchive), errors.Is(err, ErrInvalidRepository):
+		c.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+	case errors.Is(err, ErrProjectReadOnly), errors.Is(err, ErrRepositoryNotSynced):
+		c.JSON(http.StatusConflict, common.ErrorResponse(err.Error()))
+	case errors.Is(err, ErrRepositorySync):
+		c.JSON(http.StatusBadGateway, common.ErrorResponse(err.Error()))
+	case errors.Is(err, ErrProjectFileNotFound):
+		c.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+	case strings.Contains(err.Error(), "record not found"):
+		c.JSON(http.StatusNotFound, common.ErrorResponse("Project not found"))
+	case strings.Contains(err.Error(), "UNIQUE constraint failed"):
+		c.JSON(http.StatusConflict, common.ErrorResponse("Project name already exists"))
+	default:
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse(message))
+	}
+}
+
+// CreateSchedule 创建定时任务
+func (h *Handler) CreateSchedule(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	schedule, err := h.service.CreateSchedule(userID, &req)
+	if err != nil {
+		if errors.Is(err, ErrModuleNotAllowed) {
+			c.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, ErrInvalidSchedule) {
+			c.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			c.JSON(http.StatusConflict, common.ErrorResponse("Schedule name already exists"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Create schedule failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Schedule created successfully", schedule))
+}
+
+// ListSchedules 列出定时任务
+func (h *Handler) ListSchedules(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	// 解析分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	
+	offset := (page - 1) * pageSize
+	
+	schedules, total, err := h.service.ListSchedules(userID, offset, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get schedules failed"))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"data":       schedules,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Schedules retrieved successfully", response))
+}
+
+// GetSchedule 获取定时任务详情
+func (h *Handler) GetSchedule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid schedule ID"))
+		return
+	}
+	
+	schedule, err := h.service.GetSchedule(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.ErrorResponse("Schedule not found"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Schedule retrieved successfully", schedule))
+}
+
+// UpdateSchedule 更新定时任务
+func (h *Handler) UpdateSchedule(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid schedule ID"))
+		return
+	}
+	
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	schedule, err := h.service.UpdateSchedule(uint(id), userID, &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Schedule not found"))
+			return
+		}
+		if errors.Is(err, ErrModuleNotAllowed) {
+			c.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, ErrInvalidSchedule) {
+			c.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			c.JSON(http.StatusConflict, common.ErrorResponse("Schedule name already exists"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Update schedule failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Schedule updated successfully", schedule))
+}
+
+// DeleteSchedule 删除定时任务
+func (h *Handler) DeleteSchedule(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid schedule ID"))
+		return
+	}
+	
+	err = h.service.DeleteSchedule(uint(id), userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Schedule not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Delete schedule failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Schedule deleted successfully", map[string]string{"message": "Schedule deleted successfully"}))
+}
+
+// CreateJobTemplate 创建作业模板
+func (h *Handler) CreateJobTemplate(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	var req JobTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	template, err := h.service.CreateJobTemplate(userID, &req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidJobTemplate) {
+			c.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, ErrVaultSecretNotFound) {
+			c.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			c.JSON(http.StatusConflict, common.ErrorResponse("Job template name already exists"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Create job template failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Job template created successfully", template))
+}
+
+// ListJobTemplates 列出作业模板
+func (h *Handler) ListJobTemplates(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	// 解析分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	
+	offset := (page - 1) * pageSize
+	
+	templates, total, err := h.service.ListJobTemplates(userID, offset, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get job templates failed"))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"data":       templates,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Job templates retrieved successfully", response))
+}
+
+// GetJobTemplate 获取作业模板详情
+func (h *Handler) GetJobTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid job template ID"))
+		return
+	}
+	
+	template, err := h.service.GetJobTemplate(uint(id))
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Job template not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get job template failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Job template retrieved successfully", template))
+}
+
+// UpdateJobTemplate 更新作业模板
+func (h *Handler) UpdateJobTemplate(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid job template ID"))
+		return
+	}
+	
+	var req JobTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	template, err := h.service.UpdateJobTemplate(uint(id), userID, &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Job template not found"))
+			return
+		}
+		if errors.Is(err, ErrInvalidJobTemplate) {
+			c.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, ErrVaultSecretNotFound) {
+			c.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			c.JSON(http.StatusConflict, common.ErrorResponse("Job template name already exists"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Update job template failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Job template updated successfully", template))
+}
+
+// DeleteJobTemplate 删除作业模板
+func (h *Handler) DeleteJobTemplate(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid job template ID"))
+		return
+	}
+	
+	err = h.service.DeleteJobTemplate(uint(id), userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Job template not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Delete job template failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Job template deleted successfully", map[string]string{"message": "Job template deleted successfully"}))
+}
+
+// LaunchJobTemplate 根据问卷答案启动作业模板
+func (h *Handler) LaunchJobTemplate(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid job template ID"))
+		return
+	}
+	
+	// 没有问卷或全部使用默认值时可以不提供请求体
+	var req JobTemplateLaunchRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	execution, err := h.service.LaunchJobTemplate(c.Request.Context(), uint(id), userID, &req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSurveyAnswers) {
+			response := common.ErrorResponse(err.Error())
+			var validationErr *SurveyValidationError
+			if errors.As(err, &validationErr) {
+				response["errors"] = validationErr.Errors
+			}
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+		if errors.Is(err, ErrInventoryNotFound) {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Inventory not found"))
+			return
+		}
+		if errors.Is(err, ErrVaultSecretNotFound) {
+			c.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, ErrInvalidInventory) {
+			c.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Job template not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Launch job template failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Job template launched successfully", execution))
+}
+
+// CreateWorkflow 创建工作流
+func (h *Handler) CreateWorkflow(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	var req WorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	workflow, err := h.service.CreateWorkflow(userID, &req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidWorkflow) {
+			c.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			c.JSON(http.StatusConflict, common.ErrorResponse("Workflow name already exists"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Create workflow failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Workflow created successfully", workflow))
+}
+
+// ListWorkflows 列出工作流
+func (h *Handler) ListWorkflows(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	// 解析分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	
+	offset := (page - 1) * pageSize
+	
+	workflows, total, err := h.service.ListWorkflows(userID, offset, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get workflows failed"))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"data":       workflows,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Workflows retrieved successfully", response))
+}
+
+// GetWorkflow 获取工作流详情
+func (h *Handler) GetWorkflow(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid workflow ID"))
+		return
+	}
+	
+	workflow, err := h.service.GetWorkflow(uint(id))
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Workflow not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get workflow failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Workflow retrieved successfully", workflow))
+}
+
+// UpdateWorkflow 更新工作流
+func (h *Handler) UpdateWorkflow(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid workflow ID"))
+		return
+	}
+	
+	var req WorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	workflow, err := h.service.UpdateWorkflow(uint(id), userID, &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Workflow not found"))
+			return
+		}
+		if errors.Is(err, ErrInvalidWorkflow) {
+			c.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			c.JSON(http.StatusConflict, common.ErrorResponse("Workflow name already exists"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Update workflow failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Workflow updated successfully", workflow))
+}
+
+// DeleteWorkflow 删除工作流
+func (h *Handler) DeleteWorkflow(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid workflow ID"))
+		return
+	}
+	
+	err = h.service.DeleteWorkflow(uint(id), userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Workflow not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Delete workflow failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Workflow deleted successfully", map[string]string{"message": "Workflow deleted successfully"}))
+}
+
+// LaunchWorkflow 启动工作流
+func (h *Handler) LaunchWorkflow(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid workflow ID"))
+		return
+	}
+	
+	// 不传额外变量时可以不提供请求体
+	var req WorkflowLaunchRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	run, err := h.service.LaunchWorkflow(uint(id), userID, &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Workflow not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Launch workflow failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Workflow launched successfully", run))
+}
+
+// ListWorkflowRuns 列出工作流的运行记录
+func (h *Handler) ListWorkflowRuns(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid workflow ID"))
+		return
+	}
+	
+	// 解析分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	
+	offset := (page - 1) * pageSize
+	
+	runs, total, err := h.service.ListWorkflowRuns(uint(id), userID, offset, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get workflow runs failed"))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"data":       runs,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Workflow runs retrieved successfully", response))
+}
+
+// GetWorkflowRun 获取工作流运行的图状态，包括每个节点的状态和子执行ID
+func (h *Handler) GetWorkflowRun(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid workflow run ID"))
+		return
+	}
+	
+	run, err := h.service.GetWorkflowRun(uint(id))
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Workflow run not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get workflow run failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Workflow run retrieved successfully", run))
+}
+
+// CancelWorkflowRun 取消工作流运行
+func (h *Handler) CancelWorkflowRun(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid workflow run ID"))
+		return
+	}
+	
+	err = h.service.CancelWorkflowRun(uint(id), userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Workflow run not found"))
+			return
+		}
+		if errors.Is(err, ErrExecutionNotRunning) {
+			c.JSON(http.StatusConflict, common.ErrorResponse("Workflow run is not running"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Cancel workflow run failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Workflow run cancelled successfully", map[string]interface{}{"id": id}))
+}
+
+// GetExecutionStats 获取执行统计信息
+func (h *Handler) GetExecutionStats(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
 	stats, err := h.service.GetExecutionStats(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get execution stats failed"))
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get execution stats failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Execution stats retrieved successfully", stats))
+}
+
+// CheckAnsible 检查ansible安装状态
+func (h *Handler) CheckAnsible(c *gin.Context) {
+	err := h.service.CheckAnsibleInstallation()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, common.ErrorResponse("Ansible not available"))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"ansible_available": true,
+		"message": "Ansible is installed and available",
+	}
+	c.JSON(http.StatusOK, common.SuccessResponse("Ansible status checked successfully", response))
+}
+
+// GetAllowedModules 获取当前用户可以使用的模块列表
+func (h *Handler) GetAllowedModules(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	modules, err := h.service.AllowedModules(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get modules failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Modules retrieved successfully", modules))
+}
+
+// ListModules 列出模块允许列表
+func (h *Handler) ListModules(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	
+	modules, err := h.service.ListModules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get modules failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Modules retrieved successfully", modules))
+}
+
+// CreateModule 添加模块到允许列表
+func (h *Handler) CreateModule(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	
+	var req AnsibleModuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	module, err := h.service.CreateModule(&req)
+	if err != nil {
+		moduleErrorResponse(c, err, "Create module failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Module created successfully", module))
+}
+
+// UpdateModule 更新允许列表中的模块
+func (h *Handler) UpdateModule(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid module ID"))
+		return
+	}
+	
+	var req AnsibleModuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	module, err := h.service.UpdateModule(uint(id), &req)
+	if err != nil {
+		moduleErrorResponse(c, err, "Update module failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Module updated successfully", module))
+}
+
+// DeleteModule 从允许列表删除模块
+func (h *Handler) DeleteModule(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid module ID"))
+		return
+	}
+	
+	if err := h.service.DeleteModule(uint(id)); err != nil {
+		moduleErrorResponse(c, err, "Delete module failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Module deleted successfully", map[string]string{"message": "Module deleted successfully"}))
+}
+
+// ListModuleGrants 列出模块授权
+func (h *Handler) ListModuleGrants(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	
+	grants, err := h.service.ListModuleGrants()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get module grants failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Module grants retrieved successfully", grants))
+}
+
+// SaveModuleGrant 设置角色或用户的模块授权
+func (h *Handler) SaveModuleGrant(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	
+	var req ModuleGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	grant, err := h.service.SaveModuleGrant(&req)
+	if err != nil {
+		moduleErrorResponse(c, err, "Save module grant failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Module grant saved successfully", grant))
+}
+
+// DeleteModuleGrant 删除模块授权
+func (h *Handler) DeleteModuleGrant(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid module grant ID"))
 		return
 	}
 	
-	c.JSON(http.StatusOK, common.SuccessResponse("Execution stats retrieved successfully", stats))
+	if err := h.service.DeleteModuleGrant(uint(id)); err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			c.JSON(http.StatusNotFound, common.ErrorResponse("Module grant not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Delete module grant failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Module grant deleted successfully", map[string]string{"message": "Module grant deleted successfully"}))
 }
 
-// CheckAnsible 检查ansible安装状态
-func (h *Handler) CheckAnsible(c *gin.Context) {
-	err := h.service.CheckAnsibleInstallation()
+// moduleErrorResponse 将模块相关的错误转换为响应
+func moduleErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrInvalidModule):
+		c.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+	case strings.Contains(err.Error(), "record not found"):
+		c.JSON(http.StatusNotFound, common.ErrorResponse("Module not found"))
+	case strings.Contains(err.Error(), "UNIQUE constraint failed"):
+		c.JSON(http.StatusConflict, common.ErrorResponse("Module name already exists"))
+	default:
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse(message))
+	}
+}
+
+// ListPolicyRules 列出策略规则
+func (h *Handler) ListPolicyRules(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	
+	rules, err := h.service.ListPolicyRules()
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, common.ErrorResponse("Ansible not available"))
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get policy rules failed"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Policy rules retrieved successfully", rules))
+}
+
+// CreatePolicyRule 创建策略规则
+func (h *Handler) CreatePolicyRule(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	
+	var req PolicyRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	rule, err := h.service.CreatePolicyRule(&req)
+	if err != nil {
+		policyRuleErrorResponse(c, err, "Create policy rule failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Policy rule created successfully", rule))
+}
+
+// UpdatePolicyRule 更新策略规则
+func (h *Handler) UpdatePolicyRule(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid policy rule ID"))
+		return
+	}
+	
+	var req PolicyRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	rule, err := h.service.UpdatePolicyRule(uint(id), &req)
+	if err != nil {
+		policyRuleErrorResponse(c, err, "Update policy rule failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Policy rule updated successfully", rule))
+}
+
+// DeletePolicyRule 删除策略规则
+func (h *Handler) DeletePolicyRule(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid policy rule ID"))
+		return
+	}
+	
+	if err := h.service.DeletePolicyRule(uint(id)); err != nil {
+		policyRuleErrorResponse(c, err, "Delete policy rule failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Policy rule deleted successfully", map[string]string{"message": "Policy rule deleted successfully"}))
+}
+
+// ListPolicyAudits 列出策略阻止、警告和确认执行的记录，可按action和user_id过滤
+func (h *Handler) ListPolicyAudits(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	
+	action := c.Query("action")
+	switch action {
+	case "", PolicyAuditBlocked, PolicyAuditWarned, PolicyAuditOverridden:
+	default:
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid audit action"))
+		return
+	}
+	
+	var userID uint
+	if value := c.Query("user_id"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid user ID"))
+			return
+		}
+		userID = uint(parsed)
+	}
+	
+	// 解析分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	
+	offset := (page - 1) * pageSize
+	
+	audits, total, err := h.service.ListPolicyAudits(action, userID, offset, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get policy audits failed"))
 		return
 	}
 	
 	response := map[string]interface{}{
-		"ansible_available": true,
-		"message": "Ansible is installed and available",
+		"data":       audits,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
 	}
-	c.JSON(http.StatusOK, common.SuccessResponse("Ansible status checked successfully", response))
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Policy audits retrieved successfully", response))
+}
+
+// policyRuleErrorResponse 将策略规则相关的错误转换为响应
+func policyRuleErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrInvalidPolicyRule):
+		c.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+	case strings.Contains(err.Error(), "record not found"):
+		c.JSON(http.StatusNotFound, common.ErrorResponse("Policy rule not found"))
+	case strings.Contains(err.Error(), "UNIQUE constraint failed"):
+		c.JSON(http.StatusConflict, common.ErrorResponse("Policy rule name already exists"))
+	default:
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse(message))
+	}
+}
+
+// policyErrorResponse 命令被策略阻止时返回403，需要确认时返回428和确认令牌，
+// 匹配的规则放在policy字段中；不是策略错误时返回false
+func policyErrorResponse(c *gin.Context, err error) bool {
+	var violation *PolicyViolation
+	if errors.As(err, &violation) {
+		response := common.ErrorResponse(err.Error())
+		response["policy"] = violation
+		if errors.Is(err, ErrPolicyConfirmationRequired) {
+			c.JSON(http.StatusPreconditionRequired, response)
+		} else {
+			c.JSON(http.StatusForbidden, response)
+		}
+		return true
+	}
+	if errors.Is(err, ErrPolicyConfirmationRequired) {
+		c.JSON(http.StatusConflict, common.ErrorResponse(err.Error()))
+		return true
+	}
+	return false
+}
+
+// isAdmin 判断当前用户是否为管理员
+func isAdmin(c *gin.Context) bool {
+	return c.GetString("role") == adminRole
+}
+
+// requireAdmin 当前用户不是管理员时返回403，调用方应直接返回
+func requireAdmin(c *gin.Context) bool {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, common.ErrorResponse("Admin access required"))
+		return false
+	}
+	return true
+}
+
+// CreateVaultSecret 创建vault密码
+func (h *Handler) CreateVaultSecret(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	var req VaultSecretRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	secret, err := h.service.CreateVaultSecret(userID, &req)
+	if err != nil {
+		vaultErrorResponse(c, err, "Create vault secret failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Vault secret created successfully", secret))
+}
+
+// ListVaultSecrets 列出vault密码
+func (h *Handler) ListVaultSecrets(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	// 解析分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	
+	offset := (page - 1) * pageSize
+	
+	secrets, total, err := h.service.ListVaultSecrets(userID, offset, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse("Get vault secrets failed"))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"data":       secrets,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Vault secrets retrieved successfully", response))
+}
+
+// GetVaultSecret 获取vault密码信息，不返回密码
+func (h *Handler) GetVaultSecret(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid vault secret ID"))
+		return
+	}
+	
+	secret, err := h.service.GetVaultSecret(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.ErrorResponse("Vault secret not found"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Vault secret retrieved successfully", secret))
+}
+
+// UpdateVaultSecret 更新vault密码
+func (h *Handler) UpdateVaultSecret(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid vault secret ID"))
+		return
+	}
+	
+	var req VaultSecretRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	secret, err := h.service.UpdateVaultSecret(uint(id), userID, &req)
+	if err != nil {
+		vaultErrorResponse(c, err, "Update vault secret failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Vault secret updated successfully", secret))
+}
+
+// DeleteVaultSecret 删除vault密码
+func (h *Handler) DeleteVaultSecret(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid vault secret ID"))
+		return
+	}
+	
+	if err := h.service.DeleteVaultSecret(uint(id), userID); err != nil {
+		vaultErrorResponse(c, err, "Delete vault secret failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Vault secret deleted successfully", map[string]string{"message": "Vault secret deleted successfully"}))
+}
+
+// EncryptVaultString 加密字符串，返回与ansible-vault encrypt_string兼容的结果
+func (h *Handler) EncryptVaultString(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	var req VaultEncryptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	result, err := h.service.EncryptVaultString(userID, &req)
+	if err != nil {
+		vaultErrorResponse(c, err, "Encrypt failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Encrypted successfully", result))
+}
+
+// DecryptVaultString 解密vault字符串
+func (h *Handler) DecryptVaultString(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	var req VaultDecryptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Invalid request parameters"))
+		return
+	}
+	
+	result, err := h.service.DecryptVaultString(userID, &req)
+	if err != nil {
+		vaultErrorResponse(c, err, "Decrypt failed")
+		return
+	}
+	
+	c.JSON(http.StatusOK, common.SuccessResponse("Decrypted successfully", result))
 }
 
-// GetCommonModules 获取常用模块列表
-func (h *Handler) GetCommonModules(c *gin.Context) {
-	modules := GetCommonModules()
-	c.JSON(http.StatusOK, common.SuccessResponse("Common modules retrieved successfully", modules))
-}
\ No newline at end of file
+// EncryptVaultFile 加密上传的文件，返回ansible-vault格式的文件
+func (h *Handler) EncryptVaultFile(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	data, filename, ok := readVaultUpload(c)
+	if !ok {
+		return
+	}
+	
+	vaulttext, err := h.service.EncryptVaultData(userID, c.PostForm("vault_id"), data)
+	if err != nil {
+		vaultErrorResponse(c, err, "Encrypt failed")
+		return
+	}
+	
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(vaulttext))
+}
+
+// DecryptVaultFile 解密上传的ansible-vault文件，返回原始文件内容
+func (h *Handler) DecryptVaultFile(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	
+	data, filename, ok := readVaultUpload(c)
+	if !ok {
+		return
+	}
+	
+	plaintext, _, err := h.service.DecryptVaultData(userID, c.PostForm("vault_id"), string(data))
+	if err != nil {
+		vaultErrorResponse(c, err, "Decrypt failed")
+		return
+	}
+	
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/octet-stream", plaintext)
+}
+
+// readVaultUpload 读取multipart表单中的file字段，失败时已写入错误响应
+func readVaultUpload(c *gin.Context) ([]byte, string, bool) {
+	// 为multipart表单的其他部分保留余量
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxVaultFileSize+1<<20)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("File is required"))
+		return nil, "", false
+	}
+	defer file.Close()
+	
+	data, err := io.ReadAll(io.LimitReader(file, MaxVaultFileSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse("Read file failed"))
+		return nil, "", false
+	}
+	if len(data) > MaxVaultFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, common.ErrorResponse(fmt.Sprintf("File exceeds %d bytes", MaxVaultFileSize)))
+		return nil, "", false
+	}
+	return data, filepath.Base(header.Filename), true
+}
+
+// vaultErrorResponse 将vault相关的错误转换为响应，错误信息中不包含密码
+func vaultErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrInvalidVaultSecret), errors.Is(err, ErrInvalidVaultData):
+		c.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+	case errors.Is(err, ErrVaultDecrypt):
+		c.JSON(http.StatusUnprocessableEntity, common.ErrorResponse(err.Error()))
+	case errors.Is(err, ErrVaultSecretNotFound):
+		c.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+	case strings.Contains(err.Error(), "record not found"):
+		c.JSON(http.StatusNotFound, common.ErrorResponse("Vault secret not found"))
+	case strings.Contains(err.Error(), "UNIQUE constraint failed"):
+		c.JSON(http.StatusConflict, common.ErrorResponse("Vault secret name already exists"))
+	default:
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse(message))
+	}
+}
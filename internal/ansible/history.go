@@ -0,0 +1,255 @@
+package ansible
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidExecutionFilter 执行历史查询条件无效
+var ErrInvalidExecutionFilter = errors.New("invalid execution filter")
+
+// maxExecutionExport 一次导出的最大执行记录数
+const maxExecutionExport = 10000
+
+// executionSortColumns 执行历史允许的排序字段
+var executionSortColumns = map[string]string{
+	"created_at": "created_at",
+	"start_time": "start_time",
+	"end_time":   "end_time",
+	"duration":   "duration",
+	"status":     "status",
+	"name":       "name",
+	"type":       "type",
+}
+
+// adhocHistoryColumns、playbookHistoryColumns 合并adhoc和playbook执行记录时两边选择的列，顺序必须一致
+var (
+	adhocHistoryColumns = []string{
+		"'adhoc' AS type", "id", "command AS name", "module", "args",
+		"0 AS playbook_id", "NULL AS project_id", "'' AS playbook_path",
+		"inventory_id", "hosts", "`limit`", "'' AS tags",
+		"status", "exit_code", "dry_run", "start_time", "end_time", "duration",
+		"schedule_id", "workflow_run_id", "relaunch_of_id", "user_id", "created_at",
+		"approval_status", "protected_targets", "approval_expires_at",
+	}
+	playbookHistoryColumns = []string{
+		"'playbook' AS type", "id", "name", "'' AS module", "'' AS args",
+		"playbook_id", "project_id", "playbook_path",
+		"inventory_id", "'' AS hosts", "`limit`", "tags",
+		"status", "exit_code", "dry_run", "start_time", "end_time", "duration",
+		"schedule_id", "workflow_run_id", "relaunch_of_id", "user_id", "created_at",
+		"approval_status", "protected_targets", "approval_expires_at",
+	}
+)
+
+// ListExecutions 按条件列出adhoc和playbook执行记录
+func (s *AnsibleService) ListExecutions(filter *ExecutionFilter, offset, limit int) ([]ExecutionSummary, int64, error) {
+	query, err := s.executionHistoryQuery(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	// 获取总数
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	// 获取分页数据
+	executions := []ExecutionSummary{}
+	err = sortExecutionHistory(query, filter).Offset(offset).Limit(limit).Find(&executions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	return executions, total, nil
+}
+
+// ExportExecutions 按条件导出执行记录，最多导出maxExecutionExport条
+func (s *AnsibleService) ExportExecutions(filter *ExecutionFilter) ([]ExecutionSummary, error) {
+	query, err := s.executionHistoryQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	
+	executions := []ExecutionSummary{}
+	if err := sortExecutionHistory(query, filter).Limit(maxExecutionExport).Find(&executions).Error; err != nil {
+		return nil, err
+	}
+	return executions, nil
+}
+
+// executionHistoryQuery 合并两种执行记录并应用过滤条件
+func (s *AnsibleService) executionHistoryQuery(filter *ExecutionFilter) (*gorm.DB, error) {
+	adhoc := s.db.Model(&AdhocExecution{}).Select(adhocHistoryColumns)
+	playbook := s.db.Model(&PlaybookExecution{}).Select(playbookHistoryColumns)
+	
+	var union *gorm.DB
+	switch filter.Type {
+	case "":
+		union = s.db.Raw("? UNION ALL ?", adhoc, playbook)
+	case ExecutionTypeAdhoc:
+		union = adhoc
+	case ExecutionTypePlaybook:
+		union = playbook
+	default:
+		return nil, ErrInvalidExecutionFilter
+	}
+	
+	query := s.db.Table("(?) AS executions", union)
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.Module != "" {
+		query = query.Where("module = ?", filter.Module)
+	}
+	if filter.PlaybookID != 0 {
+		query = query.Where("playbook_id = ?", filter.PlaybookID)
+	}
+	if filter.ProjectID != 0 {
+		query = query.Where("project_id = ?", filter.ProjectID)
+	}
+	if filter.InventoryID != 0 {
+		query = query.Where("inventory_id = ?", filter.InventoryID)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	
+	// 主机匹配实际产生结果的主机，以及adhoc的目标和--limit中出现的主机
+	if filter.Host != "" {
+		pattern := globToLike(filter.Host)
+		contains := "%" + pattern + "%"
+		query = query.Where(
+			"EXISTS (SELECT 1 FROM host_results WHERE host_results.execution_type = executions.type AND host_results.execution_id = executions.id AND host_results.host LIKE ? ESCAPE '\\') OR hosts LIKE ? ESCAPE '\\' OR `limit` LIKE ? ESCAPE '\\'",
+			pattern, contains, contains,
+		)
+	}
+	
+	if filter.Search != "" {
+		contains := "%" + escapeLike(filter.Search) + "%"
+		query = query.Where(
+			"name LIKE ? ESCAPE '\\' OR args LIKE ? ESCAPE '\\' OR playbook_path LIKE ? ESCAPE '\\'",
+			contains, contains, contains,
+		)
+	}
+	
+	return query, nil
+}
+
+// sortExecutionHistory 按指定字段排序，默认按创建时间倒序；ID在两种执行之间可能重复，相同时按类型区分
+func sortExecutionHistory(query *gorm.DB, filter *ExecutionFilter) *gorm.DB {
+	column, ok := executionSortColumns[filter.Sort]
+	if !ok {
+		column = "created_at"
+	}
+	direction := " ASC"
+	if filter.Desc {
+		direction = " DESC"
+	}
+	return query.Order(column + direction).Order("type ASC").Order("id" + direction)
+}
+
+// validExecutionSort 判断排序字段是否允许，空值使用默认排序
+func validExecutionSort(sort string) bool {
+	_, ok := executionSortColumns[sort]
+	return sort == "" || ok
+}
+
+// escapeLike 转义LIKE模式中的特殊字符，配合ESCAPE '\'使用
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
+
+// globToLike 将*和?通配符转换为LIKE模式
+func globToLike(pattern string) string {
+	replacer := strings.NewReplacer("*", "%", "?", "_")
+	return replacer.Replace(escapeLike(pattern))
+}
+
+// writeExecutionsCSV 以CSV格式写出执行记录
+func writeExecutionsCSV(w io.Writer, executions []ExecutionSummary) error {
+	writer := csv.NewWriter(w)
+	header := []string{
+		"type", "id", "name", "module", "args", "playbook_id", "project_id", "playbook_path",
+		"inventory_id", "hosts", "limit", "tags", "status", "exit_code", "dry_run",
+		"start_time", "end_time", "duration", "schedule_id", "workflow_run_id", "relaunch_of_id",
+		"user_id", "created_at", "approval_status", "protected_targets",
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	
+	for _, e := range executions {
+		record := []string{
+			e.Type,
+			strconv.FormatUint(uint64(e.ID), 10),
+			csvCell(e.Name),
+			csvCell(e.Module),
+			csvCell(e.Args),
+			strconv.FormatUint(uint64(e.PlaybookID), 10),
+			csvID(e.ProjectID),
+			csvCell(e.PlaybookPath),
+			csvID(e.InventoryID),
+			csvCell(e.Hosts),
+			csvCell(e.Limit),
+			csvCell(e.Tags),
+			e.Status,
+			strconv.Itoa(e.ExitCode),
+			strconv.FormatBool(e.DryRun),
+			csvTime(e.StartTime),
+			csvTime(e.EndTime),
+			strconv.Itoa(e.Duration),
+			csvID(e.ScheduleID),
+			csvID(e.WorkflowRunID),
+			csvID(e.RelaunchOfID),
+			strconv.FormatUint(uint64(e.UserID), 10),
+			e.CreatedAt.Format(time.RFC3339),
+			e.ApprovalStatus,
+			csvCell(e.ProtectedTargets),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvCell 以公式字符开头的内容加上单引号，避免在电子表格中被当作公式执行
+func csvCell(value string) string {
+	if value != "" && strings.ContainsAny(value[:1], "=+-@\t\r") {
+		return "'" + value
+	}
+	return value
+}
+
+// csvID 格式化可为空的ID
+func csvID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
+// csvTime 格式化可为空的时间
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,1037 @@
+package ansible
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// inventory内容格式
+const (
+	InventoryFormatINI  = "ini"
+	InventoryFormatYAML = "yaml"
+)
+
+const (
+	// inventoryAllGroup 包含全部主机的根组
+	inventoryAllGroup = "all"
+	// inventoryUngroupedGroup 不属于其他组的主机所在的组
+	inventoryUngroupedGroup = "ungrouped"
+)
+
+var (
+	// yamlErrorLine 匹配yaml错误信息中的行号
+	yamlErrorLine = regexp.MustCompile(`line (\d+): (.*)`)
+	// iniDecimal 符合INI变量中小数格式的值
+	iniDecimal = regexp.MustCompile(`^-?(\d+\.\d*|\.\d+)$`)
+)
+
+// InventoryError 表示inventory内容中的一处错误
+type InventoryError struct {
+	Line    int    `json:"line"`             // 行号，从1开始
+	Column  int    `json:"column,omitempty"` // 列号，从1开始，未知时为0
+	Message string `json:"message"`
+}
+
+// String 格式化错误及其位置
+func (e InventoryError) String() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// InventoryValidationError inventory校验失败，包含全部错误及行号
+type InventoryValidationError struct {
+	Errors []InventoryError
+}
+
+// Error 实现error接口
+func (e *InventoryValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, item := range e.Errors {
+		messages[i] = item.String()
+	}
+	return fmt.Sprintf("%v: %s", ErrInvalidInventory, strings.Join(messages, "; "))
+}
+
+// Unwrap 使errors.Is(err, ErrInvalidInventory)成立
+func (e *InventoryValidationError) Unwrap() error {
+	return ErrInvalidInventory
+}
+
+// ParsedGroup 解析后的inventory组
+type ParsedGroup struct {
+	Name     string
+	Hosts    []string               // 直接属于该组的主机
+	Children []string               // 子组
+	Vars     map[string]interface{} // 组变量
+	line     int                    // 组首次出现的行号
+}
+
+// ParsedHost 解析后的inventory主机
+type ParsedHost struct {
+	Name   string
+	Groups []string               // 直接所属的组
+	Vars   map[string]interface{} // 主机变量
+}
+
+// ParsedInventory 解析后的inventory
+type ParsedInventory struct {
+	Format string
+	Groups map[string]*ParsedGroup
+	Hosts  map[string]*ParsedHost
+}
+
+// ParseInventory 解析INI或YAML格式的inventory内容，失败时返回*InventoryValidationError
+func ParseInventory(content string) (*ParsedInventory, error) {
+	var inventory *ParsedInventory
+	var errs []InventoryError
+	
+	if detectInventoryFormat(content) == InventoryFormatYAML {
+		inventory, errs = parseYAMLInventory(content)
+	} else {
+		inventory, errs = parseINIInventory(content)
+	}
+	
+	if len(errs) == 0 {
+		errs = inventory.reconcile()
+	}
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+		return nil, &InventoryValidationError{Errors: errs}
+	}
+	
+	return inventory, nil
+}
+
+// detectInventoryFormat 根据第一行有效内容判断inventory格式
+func detectInventoryFormat(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+	
+		switch {
+		case strings.HasPrefix(line, "["):
+			return InventoryFormatINI
+		case line == "---", strings.HasPrefix(line, "{"):
+			return InventoryFormatYAML
+		}
+	
+		// YAML格式的第一行是"组名:"，INI的主机行不会以冒号结尾
+		if key, _, found := strings.Cut(line, "#"); found {
+			line = strings.TrimSpace(key)
+		}
+		if strings.HasSuffix(line, ":") && !strings.ContainsAny(line, " \t=") {
+			return InventoryFormatYAML
+		}
+		return InventoryFormatINI
+	}
+	return InventoryFormatINI
+}
+
+// newParsedInventory 创建包含all和ungrouped组的空inventory
+func newParsedInventory(format string) *ParsedInventory {
+	inventory := &ParsedInventory{
+		Format: format,
+		Groups: make(map[string]*ParsedGroup),
+		Hosts:  make(map[string]*ParsedHost),
+	}
+	inventory.group(inventoryAllGroup, 0)
+	inventory.group(inventoryUngroupedGroup, 0)
+	return inventory
+}
+
+// group 获取组，不存在时创建
+func (p *ParsedInventory) group(name string, line int) *ParsedGroup {
+	g, exists := p.Groups[name]
+	if !exists {
+		g = &ParsedGroup{Name: name, Vars: make(map[string]interface{}), line: line}
+		p.Groups[name] = g
+	}
+	return g
+}
+
+// addHost 将主机加入组并合并主机变量
+func (p *ParsedInventory) addHost(groupName, hostName string, vars map[string]interface{}, line int) {
+	host, exists := p.Hosts[hostName]
+	if !exists {
+		host = &ParsedHost{Name: hostName, Vars: make(map[string]interface{})}
+		p.Hosts[hostName] = host
+	}
+	for key, value := range vars {
+		host.Vars[key] = value
+	}
+	
+	g := p.group(groupName, line)
+	if !containsString(g.Hosts, hostName) {
+		g.Hosts = append(g.Hosts, hostName)
+	}
+	if !containsString(host.Groups, groupName) {
+		host.Groups = append(host.Groups, groupName)
+	}
+}
+
+// addChild 建立父子组关系
+func (p *ParsedInventory) addChild(parent, child string, line int) {
+	g := p.group(parent, line)
+	p.group(child, line)
+	if !containsString(g.Children, child) {
+		g.Children = append(g.Children, child)
+	}
+}
+
+// parents 返回组的直接父组
+func (p *ParsedInventory) parents(name string) []string {
+	var parents []string
+	for _, g := range p.Groups {
+		if containsString(g.Children, name) {
+			parents = append(parents, g.Name)
+		}
+	}
+	sort.Strings(parents)
+	return parents
+}
+
+// reconcile 检查组之间的循环引用，并按ansible的规则整理all和ungrouped组
+func (p *ParsedInventory) reconcile() []InventoryError {
+	var errs []InventoryError
+	
+	// 检查循环引用
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		for _, child := range p.Groups[name].Children {
+			switch state[child] {
+			case visiting:
+				errs = append(errs, InventoryError{
+					Line:    p.Groups[name].line,
+					Message: fmt.Sprintf("group %s has a circular child relationship with %s", name, child),
+				})
+			case unvisited:
+				visit(child)
+			}
+		}
+		state[name] = visited
+	}
+	for _, name := range sortedKeys(p.Groups) {
+		if state[name] == unvisited {
+			visit(name)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	
+	all := p.Groups[inventoryAllGroup]
+	ungrouped := p.Groups[inventoryUngroupedGroup]
+	
+	// 没有父组的组都是all的子组
+	for _, name := range sortedKeys(p.Groups) {
+		if name == inventoryAllGroup {
+			continue
+		}
+		if len(p.parents(name)) == 0 {
+			p.addChild(inventoryAllGroup, name, 0)
+		}
+	}
+	
+	// 只属于all的主机归入ungrouped，属于其他组的主机从ungrouped中移除
+	for _, name := range sortedKeys(p.Hosts) {
+		host := p.Hosts[name]
+		grouped := false
+		for _, g := range host.Groups {
+			if g != inventoryAllGroup && g != inventoryUngroupedGroup {
+				grouped = true
+				break
+			}
+		}
+	
+		if grouped {
+			ungrouped.Hosts = removeString(ungrouped.Hosts, name)
+			host.Groups = removeString(host.Groups, inventoryUngroupedGroup)
+		} else if !containsString(ungrouped.Hosts, name) {
+			p.addHost(inventoryUngroupedGroup, name, nil, 0)
+		}
+	}
+	
+	// all组不直接记录主机，直接定义在all下的主机已归入ungrouped
+	for _, name := range all.Hosts {
+		p.Hosts[name].Groups = removeString(p.Hosts[name].Groups, inventoryAllGroup)
+	}
+	all.Hosts = nil
+	
+	return nil
+}
+
+// iniDeclaration 等待在后续内容中定义的组
+type iniDeclaration struct {
+	line   int
+	state  string
+	parent string
+}
+
+// parseINIInventory 解析INI格式的inventory
+func parseINIInventory(content string) (*ParsedInventory, []InventoryError) {
+	inventory := newParsedInventory(InventoryFormatINI)
+	var errs []InventoryError
+	
+	declared := map[string]bool{inventoryAllGroup: true, inventoryUngroupedGroup: true}
+	pending := make(map[string]iniDeclaration)
+	
+	groupName := inventoryUngroupedGroup
+	state := "hosts"
+	
+	for i, raw := range strings.Split(content, "\n") {
+		lineNo := i + 1
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+	
+		// 组定义 [group], [group:vars], [group:children]
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				errs = append(errs, InventoryError{Line: lineNo, Message: fmt.Sprintf("invalid section header %q", line)})
+				state = "skip"
+				continue
+			}
+	
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			state = "hosts"
+			if idx := strings.LastIndex(name, ":"); idx >= 0 {
+				state = name[idx+1:]
+				name = name[:idx]
+				if state != "vars" && state != "children" {
+					errs = append(errs, InventoryError{Line: lineNo, Message: fmt.Sprintf("invalid section suffix %q, expected vars or children", state)})
+					state = "skip"
+					continue
+				}
+			}
+			if err := validateGroupName(name); err != "" {
+				errs = append(errs, InventoryError{Line: lineNo, Message: err})
+				state = "skip"
+				continue
+			}
+	
+			groupName = name
+			inventory.group(name, lineNo)
+			if state == "vars" {
+				if !declared[name] {
+					if _, exists := pending[name]; !exists {
+						pending[name] = iniDeclaration{line: lineNo, state: state}
+					}
+				}
+			} else {
+				declared[name] = true
+				delete(pending, name)
+			}
+			continue
+		}
+	
+		switch state {
+		case "skip":
+			// 所在组定义无效，忽略该组内容
+		case "hosts":
+			tokens, err := splitINILine(line)
+			if err != nil {
+				errs = append(errs, InventoryError{Line: lineNo, Message: err.Error()})
+				continue
+			}
+			if len(tokens) == 0 {
+				continue
+			}
+	
+			names, port, err := expandHostPattern(tokens[0])
+			if err != nil {
+				errs = append(errs, InventoryError{Line: lineNo, Message: err.Error()})
+				continue
+			}
+	
+			vars := make(map[string]interface{})
+			if port > 0 {
+				vars["ansible_port"] = port
+			}
+			valid := true
+			for _, token := range tokens[1:] {
+				key, value, found := strings.Cut(token, "=")
+				if !found || key == "" {
+					errs = append(errs, InventoryError{Line: lineNo, Message: fmt.Sprintf("expected key=value host variable assignment, got: %s", token)})
+					valid = false
+					break
+				}
+				vars[key] = parseINIValue(value)
+			}
+			if !valid {
+				continue
+			}
+	
+			for _, name := range names {
+				inventory.addHost(groupName, name, vars, lineNo)
+			}
+		case "vars":
+			key, value, found := strings.Cut(line, "=")
+			key = strings.TrimSpace(key)
+			if !found || key == "" {
+				errs = append(errs, InventoryError{Line: lineNo, Message: fmt.Sprintf("expected key=value, got: %s", line)})
+				continue
+			}
+			inventory.group(groupName, lineNo).Vars[key] = parseINIVarValue(strings.TrimSpace(value))
+		case "children":
+			child := line
+			if idx := strings.IndexAny(child, "#;"); idx >= 0 {
+				child = strings.TrimSpace(child[:idx])
+			}
+			if err := validateGroupName(child); err != "" {
+				errs = append(errs, InventoryError{Line: lineNo, Message: err})
+				continue
+			}
+			if child == groupName || child == inventoryAllGroup {
+				errs = append(errs, InventoryError{Line: lineNo, Message: fmt.Sprintf("group %s cannot be a child of %s", child, groupName)})
+				continue
+			}
+	
+			inventory.addChild(groupName, child, lineNo)
+			if !declared[child] {
+				if decl, exists := pending[child]; !exists || decl.state != "children" {
+					pending[child] = iniDeclaration{line: lineNo, state: state, parent: groupName}
+				}
+			}
+		}
+	}
+	
+	// 引用了但没有定义的组
+	for name, decl := range pending {
+		if decl.state == "vars" {
+			errs = append(errs, InventoryError{Line: decl.line, Message: fmt.Sprintf("section [%s:vars] not valid for undefined group: %s", name, name)})
+		} else {
+			errs = append(errs, InventoryError{Line: decl.line, Message: fmt.Sprintf("section [%s:children] includes undefined group: %s", decl.parent, name)})
+		}
+	}
+	
+	return inventory, errs
+}
+
+// splitINILine 按shell规则拆分INI行，支持引号、转义和行尾注释
+func splitINILine(line string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inToken := false
+	escaped := false
+	var quote rune
+	
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' {
+				escaped = true
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inToken = true
+		case r == '"' || r == '\'':
+			quote = r
+			inToken = true
+		case r == '#':
+			if inToken {
+				tokens = append(tokens, current.String())
+			}
+			return tokens, nil
+		case unicode.IsSpace(r):
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+	
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quoted string")
+	}
+	if escaped {
+		return nil, fmt.Errorf("no escaped character at end of line")
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+// parseINIValue 按ansible的规则解析主机变量值：整数、小数、True/False，其余保留为字符串
+func parseINIValue(value string) interface{} {
+	// 以0开头的数字(如文件权限0644)保留为字符串
+	digits := strings.TrimPrefix(value, "-")
+	if !(len(digits) > 1 && digits[0] == '0') {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	if iniDecimal.MatchString(value) {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	
+	switch value {
+	case "True":
+		return true
+	case "False":
+		return false
+	}
+	return value
+}
+
+// parseINIVarValue 解析[group:vars]中的变量值，带引号的值作为字符串
+func parseINIVarValue(value string) interface{} {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return parseINIValue(value)
+}
+
+// validateGroupName 校验组名，合法时返回空字符串
+func validateGroupName(name string) string {
+	if name == "" {
+		return "group name is empty"
+	}
+	if strings.IndexFunc(name, unicode.IsSpace) >= 0 || strings.ContainsAny(name, ":[]") {
+		return fmt.Sprintf("invalid group name %q", name)
+	}
+	return ""
+}
+
+// expandHostPattern 展开主机范围(如web[01:03].example.com)，并解析host:port形式的端口
+func expandHostPattern(pattern string) ([]string, int, error) {
+	port := 0
+	
+	// 方括号之外只有一个冒号时为端口，多个冒号视为IPv6地址
+	colons := 0
+	lastColon := -1
+	depth := 0
+	for i, r := range pattern {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ':':
+			if depth == 0 {
+				colons++
+				lastColon = i
+			}
+		}
+	}
+	if colons == 1 {
+		p, err := strconv.Atoi(pattern[lastColon+1:])
+		if err != nil || p < 1 || p > 65535 {
+			return nil, 0, fmt.Errorf("invalid port in host %q", pattern)
+		}
+		port = p
+		pattern = pattern[:lastColon]
+	}
+	
+	hosts, err := expandHostRange(pattern)
+	if err != nil {
+		return nil, 0, err
+	}
+	return hosts, port, nil
+}
+
+// expandHostRange 递归展开主机名中的[start:end:step]范围
+func expandHostRange(pattern string) ([]string, error) {
+	start := strings.Index(pattern, "[")
+	if start < 0 {
+		if pattern == "" {
+			return nil, fmt.Errorf("host name is empty")
+		}
+		return []string{pattern}, nil
+	}
+	end := strings.Index(pattern[start:], "]")
+	if end < 0 {
+		return nil, fmt.Errorf("unterminated range in host %q", pattern)
+	}
+	end += start
+	
+	prefix, spec, suffix := pattern[:start], pattern[start+1:end], pattern[end+1:]
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, fmt.Errorf("invalid range %q in host %q", spec, pattern)
+	}
+	
+	step := 1
+	if len(parts) == 3 {
+		s, err := strconv.Atoi(parts[2])
+		if err != nil || s < 1 {
+			return nil, fmt.Errorf("invalid range step %q in host %q", parts[2], pattern)
+		}
+		step = s
+	}
+	
+	var values []string
+	first, last := parts[0], parts[1]
+	if first == "" {
+		first = "0"
+	}
+	if a, errA := strconv.Atoi(first); errA == nil {
+		b, errB := strconv.Atoi(last)
+		if errB != nil || b < a {
+			return nil, fmt.Errorf("invalid range %q in host %q", spec, pattern)
+		}
+		// 起始值有前导零时按起始值的宽度补零
+		width := 0
+		if len(first) > 1 && first[0] == '0' {
+			width = len(first)
+		}
+		for n := a; n <= b; n += step {
+			values = append(values, fmt.Sprintf("%0*d", width, n))
+		}
+	} else if len(first) == 1 && len(last) == 1 && unicode.IsLetter(rune(first[0])) && unicode.IsLetter(rune(last[0])) && first[0] <= last[0] {
+		for c := int(first[0]); c <= int(last[0]); c += step {
+			values = append(values, string(rune(c)))
+		}
+	} else {
+		return nil, fmt.Errorf("invalid range %q in host %q", spec, pattern)
+	}
+	
+	rest := []string{""}
+	if suffix != "" {
+		expanded, err := expandHostRange(suffix)
+		if err != nil {
+			return nil, err
+		}
+		rest = expanded
+	}
+	
+	var hosts []string
+	for _, value := range values {
+		for _, tail := range rest {
+			hosts = append(hosts, prefix+value+tail)
+		}
+	}
+	return hosts, nil
+}
+
+// parseYAMLInventory 解析YAML格式的inventory
+func parseYAMLInventory(content string) (*ParsedInventory, []InventoryError) {
+	inventory := newParsedInventory(InventoryFormatYAML)
+	
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
+		return inventory, yamlErrors(err)
+	}
+	if len(root.Content) == 0 {
+		return inventory, nil
+	}
+	
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return inventory, []InventoryError{{Line: doc.Line, Column: doc.Column, Message: "inventory must be a mapping of groups"}}
+	}
+	
+	var errs []InventoryError
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		errs = append(errs, parseYAMLGroup(inventory, doc.Content[i], doc.Content[i+1], "")...)
+	}
+	return inventory, errs
+}
+
+// parseYAMLGroup 解析YAML中的一个组及其主机、变量和子组
+func parseYAMLGroup(inventory *ParsedInventory, keyNode, node *yaml.Node, parent string) []InventoryError {
+	name := keyNode.Value
+	if err := validateGroupName(name); err != "" {
+		return []InventoryError{{Line: keyNode.Line, Column: keyNode.Column, Message: err}}
+	}
+	
+	inventory.group(name, keyNode.Line)
+	if parent != "" {
+		inventory.addChild(parent, name, keyNode.Line)
+	}
+	
+	if isYAMLNull(node) {
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return []InventoryError{{Line: node.Line, Column: node.Column, Message: fmt.Sprintf("group %s must be a mapping", name)}}
+	}
+	
+	var errs []InventoryError
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+	
+		switch key.Value {
+		case "hosts":
+			if isYAMLNull(value) {
+				continue
+			}
+			if value.Kind != yaml.MappingNode {
+				errs = append(errs, InventoryError{Line: value.Line, Column: value.Column, Message: fmt.Sprintf("hosts of group %s must be a mapping", name)})
+				continue
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				hostKey, hostValue := value.Content[j], value.Content[j+1]
+	
+				names, port, err := expandHostPattern(hostKey.Value)
+				if err != nil {
+					errs = append(errs, InventoryError{Line: hostKey.Line, Column: hostKey.Column, Message: err.Error()})
+					continue
+				}
+	
+				vars := make(map[string]interface{})
+				if !isYAMLNull(hostValue) {
+					if hostValue.Kind != yaml.MappingNode {
+						errs = append(errs, InventoryError{Line: hostValue.Line, Column: hostValue.Column, Message: fmt.Sprintf("variables of host %s must be a mapping", hostKey.Value)})
+						continue
+					}
+					if err := hostValue.Decode(&vars); err != nil {
+						errs = append(errs, InventoryError{Line: hostValue.Line, Column: hostValue.Column, Message: err.Error()})
+						continue
+					}
+				}
+				if port > 0 {
+					vars["ansible_port"] = port
+				}
+	
+				for _, host := range names {
+					inventory.addHost(name, host, vars, hostKey.Line)
+				}
+			}
+		case "vars":
+			if isYAMLNull(value) {
+				continue
+			}
+			if value.Kind != yaml.MappingNode {
+				errs = append(errs, InventoryError{Line: value.Line, Column: value.Column, Message: fmt.Sprintf("vars of group %s must be a mapping", name)})
+				continue
+			}
+			vars := make(map[string]interface{})
+			if err := value.Decode(&vars); err != nil {
+				errs = append(errs, InventoryError{Line: value.Line, Column: value.Column, Message: err.Error()})
+				continue
+			}
+			g := inventory.group(name, keyNode.Line)
+			for k, v := range vars {
+				g.Vars[k] = v
+			}
+		case "children":
+			if isYAMLNull(value) {
+				continue
+			}
+			if value.Kind != yaml.MappingNode {
+				errs = append(errs, InventoryError{Line: value.Line, Column: value.Column, Message: fmt.Sprintf("children of group %s must be a mapping", name)})
+				continue
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				if child := value.Content[j].Value; child == name || child == inventoryAllGroup {
+					errs = append(errs, InventoryError{Line: value.Content[j].Line, Column: value.Content[j].Column, Message: fmt.Sprintf("group %s cannot be a child of %s", child, name)})
+					continue
+				}
+				errs = append(errs, parseYAMLGroup(inventory, value.Content[j], value.Content[j+1], name)...)
+			}
+		default:
+			errs = append(errs, InventoryError{
+				Line:    key.Line,
+				Column:  key.Column,
+				Message: fmt.Sprintf("invalid key %q in group %s, only hosts, vars and children are allowed", key.Value, name),
+			})
+		}
+	}
+	
+	return errs
+}
+
+// isYAMLNull 判断YAML节点是否为空值
+func isYAMLNull(node *yaml.Node) bool {
+	return node == nil || (node.Kind == yaml.ScalarNode && node.Tag == "!!null")
+}
+
+// yamlErrors 将yaml库的错误转换为带行号的错误
+func yamlErrors(err error) []InventoryError {
+	var typeErr *yaml.TypeError
+	messages := []string{err.Error()}
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+	
+	var errs []InventoryError
+	for _, message := range messages {
+		if match := yamlErrorLine.FindStringSubmatch(message); match != nil {
+			line, _ := strconv.Atoi(match[1])
+			errs = append(errs, InventoryError{Line: line, Message: match[2]})
+		} else {
+			errs = append(errs, InventoryError{Line: 1, Message: strings.TrimPrefix(message, "yaml: ")})
+		}
+	}
+	return errs
+}
+
+// InventoryGraphGroup 组树中的一个组
+type InventoryGraphGroup struct {
+	Name     string                 `json:"name"`
+	Vars     map[string]interface{} `json:"vars,omitempty"`     // 组变量
+	Hosts    []string               `json:"hosts"`              // 直接属于该组的主机
+	AllHosts []string               `json:"all_hosts"`          // 包括子组在内的全部主机
+	Children []*InventoryGraphGroup `json:"children,omitempty"` // 子组
+}
+
+// InventoryGraphHost 主机及其合并后的变量
+type InventoryGraphHost struct {
+	Name   string                 `json:"name"`
+	Groups []string               `json:"groups"` // 所属的全部组，包括祖先组
+	Vars   map[string]interface{} `json:"vars"`   // 按ansible优先级合并的组变量和主机变量
+}
+
+// InventoryGraph inventory的组树和主机
+type InventoryGraph struct {
+	Format string                `json:"format"`
+	Tree   *InventoryGraphGroup  `json:"tree"`
+	Hosts  []*InventoryGraphHost `json:"hosts"`
+}
+
+// Graph 构建组树，并计算每个主机合并后的变量
+func (p *ParsedInventory) Graph() *InventoryGraph {
+	graph := &InventoryGraph{
+		Format: p.Format,
+		Tree:   p.graphGroup(inventoryAllGroup),
+	}
+	
+	depths := p.groupDepths()
+	for _, name := range sortedKeys(p.Hosts) {
+		host := p.Hosts[name]
+	
+		groups := p.hostGroups(name)
+		// 组变量按组深度、组名排序合并，深度越大优先级越高，主机变量优先级最高
+		sort.Slice(groups, func(i, j int) bool {
+			if depths[groups[i]] != depths[groups[j]] {
+				return depths[groups[i]] < depths[groups[j]]
+			}
+			return groups[i] < groups[j]
+		})
+	
+		vars := make(map[string]interface{})
+		for _, g := range groups {
+			for k, v := range p.Groups[g].Vars {
+				vars[k] = v
+			}
+		}
+		for k, v := range host.Vars {
+			vars[k] = v
+		}
+	
+		graph.Hosts = append(graph.Hosts, &InventoryGraphHost{Name: name, Groups: groups, Vars: vars})
+	}
+	
+	return graph
+}
+
+// graphGroup 递归构建组树节点
+func (p *ParsedInventory) graphGroup(name string) *InventoryGraphGroup {
+	g := p.Groups[name]
+	node := &InventoryGraphGroup{
+		Name:     name,
+		Hosts:    append([]string{}, g.Hosts...),
+		AllHosts: p.MatchHosts(name),
+	}
+	sort.Strings(node.Hosts)
+	if len(g.Vars) > 0 {
+		node.Vars = g.Vars
+	}
+	
+	children := append([]string{}, g.Children...)
+	sort.Strings(children)
+	for _, child := range children {
+		node.Children = append(node.Children, p.graphGroup(child))
+	}
+	return node
+}
+
+// groupDepths 计算每个组到all的最大深度
+func (p *ParsedInventory) groupDepths() map[string]int {
+	depths := make(map[string]int)
+	var walk func(name string, depth int)
+	walk = func(name string, depth int) {
+		if d, exists := depths[name]; exists && d >= depth {
+			return
+		}
+		depths[name] = depth
+		for _, child := range p.Groups[name].Children {
+			walk(child, depth+1)
+		}
+	}
+	walk(inventoryAllGroup, 0)
+	return depths
+}
+
+// hostGroups 返回主机所属的全部组，包括祖先组和all
+func (p *ParsedInventory) hostGroups(host string) []string {
+	seen := make(map[string]bool)
+	var add func(name string)
+	add = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		for _, parent := range p.parents(name) {
+			add(parent)
+		}
+	}
+	for _, g := range p.Hosts[host].Groups {
+		add(g)
+	}
+	add(inventoryAllGroup)
+	
+	return sortedKeys(seen)
+}
+
+// groupHosts 返回组及其子组中的全部主机
+func (p *ParsedInventory) groupHosts(name string) []string {
+	g, exists := p.Groups[name]
+	if !exists {
+		return nil
+	}
+	if name == inventoryAllGroup {
+		return sortedKeys(p.Hosts)
+	}
+	
+	seen := make(map[string]bool)
+	var walk func(g *ParsedGroup)
+	walk = func(g *ParsedGroup) {
+		for _, host := range g.Hosts {
+			seen[host] = true
+		}
+		for _, child := range g.Children {
+			walk(p.Groups[child])
+		}
+	}
+	walk(g)
+	return sortedKeys(seen)
+}
+
+// MatchHosts 按ansible主机模式匹配主机，支持组名、主机名、通配符、~开头的正则表达式、逗号或冒号分隔的并集、
+// &交集和!排除，返回排序后的主机名
+func (p *ParsedInventory) MatchHosts(pattern string) []string {
+	terms := strings.FieldsFunc(pattern, func(r rune) bool { return r == ',' || r == ':' })
+	
+	selected := make(map[string]bool)
+	var intersections, exclusions [][]string
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		switch {
+		case term == "":
+		case strings.HasPrefix(term, "!"):
+			exclusions = append(exclusions, p.matchTerm(term[1:]))
+		case strings.HasPrefix(term, "&"):
+			intersections = append(intersections, p.matchTerm(term[1:]))
+		default:
+			for _, host := range p.matchTerm(term) {
+				selected[host] = true
+			}
+		}
+	}
+	
+	for _, hosts := range intersections {
+		keep := make(map[string]bool)
+		for _, host := range hosts {
+			if selected[host] {
+				keep[host] = true
+			}
+		}
+		selected = keep
+	}
+	for _, hosts := range exclusions {
+		for _, host := range hosts {
+			delete(selected, host)
+		}
+	}
+	
+	return sortedKeys(selected)
+}
+
+// matchTerm 匹配单个模式项：all或*、组名、主机名、通配符或~开头的正则表达式
+func (p *ParsedInventory) matchTerm(term string) []string {
+	if term == inventoryAllGroup || term == "*" {
+		return sortedKeys(p.Hosts)
+	}
+	if _, exists := p.Groups[term]; exists {
+		return p.groupHosts(term)
+	}
+	if _, exists := p.Hosts[term]; exists {
+		return []string{term}
+	}
+	
+	// ~开头的模式按正则表达式从名称开头匹配，与ansible一致
+	match := func(name string) bool {
+		ok, _ := path.Match(term, name)
+		return ok
+	}
+	if strings.HasPrefix(term, "~") {
+		pattern, err := regexp.Compile("^(?:" + term[1:] + ")")
+		if err != nil {
+			return nil
+		}
+		match = pattern.MatchString
+	} else if !strings.ContainsAny(term, "*?[") {
+		return nil
+	}
+	
+	seen := make(map[string]bool)
+	for name := range p.Groups {
+		if match(name) {
+			for _, host := range p.groupHosts(name) {
+				seen[host] = true
+			}
+		}
+	}
+	for name := range p.Hosts {
+		if match(name) {
+			seen[name] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// sortedKeys 返回map排序后的键
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// removeString 从列表中删除指定字符串
+func removeString(list []string, value string) []string {
+	result := list[:0]
+	for _, item := range list {
+		if item != value {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,372 @@
+package ansible
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseInventory(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		format    string
+		groups    map[string][]string // 组及其全部主机(含子组)
+		children  map[string][]string
+		hostVars  map[string]map[string]interface{}
+		groupVars map[string]map[string]interface{}
+	}{
+		{
+			name:    "ini ungrouped hosts",
+			content: "web1\nweb2 ansible_host=10.0.0.2\n",
+			format:  InventoryFormatINI,
+			groups: map[string][]string{
+				inventoryAllGroup:       {"web1", "web2"},
+				inventoryUngroupedGroup: {"web1", "web2"},
+			},
+			hostVars: map[string]map[string]interface{}{
+				"web2": {"ansible_host": "10.0.0.2"},
+			},
+		},
+		{
+			name: "ini groups children and vars",
+			content: `# comment
+[web]
+web1 http_port=80 enabled=True ratio=0.5 mode=0644
+web2:2222
+
+[db]
+db1 ansible_user="admin user"
+
+[prod:children]
+web
+db
+
+[prod:vars]
+env=production
+quoted="42"
+retries=3
+`,
+			format: InventoryFormatINI,
+			groups: map[string][]string{
+				inventoryAllGroup:       {"db1", "web1", "web2"},
+				inventoryUngroupedGroup: nil,
+				"web":                   {"web1", "web2"},
+				"db":                    {"db1"},
+				"prod":                  {"db1", "web1", "web2"},
+			},
+			children: map[string][]string{
+				inventoryAllGroup: {"prod", "ungrouped"},
+				"prod":            {"db", "web"},
+			},
+			hostVars: map[string]map[string]interface{}{
+				"web1": {"http_port": 80, "enabled": true, "ratio": 0.5, "mode": "0644"},
+				"web2": {"ansible_port": 2222},
+				"db1":  {"ansible_user": "admin user"},
+			},
+			groupVars: map[string]map[string]interface{}{
+				"prod": {"env": "production", "quoted": "42", "retries": 3},
+			},
+		},
+		{
+			name:    "ini numeric range with padding and step",
+			content: "[web]\nweb[01:05:2].example.com\n",
+			format:  InventoryFormatINI,
+			groups: map[string][]string{
+				"web": {"web01.example.com", "web03.example.com", "web05.example.com"},
+			},
+		},
+		{
+			name:    "ini alphabetic and nested ranges",
+			content: "[db]\ndb-[a:b][1:2]\n",
+			format:  InventoryFormatINI,
+			groups: map[string][]string{
+				"db": {"db-a1", "db-a2", "db-b1", "db-b2"},
+			},
+		},
+		{
+			name:    "ini ipv6 address is not a port",
+			content: "[v6]\nfe80::1\n",
+			format:  InventoryFormatINI,
+			groups: map[string][]string{
+				"v6": {"fe80::1"},
+			},
+		},
+		{
+			name:    "ini vars declared before group",
+			content: "[web:vars]\nport=80\n[web]\nweb1\n",
+			format:  InventoryFormatINI,
+			groups: map[string][]string{
+				"web": {"web1"},
+			},
+			groupVars: map[string]map[string]interface{}{
+				"web": {"port": 80},
+			},
+		},
+		{
+			name: "yaml groups children and vars",
+			content: `all:
+  hosts:
+    bastion:
+  children:
+    web:
+      hosts:
+        web[1:2]:
+          http_port: 8080
+    db:
+      hosts:
+        db1:2200:
+      vars:
+        backup: true
+  vars:
+    env: staging
+`,
+			format: InventoryFormatYAML,
+			groups: map[string][]string{
+				inventoryAllGroup:       {"bastion", "db1", "web1", "web2"},
+				inventoryUngroupedGroup: {"bastion"},
+				"web":                   {"web1", "web2"},
+				"db":                    {"db1"},
+			},
+			children: map[string][]string{
+				inventoryAllGroup: {"db", "ungrouped", "web"},
+			},
+			hostVars: map[string]map[string]interface{}{
+				"web1": {"http_port": 8080},
+				"web2": {"http_port": 8080},
+				"db1":  {"ansible_port": 2200},
+			},
+			groupVars: map[string]map[string]interface{}{
+				inventoryAllGroup: {"env": "staging"},
+				"db":              {"backup": true},
+			},
+		},
+		{
+			name: "yaml nested children",
+			content: `---
+prod:
+  children:
+    web:
+      hosts:
+        web1:
+    db:
+      hosts:
+        db1:
+`,
+			format: InventoryFormatYAML,
+			groups: map[string][]string{
+				"prod": {"db1", "web1"},
+				"web":  {"web1"},
+			},
+			children: map[string][]string{
+				inventoryAllGroup: {"prod", "ungrouped"},
+				"prod":            {"db", "web"},
+			},
+		},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inventory, err := ParseInventory(tt.content)
+			if err != nil {
+				t.Fatalf("ParseInventory() error = %v", err)
+			}
+			if inventory.Format != tt.format {
+				t.Errorf("Format = %q, want %q", inventory.Format, tt.format)
+			}
+	
+			for group, want := range tt.groups {
+				if inventory.Groups[group] == nil {
+					t.Errorf("group %s not found", group)
+					continue
+				}
+				if got := inventory.groupHosts(group); !equalStrings(got, want) {
+					t.Errorf("hosts of group %s = %v, want %v", group, got, want)
+				}
+			}
+			for group, want := range tt.children {
+				if got := sortedCopy(inventory.Groups[group].Children); !equalStrings(got, want) {
+					t.Errorf("children of group %s = %v, want %v", group, got, want)
+				}
+			}
+			for host, want := range tt.hostVars {
+				if inventory.Hosts[host] == nil {
+					t.Errorf("host %s not found", host)
+					continue
+				}
+				if got := inventory.Hosts[host].Vars; !reflect.DeepEqual(got, want) {
+					t.Errorf("vars of host %s = %#v, want %#v", host, got, want)
+				}
+			}
+			for group, want := range tt.groupVars {
+				if got := inventory.Groups[group].Vars; !reflect.DeepEqual(got, want) {
+					t.Errorf("vars of group %s = %#v, want %#v", group, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseInventoryErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []InventoryError
+	}{
+		{
+			name:    "ini invalid section header",
+			content: "[web]\nweb1\n[db\ndb1\n",
+			want:    []InventoryError{{Line: 3, Message: `invalid section header "[db"`}},
+		},
+		{
+			name:    "ini invalid section suffix",
+			content: "[web]\nweb1\n\n[web:hosts]\n",
+			want:    []InventoryError{{Line: 4, Message: `invalid section suffix "hosts", expected vars or children`}},
+		},
+		{
+			name:    "ini invalid host variable",
+			content: "[web]\nweb1\nweb2 port\n",
+			want:    []InventoryError{{Line: 3, Message: "expected key=value host variable assignment, got: port"}},
+		},
+		{
+			name:    "ini invalid group variable",
+			content: "[web]\nweb1\n[web:vars]\nport\n",
+			want:    []InventoryError{{Line: 4, Message: "expected key=value, got: port"}},
+		},
+		{
+			name:    "ini invalid port",
+			content: "web1:99999\n",
+			want:    []InventoryError{{Line: 1, Message: `invalid port in host "web1:99999"`}},
+		},
+		{
+			name:    "ini invalid range",
+			content: "[web]\nweb[5:1]\nweb[1:2\n",
+			want: []InventoryError{
+				{Line: 2, Message: `invalid range "5:1" in host "web[5:1]"`},
+				{Line: 3, Message: `unterminated range in host "web[1:2"`},
+			},
+		},
+		{
+			name:    "ini vars for undefined group",
+			content: "web1\n\n[db:vars]\nport=5432\n",
+			want:    []InventoryError{{Line: 3, Message: "section [db:vars] not valid for undefined group: db"}},
+		},
+		{
+			name:    "ini children includes undefined group",
+			content: "[web]\nweb1\n[prod:children]\nweb\ndb\n",
+			want:    []InventoryError{{Line: 5, Message: "section [prod:children] includes undefined group: db"}},
+		},
+		{
+			name:    "ini circular children",
+			content: "[a:children]\nb\n[b:children]\na\n",
+			want:    []InventoryError{{Line: 2, Message: "group b has a circular child relationship with a"}},
+		},
+		{
+			name:    "yaml invalid key",
+			content: "web:\n  hosts:\n    web1:\n  host:\n    web2:\n",
+			want:    []InventoryError{{Line: 4, Column: 3, Message: `invalid key "host" in group web, only hosts, vars and children are allowed`}},
+		},
+		{
+			name:    "yaml hosts must be a mapping",
+			content: "web:\n  hosts:\n    - web1\n",
+			want:    []InventoryError{{Line: 3, Column: 5, Message: "hosts of group web must be a mapping"}},
+		},
+		{
+			name:    "yaml syntax error",
+			content: "web:\n  hosts:\n    web1:\n   bad: [\n",
+			want:    nil, // 行号来自yaml库，只检查返回错误
+		},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseInventory(tt.content)
+			if !errors.Is(err, ErrInvalidInventory) {
+				t.Fatalf("ParseInventory() error = %v, want ErrInvalidInventory", err)
+			}
+			var validation *InventoryValidationError
+			if !errors.As(err, &validation) {
+				t.Fatalf("ParseInventory() error type = %T, want *InventoryValidationError", err)
+			}
+			if tt.want == nil {
+				if len(validation.Errors) == 0 || validation.Errors[0].Line == 0 {
+					t.Errorf("errors = %v, want at least one error with a line number", validation.Errors)
+				}
+				return
+			}
+			if !reflect.DeepEqual(validation.Errors, tt.want) {
+				t.Errorf("errors = %#v, want %#v", validation.Errors, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectInventoryFormat(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"", InventoryFormatINI},
+		{"web1\n", InventoryFormatINI},
+		{"# hosts\n[web]\nweb1\n", InventoryFormatINI},
+		{"web1 ansible_host=10.0.0.1\n", InventoryFormatINI},
+		{"---\nall:\n", InventoryFormatYAML},
+		{"all:\n  hosts:\n", InventoryFormatYAML},
+		{"web: # comment\n", InventoryFormatYAML},
+		{`{"all": {}}`, InventoryFormatYAML},
+	}
+	
+	for _, tt := range tests {
+		if got := detectInventoryFormat(tt.content); got != tt.want {
+			t.Errorf("detectInventoryFormat(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestMatchHosts(t *testing.T) {
+	inventory, err := ParseInventory("[web]\nweb1\nweb2\n[db]\ndb1\n[prod:children]\nweb\ndb\n[staging]\nweb2\nstage1\n")
+	if err != nil {
+		t.Fatalf("ParseInventory() error = %v", err)
+	}
+	
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"all", []string{"db1", "stage1", "web1", "web2"}},
+		{"*", []string{"db1", "stage1", "web1", "web2"}},
+		{"prod", []string{"db1", "web1", "web2"}},
+		{"web1,db1", []string{"db1", "web1"}},
+		{"web:db", []string{"db1", "web1", "web2"}},
+		{"prod:!db", []string{"web1", "web2"}},
+		{"prod:&staging", []string{"web2"}},
+		{"web*", []string{"web1", "web2"}},
+		{"~(web|db)1", []string{"db1", "web1"}},
+		{"~eb", nil},
+		{"~[", nil},
+		{"missing", nil},
+	}
+	
+	for _, tt := range tests {
+		if got := inventory.MatchHosts(tt.pattern); !equalStrings(got, tt.want) {
+			t.Errorf("MatchHosts(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+// equalStrings 比较两个字符串列表，nil与空列表相等
+func equalStrings(a, b []string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// sortedCopy 返回排序后的副本
+func sortedCopy(list []string) []string {
+	set := make(map[string]bool, len(list))
+	for _, item := range list {
+		set[item] = true
+	}
+	return sortedKeys(set)
+}
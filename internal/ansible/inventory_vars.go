@@ -0,0 +1,247 @@
+package ansible
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidInventoryVars group_vars/host_vars校验失败
+var ErrInvalidInventoryVars = errors.New("invalid inventory vars")
+
+const (
+	// inventoryVarsFile 写入group_vars和host_vars目录的变量文件名，不含扩展名
+	inventoryVarsFile = "server_manager_vars"
+	// inventoryVarsFormatYAML、inventoryVarsFormatJSON 变量内容格式
+	inventoryVarsFormatYAML = "yaml"
+	inventoryVarsFormatJSON = "json"
+)
+
+// ansibleVarName 合法的ansible变量名
+var ansibleVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateInventoryVarsContent 校验变量内容为映射且变量名合法，返回带行号的错误
+func ValidateInventoryVarsContent(format, content string) []InventoryError {
+	if strings.TrimSpace(content) == "" {
+		return []InventoryError{{Line: 1, Message: "vars content is empty"}}
+	}
+	
+	if format == inventoryVarsFormatJSON {
+		var vars map[string]interface{}
+		if err := json.Unmarshal([]byte(content), &vars); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return []InventoryError{{Line: 1 + strings.Count(content[:syntaxErr.Offset], "\n"), Message: syntaxErr.Error()}}
+			}
+			return []InventoryError{{Line: 1, Message: "vars must be a JSON object"}}
+		}
+		var errs []InventoryError
+		for _, name := range sortedKeys(vars) {
+			if !ansibleVarName.MatchString(name) {
+				errs = append(errs, InventoryError{Line: 1, Message: fmt.Sprintf("invalid variable name %q", name)})
+			}
+		}
+		return errs
+	}
+	
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return yamlErrors(err)
+	}
+	if len(doc.Content) == 0 {
+		return []InventoryError{{Line: 1, Message: "vars content is empty"}}
+	}
+	
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return []InventoryError{{Line: root.Line, Column: root.Column, Message: "vars must be a mapping"}}
+	}
+	
+	var errs []InventoryError
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i]
+		if !ansibleVarName.MatchString(key.Value) {
+			errs = append(errs, InventoryError{Line: key.Line, Column: key.Column, Message: fmt.Sprintf("invalid variable name %q", key.Value)})
+		}
+	}
+	return errs
+}
+
+// validateInventoryVarsName 校验组名或主机名，名称会作为目录名写入执行目录
+func validateInventoryVarsName(kind, name string) error {
+	switch {
+	case kind != InventoryVarsGroup && kind != InventoryVarsHost:
+		return fmt.Errorf("%w: unknown vars kind %q", ErrInvalidInventoryVars, kind)
+	case name == "" || name == "." || name == "..":
+		return fmt.Errorf("%w: invalid %s name %q", ErrInvalidInventoryVars, kind, name)
+	case strings.ContainsAny(name, "/\\\x00") || strings.TrimSpace(name) != name:
+		return fmt.Errorf("%w: invalid %s name %q", ErrInvalidInventoryVars, kind, name)
+	}
+	if kind == InventoryVarsGroup {
+		if message := validateGroupName(name); message != "" {
+			return fmt.Errorf("%w: %s", ErrInvalidInventoryVars, message)
+		}
+	}
+	return nil
+}
+
+// ListInventoryVars 列出inventory的变量集，kind为空时列出全部
+func (s *AnsibleService) ListInventoryVars(inventoryID uint, kind string) ([]InventoryVars, error) {
+	if _, err := s.GetInventory(inventoryID); err != nil {
+		return nil, err
+	}
+	
+	query := s.db.Where("inventory_id = ?", inventoryID)
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	
+	vars := []InventoryVars{}
+	if err := query.Order("kind ASC, name ASC").Find(&vars).Error; err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// GetInventoryVars 获取指定组或主机的变量集
+func (s *AnsibleService) GetInventoryVars(inventoryID uint, kind, name string) (*InventoryVars, error) {
+	var vars InventoryVars
+	err := s.db.Where("inventory_id = ? AND kind = ? AND name = ?", inventoryID, kind, name).First(&vars).Error
+	if err != nil {
+		return nil, err
+	}
+	return &vars, nil
+}
+
+// SaveInventoryVars 创建或替换指定组或主机的变量集；静态inventory中必须已定义该组或主机
+func (s *AnsibleService) SaveInventoryVars(inventoryID uint, userID uint, kind, name string, req *InventoryVarsRequest) (*InventoryVars, error) {
+	var inventory Inventory
+	if err := s.db.Where("id = ? AND user_id = ?", inventoryID, userID).First(&inventory).Error; err != nil {
+		return nil, err
+	}
+	
+	if err := validateInventoryVarsName(kind, name); err != nil {
+		return nil, err
+	}
+	
+	format := req.Format
+	if format == "" {
+		format = inventoryVarsFormatYAML
+	}
+	if errs := ValidateInventoryVarsContent(format, req.Content); len(errs) > 0 {
+		return nil, &InventoryVarsValidationError{Errors: errs}
+	}
+	
+	// 动态inventory的组和主机在执行时才能确定，不做检查
+	if inventory.Type != InventoryTypeDynamic {
+		parsed, err := ParseInventory(inventory.Content)
+		if err != nil {
+			return nil, fmt.Errorf("%w: inventory content is invalid", ErrInvalidInventoryVars)
+		}
+		if kind == InventoryVarsGroup && parsed.Groups[name] == nil {
+			return nil, fmt.Errorf("%w: group %q is not defined in inventory", ErrInvalidInventoryVars, name)
+		}
+		if kind == InventoryVarsHost && parsed.Hosts[name] == nil {
+			return nil, fmt.Errorf("%w: host %q is not defined in inventory", ErrInvalidInventoryVars, name)
+		}
+	}
+	
+	vars, err := s.GetInventoryVars(inventoryID, kind, name)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if vars == nil {
+		vars = &InventoryVars{InventoryID: inventoryID, Kind: kind, Name: name}
+	}
+	vars.Format = format
+	vars.Content = req.Content
+	vars.UserID = userID
+	
+	if err := s.db.Save(vars).Error; err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// DeleteInventoryVars 删除指定组或主机的变量集
+func (s *AnsibleService) DeleteInventoryVars(inventoryID uint, userID uint, kind, name string) error {
+	var inventory Inventory
+	if err := s.db.Where("id = ? AND user_id = ?", inventoryID, userID).First(&inventory).Error; err != nil {
+		return err
+	}
+	
+	result := s.db.Where("inventory_id = ? AND kind = ? AND name = ?", inventoryID, kind, name).Delete(&InventoryVars{})
+	if result.Error != nil {
+		return result.Error
+	}
+	
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	
+	return nil
+}
+
+// applyInventoryVars 执行前加载inventory的变量集，由执行器写入inventory旁的group_vars和host_vars目录
+func (s *AnsibleService) applyInventoryVars(inventoryID uint, opts *RunOptions) error {
+	if inventoryID == 0 {
+		return nil
+	}
+	
+	var vars []InventoryVars
+	if err := s.db.Where("inventory_id = ?", inventoryID).Order("kind ASC, name ASC").Find(&vars).Error; err != nil {
+		return fmt.Errorf("load inventory vars failed: %v", err)
+	}
+	opts.InventoryVars = vars
+	return nil
+}
+
+// prepareInventoryVars 将变量集写入inventory所在目录的group_vars/<组名>/和host_vars/<主机名>/，
+// 与受管服务器凭据文件位于同一目录但文件名不同
+func (e *DefaultCommandExecutor) prepareInventoryVars(runDir string, vars []InventoryVars) error {
+	for _, item := range vars {
+		if err := validateInventoryVarsName(item.Kind, item.Name); err != nil {
+			return err
+		}
+	
+		varsDir := filepath.Join(runDir, item.Kind+"_vars", item.Name)
+		if err := os.MkdirAll(varsDir, 0700); err != nil {
+			return fmt.Errorf("create %s vars dir for %s failed: %v", item.Kind, item.Name, err)
+		}
+	
+		ext := ".yml"
+		if item.Format == inventoryVarsFormatJSON {
+			ext = ".json"
+		}
+		if err := os.WriteFile(filepath.Join(varsDir, inventoryVarsFile+ext), []byte(item.Content), 0600); err != nil {
+			return fmt.Errorf("write %s vars for %s failed: %v", item.Kind, item.Name, err)
+		}
+	}
+	return nil
+}
+
+// InventoryVarsValidationError 变量内容校验失败，包含全部错误及行号
+type InventoryVarsValidationError struct {
+	Errors []InventoryError
+}
+
+// Error 实现error接口
+func (e *InventoryVarsValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, item := range e.Errors {
+		messages[i] = item.String()
+	}
+	return fmt.Sprintf("%v: %s", ErrInvalidInventoryVars, strings.Join(messages, "; "))
+}
+
+// Unwrap 使errors.Is(err, ErrInvalidInventoryVars)成立
+func (e *InventoryVarsValidationError) Unwrap() error {
+	return ErrInvalidInventoryVars
+}
@@ -0,0 +1,356 @@
+package ansible
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// CreateJobTemplate 创建作业模板
+func (s *AnsibleService) CreateJobTemplate(userID uint, req *JobTemplateRequest) (*JobTemplate, error) {
+	template := &JobTemplate{UserID: userID}
+	if err := s.applyJobTemplateRequest(template, req); err != nil {
+		return nil, err
+	}
+	
+	if err := s.db.Create(template).Error; err != nil {
+		return nil, err
+	}
+	
+	if err := s.fillJobTemplateSurvey(template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// UpdateJobTemplate 更新作业模板
+func (s *AnsibleService) UpdateJobTemplate(id uint, userID uint, req *JobTemplateRequest) (*JobTemplate, error) {
+	var template JobTemplate
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&template).Error; err != nil {
+		return nil, err
+	}
+	
+	if err := s.applyJobTemplateRequest(&template, req); err != nil {
+		return nil, err
+	}
+	
+	if err := s.db.Save(&template).Error; err != nil {
+		return nil, err
+	}
+	
+	if err := s.fillJobTemplateSurvey(&template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// applyJobTemplateRequest 校验请求并写入作业模板字段，inventory和vault ID按模板创建者校验
+func (s *AnsibleService) applyJobTemplateRequest(template *JobTemplate, req *JobTemplateRequest) error {
+	if _, err := s.GetPlaybook(req.PlaybookID); err != nil {
+		return fmt.Errorf("%w: playbook %d not found", ErrInvalidJobTemplate, req.PlaybookID)
+	}
+	
+	template.InventoryID = nil
+	if req.InventoryID != 0 {
+		if _, err := s.resolveInventory(template.UserID, req.InventoryID, "", "", nil); err != nil {
+			return fmt.Errorf("%w: inventory %d: %v", ErrInvalidJobTemplate, req.InventoryID, err)
+		}
+		inventoryID := req.InventoryID
+		template.InventoryID = &inventoryID
+	}
+	
+	vaultIDs, err := s.resolveVaultIDs(template.UserID, req.VaultIDs)
+	if err != nil {
+		return err
+	}
+	
+	template.ExtraVars = ""
+	if req.ExtraVars != nil {
+		extraVarsJSON, err := json.Marshal(req.ExtraVars)
+		if err != nil {
+			return fmt.Errorf("marshal extra vars failed: %v", err)
+		}
+		template.ExtraVars = string(extraVarsJSON)
+	}
+	
+	survey, err := s.sealSurveySpec(template, req.Survey)
+	if err != nil {
+		return err
+	}
+	
+	template.Name = req.Name
+	template.Description = req.Description
+	template.PlaybookID = req.PlaybookID
+	template.Tags = req.Tags
+	template.SkipTags = req.SkipTags
+	template.TimeoutSeconds = req.TimeoutSeconds
+	template.Check = req.Check
+	template.Diff = req.Diff
+	template.Priority = req.Priority
+	template.VaultIDs = strings.Join(vaultIDs, ",")
+	template.SurveySpec = survey
+	
+	return nil
+}
+
+// sealSurveySpec 校验问卷并加密密码类型的默认值，返回保存的JSON；
+// 默认值为掩码时沿用模板中已保存的默认值
+func (s *AnsibleService) sealSurveySpec(template *JobTemplate, questions []SurveyQuestion) (string, error) {
+	if len(questions) == 0 {
+		return "", nil
+	}
+	
+	previous, err := s.openSurveySpec(template.SurveySpec)
+	if err != nil {
+		return "", err
+	}
+	for i := range questions {
+		q := &questions[i]
+		if q.Type != SurveyTypePassword || q.Default != surveyPasswordMask {
+			continue
+		}
+		q.Default = nil
+		for _, old := range previous {
+			if old.Variable == q.Variable && old.Type == SurveyTypePassword {
+				q.Default = old.Default
+			}
+		}
+	}
+	
+	if err := validateSurveySpec(questions); err != nil {
+		return "", err
+	}
+	
+	for i := range questions {
+		q := &questions[i]
+		if q.Type != SurveyTypePassword || q.Default == nil {
+			continue
+		}
+		sealed, err := s.sealVaultPassword(q.Default.(string))
+		if err != nil {
+			return "", err
+		}
+		q.Default = sealed
+	}
+	
+	data, err := json.Marshal(questions)
+	if err != nil {
+		return "", fmt.Errorf("marshal survey failed: %v", err)
+	}
+	return string(data), nil
+}
+
+// openSurveySpec 解析保存的问卷并解密密码类型的默认值
+func (s *AnsibleService) openSurveySpec(spec string) ([]SurveyQuestion, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	
+	var questions []SurveyQuestion
+	if err := json.Unmarshal([]byte(spec), &questions); err != nil {
+		return nil, fmt.Errorf("unmarshal survey failed: %v", err)
+	}
+	for i := range questions {
+		q := &questions[i]
+		sealed, ok := q.Default.(string)
+		if q.Type != SurveyTypePassword || !ok {
+			continue
+		}
+		password, err := s.openVaultPassword(sealed)
+		if err != nil {
+			return nil, err
+		}
+		q.Default = password
+	}
+	return questions, nil
+}
+
+// fillJobTemplateSurvey 解析保存的问卷用于返回，密码类型的默认值显示为掩码
+func (s *AnsibleService) fillJobTemplateSurvey(template *JobTemplate) error {
+	template.Survey = []SurveyQuestion{}
+	if template.SurveySpec == "" {
+		return nil
+	}
+	
+	if err := json.Unmarshal([]byte(template.SurveySpec), &template.Survey); err != nil {
+		return fmt.Errorf("unmarshal survey failed: %v", err)
+	}
+	for i := range template.Survey {
+		q := &template.Survey[i]
+		if q.Type == SurveyTypePassword && q.Default != nil {
+			q.Default = surveyPasswordMask
+		}
+	}
+	return nil
+}
+
+// DeleteJobTemplate 删除作业模板，已产生的执行记录保留
+func (s *AnsibleService) DeleteJobTemplate(id uint, userID uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&JobTemplate{})
+	if result.Error != nil {
+		return result.Error
+	}
+	
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	
+	return nil
+}
+
+// GetJobTemplate 获取作业模板
+func (s *AnsibleService) GetJobTemplate(id uint) (*JobTemplate, error) {
+	var template JobTemplate
+	err := s.db.First(&template, id).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fillJobTemplateSurvey(&template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// ListJobTemplates 列出作业模板
+func (s *AnsibleService) ListJobTemplates(userID uint, offset, limit int) ([]JobTemplate, int64, error) {
+	var templates []JobTemplate
+	var total int64
+	
+	query := s.db.Model(&JobTemplate{}).Where("user_id = ?", userID)
+	
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	// 获取分页数据
+	err := query.Order("name ASC").Offset(offset).Limit(limit).Find(&templates).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	for i := range templates {
+		if err := s.fillJobTemplateSurvey(&templates[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	
+	return templates, total, nil
+}
+
+// LaunchJobTemplate 校验问卷答案并启动作业模板，答案覆盖模板中的同名额外变量
+func (s *AnsibleService) LaunchJobTemplate(ctx context.Context, id uint, userID uint, req *JobTemplateLaunchRequest) (*PlaybookExecution, error) {
+	var template JobTemplate
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&template).Error; err != nil {
+		return nil, err
+	}
+	
+	questions, err := s.openSurveySpec(template.SurveySpec)
+	if err != nil {
+		return nil, err
+	}
+	values, err := validateSurveyAnswers(questions, req.Answers)
+	if err != nil {
+		return nil, err
+	}
+	
+	extraVars := make(map[string]interface{})
+	if template.ExtraVars != "" {
+		if err := json.Unmarshal([]byte(template.ExtraVars), &extraVars); err != nil {
+			return nil, fmt.Errorf("unmarshal extra vars failed: %v", err)
+		}
+	}
+	var secretVars []string
+	for _, q := range questions {
+		value, ok := values[q.Variable]
+		if !ok {
+			continue
+		}
+		extraVars[q.Variable] = value
+		if q.Type == SurveyTypePassword {
+			secretVars = append(secretVars, q.Variable)
+		}
+	}
+	
+	var inventoryID uint
+	if template.InventoryID != nil {
+		inventoryID = *template.InventoryID
+	}
+	var vaultIDs []string
+	if template.VaultIDs != "" {
+		vaultIDs = strings.Split(template.VaultIDs, ",")
+	}
+	templateID := template.ID
+	
+	return s.ExecutePlaybook(ctx, userID, template.PlaybookID, &PlaybookExecutionRequest{
+		InventoryID:    inventoryID,
+		ExtraVars:      extraVars,
+		Tags:           template.Tags,
+		SkipTags:       template.SkipTags,
+		TimeoutSeconds: template.TimeoutSeconds,
+		Check:          template.Check,
+		Diff:           template.Diff,
+		Priority:       template.Priority,
+		VaultIDs:       vaultIDs,
+		JobTemplateID:  &templateID,
+		SurveyAnswers:  maskSurveyAnswers(questions, values),
+		SecretVars:     secretVars,
+	})
+}
+
+// sealSecretVars 将需要保密的额外变量加密后移到SealedVars，队列中不保存明文；
+// 返回写入执行记录的额外变量，保密变量替换为掩码
+func (s *AnsibleService) sealSecretVars(req *PlaybookExecutionRequest) (map[string]interface{}, error) {
+	// 加密变量只能在入队时生成
+	req.SealedVars = nil
+	if len(req.SecretVars) == 0 {
+		return req.ExtraVars, nil
+	}
+	
+	extraVars := make(map[string]interface{}, len(req.ExtraVars))
+	recorded := make(map[string]interface{}, len(req.ExtraVars))
+	for name, value := range req.ExtraVars {
+		extraVars[name] = value
+		recorded[name] = value
+	}
+	
+	req.SealedVars = make(map[string]string)
+	for _, name := range req.SecretVars {
+		value, ok := extraVars[name].(string)
+		if !ok {
+			continue
+		}
+		sealed, err := s.sealVaultPassword(value)
+		if err != nil {
+			return nil, err
+		}
+		req.SealedVars[name] = sealed
+		delete(extraVars, name)
+		recorded[name] = surveyPasswordMask
+	}
+	req.ExtraVars = extraVars
+	return recorded, nil
+}
+
+// applySealedVars 执行前解密入队时加密的额外变量，明文只传给执行器并在输出中屏蔽
+func (s *AnsibleService) applySealedVars(req *PlaybookExecutionRequest, opts *RunOptions) error {
+	if len(req.SealedVars) == 0 {
+		return nil
+	}
+	
+	if req.ExtraVars == nil {
+		req.ExtraVars = make(map[string]interface{})
+	}
+	for _, name := range sortedKeys(req.SealedVars) {
+		value, err := s.openVaultPassword(req.SealedVars[name])
+		if err != nil {
+			return fmt.Errorf("decrypt extra var %s failed: %v", name, err)
+		}
+		req.ExtraVars[name] = value
+		opts.Secrets = append(opts.Secrets, value)
+	}
+	return nil
+}
@@ -4,46 +4,287 @@ import (
 	"time"
 )
 
+// 执行类型
+const (
+	ExecutionTypeAdhoc    = "adhoc"
+	ExecutionTypePlaybook = "playbook"
+)
+
+// Inventory类型
+const (
+	InventoryTypeStatic  = "static"
+	InventoryTypeDynamic = "dynamic" // 根据受管服务器和服务器组生成
+)
+
+// 执行状态
+const (
+	StatusPending         = "pending"
+	StatusRunning         = "running"
+	StatusSuccess         = "success"
+	StatusFailed          = "failed"
+	StatusCancelled       = "cancelled"
+	StatusTimedOut        = "timed_out"
+	StatusInterrupted     = "interrupted"      // 服务关闭或重启导致执行中断
+	StatusPendingApproval = "pending_approval" // 涉及受保护对象，等待审批后才进入执行队列
+	StatusRejected        = "rejected"         // 审批被拒绝或等待审批超时，不会执行
+)
+
+// 执行队列任务状态
+const (
+	JobStatusQueued  = "queued"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusHeld    = "held" // 执行等待审批，审批通过后变为queued
+)
+
+// 执行的审批状态
+const (
+	ApprovalPending   = "pending"
+	ApprovalApproved  = "approved"
+	ApprovalRejected  = "rejected"
+	ApprovalExpired   = "expired"   // 超过审批期限未处理
+	ApprovalCancelled = "cancelled" // 审批前被取消
+)
+
+// ApprovalRequested 审批记录中提交审批的动作，其余动作与审批状态相同
+const ApprovalRequested = "requested"
+
+// 受保护对象类型
+const (
+	ProtectedServerGroup = "server_group"
+	ProtectedInventory   = "inventory"
+	ProtectedPlaybook    = "playbook"
+	ProtectedProject     = "project"
+)
+
+// 项目文件来源
+const (
+	ProjectSourceFiles = "files" // 上传压缩包或在线编辑的文件
+	ProjectSourceGit   = "git"   // 从git仓库同步，文件只读
+)
+
+// Inventory变量集类型
+const (
+	InventoryVarsGroup = "group" // group_vars，按组名
+	InventoryVarsHost  = "host"  // host_vars，按主机名
+)
+
+// 定时任务错过执行时间后的补偿策略
+const (
+	CatchUpSkip = "skip" // 跳过错过的执行，等待下一次执行时间
+	CatchUpOnce = "once" // 无论错过多少次，只补执行一次
+	CatchUpAll  = "all"  // 补执行每一次错过的执行，受最大补偿次数限制
+)
+
+// 工作流边的触发条件
+const (
+	WorkflowEdgeSuccess = "success" // 上游节点成功时执行
+	WorkflowEdgeFailure = "failure" // 上游节点失败时执行
+	WorkflowEdgeAlways  = "always"  // 上游节点结束后总是执行
+)
+
+// WorkflowNodeSkipped 没有满足条件的入边而未执行的工作流节点状态
+const WorkflowNodeSkipped = "skipped"
+
+// 模块授权对象类型
+const (
+	ModuleGrantRole = "role"
+	ModuleGrantUser = "user"
+)
+
+// 命令策略规则的动作
+const (
+	PolicyActionDeny = "deny" // 匹配时拒绝执行
+	PolicyActionWarn = "warn" // 匹配时需要在第二次请求中提供确认令牌
+)
+
+// 命令策略审计记录的动作
+const (
+	PolicyAuditBlocked    = "blocked"    // 被拒绝规则阻止，或自动执行时匹配了警告规则
+	PolicyAuditWarned     = "warned"     // 匹配了警告规则，已签发确认令牌
+	PolicyAuditOverridden = "overridden" // 使用确认令牌越过警告规则执行
+)
+
+// 重新执行的主机范围
+const (
+	RelaunchModeAll         = "all"          // 按原执行的主机范围重新执行
+	RelaunchModeFailedHosts = "failed_hosts" // 只对原执行中失败或不可达的主机重新执行
+)
+
+// 作业模板问卷的问题类型
+const (
+	SurveyTypeString      = "string"
+	SurveyTypeInteger     = "integer"
+	SurveyTypeChoice      = "choice"       // 从choices中单选
+	SurveyTypeMultiChoice = "multi_choice" // 从choices中多选，变量值为列表
+	SurveyTypePassword    = "password"     // 保存和记录时加密或掩码
+	SurveyTypeBoolean     = "boolean"
+)
+
 // AdhocExecution 表示adhoc命令执行记录
 type AdhocExecution struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
 	Command     string    `json:"command" gorm:"not null"`                    // 执行的命令
 	Module      string    `json:"module" gorm:"not null"`                     // ansible模块名称 (shell, command, copy等)
 	Args        string    `json:"args"`                                       // 模块参数
-	Inventory   string    `json:"inventory" gorm:"type:text"`                 // inventory内容 (可以是主机列表或文件路径)
+	InventoryID *uint     `json:"inventory_id" gorm:"index"`                  // 使用的inventory ID，直接提供内容时为空
+	Inventory   string    `json:"inventory" gorm:"type:text"`                 // 实际使用的inventory内容快照
 	Hosts       string    `json:"hosts" gorm:"not null"`                      // 目标主机或组
+	Limit       string    `json:"limit"`                                      // 限制执行的主机，传给--limit
+	DynamicInventory string `json:"-" gorm:"type:text"`                       // 动态inventory过滤条件JSON格式，重新执行时重新生成inventory
 	ExtraVars   string    `json:"extra_vars" gorm:"type:text"`                // 额外变量JSON格式
-	Status      string    `json:"status" gorm:"default:'pending'"`            // pending, running, success, failed
+	Status      string    `json:"status" gorm:"default:'pending'"`            // pending_approval, pending, running, success, failed, cancelled, timed_out, interrupted, rejected
 	Output      string    `json:"output" gorm:"type:text"`                    // 命令输出
 	ErrorOutput string    `json:"error_output" gorm:"type:text"`              // 错误输出
 	ExitCode    int       `json:"exit_code" gorm:"default:0"`                 // 退出码
 	StartTime   *time.Time `json:"start_time"`                                // 开始时间
 	EndTime     *time.Time `json:"end_time"`                                  // 结束时间
 	Duration    int       `json:"duration"`                                   // 执行时长(秒)
+	TimeoutSeconds int    `json:"timeout_seconds"`                            // 请求的超时时间(秒)，0表示使用默认值
+	DryRun      bool      `json:"dry_run" gorm:"default:false;index"`         // 是否为check模式的试运行，不会产生实际变更
+	DiffMode    bool      `json:"diff_mode" gorm:"default:false"`             // 是否记录变更内容
+	VaultIDs    string    `json:"vault_ids"`                                  // 使用的vault ID名称，逗号分隔
+	ScheduleID  *uint     `json:"schedule_id" gorm:"index"`                   // 触发执行的定时任务ID，手动执行时为空
+	WorkflowRunID *uint   `json:"workflow_run_id" gorm:"index"`               // 所属的工作流运行ID，单独执行时为空
+	RelaunchOfID *uint    `json:"relaunch_of_id" gorm:"index"`                // 重新执行的原执行ID，不是重新执行时为空
+	RelaunchMode string   `json:"relaunch_mode"`                              // 重新执行的主机范围：all, failed_hosts
+	Artifacts   string    `json:"artifacts" gorm:"type:text"`                 // set_stats产生的执行产物JSON格式
 	UserID      uint      `json:"user_id" gorm:"not null"`                    // 执行用户ID
+	CancelledBy *uint     `json:"cancelled_by"`                               // 取消执行的用户ID
+	CancelledAt *time.Time `json:"cancelled_at"`                              // 取消时间
+	ApprovalStatus string `json:"approval_status,omitempty" gorm:"index"`     // pending, approved, rejected, expired, cancelled；不需要审批时为空
+	ProtectedTargets string `json:"protected_targets,omitempty"`              // 需要审批的受保护对象，逗号分隔
+	ApprovalExpiresAt *time.Time `json:"approval_expires_at,omitempty"`       // 审批期限，超时后执行被拒绝
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	
+	Approvals   []ExecutionApproval `json:"approvals,omitempty" gorm:"polymorphic:Execution;polymorphicValue:adhoc"` // 审批记录
+	HostResults []HostResult `json:"host_results,omitempty" gorm:"polymorphic:Execution;polymorphicValue:adhoc"` // 每个主机的任务结果
+	Recap       []HostRecap  `json:"recap,omitempty" gorm:"polymorphic:Execution;polymorphicValue:adhoc"`        // 每个主机的汇总统计
+	Diffs       []TaskDiff   `json:"diffs,omitempty" gorm:"polymorphic:Execution;polymorphicValue:adhoc"`        // diff模式下的变更内容
 }
 
 // PlaybookExecution 表示playbook执行记录
 type PlaybookExecution struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
+	PlaybookID  uint      `json:"playbook_id" gorm:"index"`                   // 关联的playbook ID，项目执行时为0
+	ProjectID   *uint     `json:"project_id" gorm:"index"`                    // 关联的项目ID，单文件playbook执行时为空
 	Name        string    `json:"name" gorm:"not null"`                       // playbook名称
 	PlaybookPath string   `json:"playbook_path" gorm:"not null"`              // playbook文件路径
-	Inventory   string    `json:"inventory" gorm:"type:text"`                 // inventory内容
+	PlaybookRevisionID *uint `json:"playbook_revision_id" gorm:"index"`       // 执行的playbook版本记录ID
+	PlaybookRevision int  `json:"playbook_revision"`                          // 执行的playbook版本号
+	PlaybookHash string   `json:"playbook_hash"`                              // 执行的playbook内容SHA-256
+	CommitSHA   string    `json:"commit_sha"`                                 // git项目执行时检出的提交
+	InventoryID *uint     `json:"inventory_id" gorm:"index"`                  // 使用的inventory ID，直接提供内容时为空
+	Inventory   string    `json:"inventory" gorm:"type:text"`                 // 实际使用的inventory内容快照
+	DynamicInventory string `json:"-" gorm:"type:text"`                       // 动态inventory过滤条件JSON格式，重新执行时重新生成inventory
+	Limit       string    `json:"limit"`                                      // 限制执行的主机，传给--limit
 	ExtraVars   string    `json:"extra_vars" gorm:"type:text"`                // 额外变量JSON格式
+	SealedVars  string    `json:"-" gorm:"type:text"`                         // 加密保存的保密变量JSON格式，重新执行时解密使用
 	Tags        string    `json:"tags"`                                       // 标签
 	SkipTags    string    `json:"skip_tags"`                                  // 跳过的标签
-	Status      string    `json:"status" gorm:"default:'pending'"`            // pending, running, success, failed
+	Status      string    `json:"status" gorm:"default:'pending'"`            // pending_approval, pending, running, success, failed, cancelled, timed_out, interrupted, rejected
 	Output      string    `json:"output" gorm:"type:text"`                    // 命令输出
 	ErrorOutput string    `json:"error_output" gorm:"type:text"`              // 错误输出
 	ExitCode    int       `json:"exit_code" gorm:"default:0"`                 // 退出码
 	StartTime   *time.Time `json:"start_time"`                                // 开始时间
 	EndTime     *time.Time `json:"end_time"`                                  // 结束时间
 	Duration    int       `json:"duration"`                                   // 执行时长(秒)
+	TimeoutSeconds int    `json:"timeout_seconds"`                            // 请求的超时时间(秒)，0表示使用默认值
+	DryRun      bool      `json:"dry_run" gorm:"default:false;index"`         // 是否为check模式的试运行，不会产生实际变更
+	DiffMode    bool      `json:"diff_mode" gorm:"default:false"`             // 是否记录变更内容
+	VaultIDs    string    `json:"vault_ids"`                                  // 使用的vault ID名称，逗号分隔
+	ScheduleID  *uint     `json:"schedule_id" gorm:"index"`                   // 触发执行的定时任务ID，手动执行时为空
+	WorkflowRunID *uint   `json:"workflow_run_id" gorm:"index"`               // 所属的工作流运行ID，单独执行时为空
+	RelaunchOfID *uint    `json:"relaunch_of_id" gorm:"index"`                // 重新执行的原执行ID，不是重新执行时为空
+	RelaunchMode string   `json:"relaunch_mode"`                              // 重新执行的主机范围：all, failed_hosts
+	Artifacts   string    `json:"artifacts" gorm:"type:text"`                 // set_stats产生的执行产物JSON格式
+	JobTemplateID *uint   `json:"job_template_id" gorm:"index"`               // 启动执行的作业模板ID，直接执行时为空
+	SurveyAnswers string  `json:"survey_answers" gorm:"type:text"`            // 作业模板问卷答案JSON格式，密码类型已掩码
 	UserID      uint      `json:"user_id" gorm:"not null"`                    // 执行用户ID
+	CancelledBy *uint     `json:"cancelled_by"`                               // 取消执行的用户ID
+	CancelledAt *time.Time `json:"cancelled_at"`                              // 取消时间
+	ApprovalStatus string `json:"approval_status,omitempty" gorm:"index"`     // pending, approved, rejected, expired, cancelled；不需要审批时为空
+	ProtectedTargets string `json:"protected_targets,omitempty"`              // 需要审批的受保护对象，逗号分隔
+	ApprovalExpiresAt *time.Time `json:"approval_expires_at,omitempty"`       // 审批期限，超时后执行被拒绝
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	
+	Approvals   []ExecutionApproval `json:"approvals,omitempty" gorm:"polymorphic:Execution;polymorphicValue:playbook"` // 审批记录
+	HostResults []HostResult `json:"host_results,omitempty" gorm:"polymorphic:Execution;polymorphicValue:playbook"` // 每个主机的任务结果
+	Recap       []HostRecap  `json:"recap,omitempty" gorm:"polymorphic:Execution;polymorphicValue:playbook"`        // 每个主机的汇总统计
+	Diffs       []TaskDiff   `json:"diffs,omitempty" gorm:"polymorphic:Execution;polymorphicValue:playbook"`        // diff模式下的变更内容
+}
+
+// ExecutionApproval 表示执行的一条审批记录：提交审批、批准、拒绝、过期或取消
+type ExecutionApproval struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	ExecutionType string    `json:"execution_type" gorm:"not null;index:idx_approval_execution"` // adhoc, playbook
+	ExecutionID   uint      `json:"execution_id" gorm:"not null;index:idx_approval_execution"`   // 对应的执行记录ID
+	Action        string    `json:"action" gorm:"not null"`                                      // requested, approved, rejected, expired, cancelled
+	UserID        *uint     `json:"user_id"`                                                     // 操作用户ID，过期时为空
+	Comment       string    `json:"comment" gorm:"type:text"`                                    // 审批意见
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// HostResult 表示单个主机上单个任务的执行结果
+type HostResult struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	ExecutionType string    `json:"execution_type" gorm:"not null;index:idx_host_result_execution"` // adhoc, playbook
+	ExecutionID   uint      `json:"execution_id" gorm:"not null;index:idx_host_result_execution"`   // 对应的执行记录ID
+	Host          string    `json:"host" gorm:"not null;index"`                                     // 主机名
+	Task          string    `json:"task"`                                                           // 任务名称
+	Status        string    `json:"status" gorm:"not null"`                                         // ok, failed, ignored, unreachable, skipped
+	Changed       bool      `json:"changed"`                                                        // 是否产生变更
+	RC            *int      `json:"rc"`                                                             // 模块返回码
+	Stdout        string    `json:"stdout" gorm:"type:text"`
+	Stderr        string    `json:"stderr" gorm:"type:text"`
+	Msg           string    `json:"msg" gorm:"type:text"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// HostRecap 表示单个主机在一次执行中的汇总统计
+type HostRecap struct {
+	ID            uint   `json:"id" gorm:"primaryKey"`
+	ExecutionType string `json:"execution_type" gorm:"not null;index:idx_host_recap_execution"` // adhoc, playbook
+	ExecutionID   uint   `json:"execution_id" gorm:"not null;index:idx_host_recap_execution"`   // 对应的执行记录ID
+	Host          string `json:"host" gorm:"not null"`
+	Ok            int    `json:"ok"`
+	Changed       int    `json:"changed"`
+	Failed        int    `json:"failed"`
+	Unreachable   int    `json:"unreachable"`
+	Skipped       int    `json:"skipped"`
+	Rescued       int    `json:"rescued"`
+	Ignored       int    `json:"ignored"`
+}
+
+// TaskDiff 表示diff模式下单个主机上单个任务产生的变更内容
+type TaskDiff struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	ExecutionType string    `json:"execution_type" gorm:"not null;index:idx_task_diff_execution"` // adhoc, playbook
+	ExecutionID   uint      `json:"execution_id" gorm:"not null;index:idx_task_diff_execution"`   // 对应的执行记录ID
+	Host          string    `json:"host" gorm:"not null"`                                         // 主机名
+	Task          string    `json:"task"`                                                         // 任务名称
+	BeforeHeader  string    `json:"before_header"`                                                // 变更前内容的标题(通常为文件路径)
+	AfterHeader   string    `json:"after_header"`                                                 // 变更后内容的标题
+	Before        string    `json:"before" gorm:"type:text"`                                      // 变更前内容
+	After         string    `json:"after" gorm:"type:text"`                                       // 变更后内容
+	Prepared      string    `json:"prepared,omitempty" gorm:"type:text"`                          // 模块已生成好的差异文本
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ExecutionJob 表示执行队列中的任务，保证服务重启后等待中的执行可以继续
+type ExecutionJob struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	ExecutionType string     `json:"execution_type" gorm:"not null;index:idx_job_execution"` // adhoc, playbook
+	ExecutionID   uint       `json:"execution_id" gorm:"not null;index:idx_job_execution"`   // 对应的执行记录ID
+	Priority      int        `json:"priority" gorm:"default:0"`                              // 优先级，数值越大越先执行
+	Status        string     `json:"status" gorm:"not null;default:'queued';index"`          // queued, held, running, done
+	Payload       string     `json:"-" gorm:"type:text"`                                     // 执行请求JSON，任务完成后清空
+	StartedAt     *time.Time `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
 // Inventory 表示inventory管理
@@ -54,11 +295,25 @@ type Inventory struct {
 	Type        string    `json:"type" gorm:"not null;default:'static'"`      // static, dynamic
 	Content     string    `json:"content" gorm:"type:text"`                   // inventory内容
 	IsDefault   bool      `json:"is_default" gorm:"default:false"`            // 是否为默认inventory
+	Protected   bool      `json:"protected" gorm:"default:false"`             // 受保护，使用该inventory的执行需要审批
 	UserID      uint      `json:"user_id" gorm:"not null"`                    // 创建用户ID
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// InventoryVars 表示inventory的一组group_vars或host_vars，执行时写入inventory旁的group_vars/<组名>/或host_vars/<主机名>/目录
+type InventoryVars struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	InventoryID uint      `json:"inventory_id" gorm:"not null;uniqueIndex:idx_inventory_vars"` // 所属inventory ID
+	Kind        string    `json:"kind" gorm:"not null;uniqueIndex:idx_inventory_vars"`         // group, host
+	Name        string    `json:"name" gorm:"not null;uniqueIndex:idx_inventory_vars"`         // 组名或主机名
+	Format      string    `json:"format" gorm:"not null;default:'yaml'"`                       // yaml, json
+	Content     string    `json:"content" gorm:"type:text"`                                    // 变量内容，必须为映射
+	UserID      uint      `json:"user_id" gorm:"not null"`                                     // 最后修改用户ID
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
 // Playbook 表示playbook管理
 type Playbook struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
@@ -67,27 +322,327 @@ type Playbook struct {
 	FileName    string    `json:"file_name" gorm:"not null"`                  // 文件名
 	Content     string    `json:"content" gorm:"type:text"`                   // playbook内容(YAML)
 	Tags        string    `json:"tags"`                                       // 标签，逗号分隔
+	Revision    int       `json:"revision" gorm:"default:0"`                  // 当前版本号
+	Protected   bool      `json:"protected" gorm:"default:false"`             // 受保护，执行该playbook需要审批
 	UserID      uint      `json:"user_id" gorm:"not null"`                    // 创建用户ID
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// Project 表示包含roles、templates、files等多个文件的playbook项目，文件保存在WorkDir下的项目目录中
+type Project struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	Name            string    `json:"name" gorm:"not null;uniqueIndex"` // 项目名称
+	Description     string    `json:"description"`                      // 描述
+	DefaultPlaybook string    `json:"default_playbook"`                 // 默认入口playbook，相对项目根目录的路径
+	SourceType      string    `json:"source_type" gorm:"not null;default:'files'"` // files, git
+	GitURL          string    `json:"git_url"`                          // git仓库地址：本地路径、file://或ssh地址
+	GitRef          string    `json:"git_ref"`                          // 同步的分支或标签，为空时使用远端默认分支
+	DeployKey       string    `json:"-" gorm:"type:text"`               // 访问仓库的SSH私钥，不返回给客户端
+	SyncInterval    int       `json:"sync_interval"`                    // 自动同步间隔(分钟)，0表示只手动同步
+	CommitSHA       string    `json:"commit_sha"`                       // 最近一次成功同步的提交
+	LastSyncedAt    *time.Time `json:"last_synced_at"`                  // 最近一次同步时间，失败也会更新
+	LastSyncError   string    `json:"last_sync_error"`                  // 最近一次同步的错误信息
+	Protected       bool      `json:"protected" gorm:"default:false"`   // 受保护，执行该项目需要审批
+	UserID          uint      `json:"user_id" gorm:"not null"`          // 创建用户ID
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ProjectFile 表示项目文件树中的一个文件或目录
+type ProjectFile struct {
+	Path       string    `json:"path"` // 相对项目根目录的路径，使用/分隔
+	Type       string    `json:"type"` // file, dir
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// PlaybookRevision 表示playbook的一个不可变版本，每次保存playbook都会产生新版本
+type PlaybookRevision struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	PlaybookID  uint      `json:"playbook_id" gorm:"not null;uniqueIndex:idx_playbook_revision"` // 所属playbook ID
+	Revision    int       `json:"revision" gorm:"not null;uniqueIndex:idx_playbook_revision"`    // 版本号，从1开始递增
+	Content     string    `json:"content,omitempty" gorm:"type:text"`                            // 该版本的playbook内容
+	ContentHash string    `json:"content_hash" gorm:"not null;index"`                            // 内容的SHA-256
+	Message     string    `json:"message"`                                                       // 变更说明
+	AuthorID    uint      `json:"author_id" gorm:"not null"`                                     // 保存该版本的用户ID
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Schedule 表示按cron表达式定时执行的adhoc命令或playbook
+type Schedule struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	Name            string     `json:"name" gorm:"not null;uniqueIndex"`             // 定时任务名称
+	Description     string     `json:"description"`                                  // 描述
+	CronExpression  string     `json:"cron_expression" gorm:"not null"`              // 标准5段cron表达式或@daily等描述符
+	Timezone        string     `json:"timezone" gorm:"not null;default:'UTC'"`       // 计算执行时间使用的时区
+	Enabled         bool       `json:"enabled" gorm:"default:true;index"`            // 是否启用
+	Type            string     `json:"type" gorm:"not null"`                         // adhoc, playbook
+	PlaybookID      *uint      `json:"playbook_id" gorm:"index"`                     // playbook类型执行的playbook ID
+	Module          string     `json:"module"`                                       // adhoc类型的模块名称
+	Args            string     `json:"args"`                                         // adhoc类型的模块参数
+	Hosts           string     `json:"hosts"`                                        // adhoc类型的目标主机或组
+	InventoryID     *uint      `json:"inventory_id"`                                 // 使用的inventory ID，为空时使用默认inventory
+	ExtraVars       string     `json:"extra_vars" gorm:"type:text"`                  // 额外变量JSON格式
+	CatchUpPolicy   string     `json:"catch_up_policy" gorm:"not null;default:'skip'"` // skip, once, all
+	NextRunAt       *time.Time `json:"next_run_at" gorm:"index"`                     // 下一次执行时间，未启用时为空
+	LastRunAt       *time.Time `json:"last_run_at"`                                  // 最近一次触发时间
+	LastExecutionID *uint      `json:"last_execution_id"`                            // 最近一次触发的执行记录ID
+	LastStatus      string     `json:"last_status" gorm:"-"`                         // 最近一次执行的状态，读取时从执行记录获取
+	LastError       string     `json:"last_error"`                                   // 最近一次触发失败的原因
+	UserID          uint       `json:"user_id" gorm:"not null;index"`                // 创建用户ID，定时执行以该用户身份进行
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// AnsibleModule 表示adhoc命令允许使用的模块，名称以.*结尾时匹配该集合下的所有模块
+type AnsibleModule struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"not null;uniqueIndex"`           // 模块名称，如shell、community.general.ufw、community.*
+	Category    string    `json:"category" gorm:"not null;index"`             // 模块分类，授权时可按分类授予
+	Description string    `json:"description"`                                // 描述
+	Enabled     bool      `json:"enabled" gorm:"default:true"`                // 停用后所有用户都不能使用
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ModuleGrant 表示授予角色或用户的模块和模块分类，用户可用的模块为其角色和本人授权的并集
+type ModuleGrant struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	SubjectType string    `json:"subject_type" gorm:"not null;uniqueIndex:idx_module_grant_subject"` // role, user
+	Subject     string    `json:"subject" gorm:"not null;uniqueIndex:idx_module_grant_subject"`      // 角色名称或用户ID
+	Modules     string    `json:"modules"`                                                           // 授予的模块名称，逗号分隔
+	Categories  string    `json:"categories"`                                                        // 授予的模块分类，逗号分隔
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PolicyRule 表示adhoc命令的策略规则，规则中设置的条件全部满足时匹配
+type PolicyRule struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Name         string    `json:"name" gorm:"not null;uniqueIndex"` // 规则名称
+	Description  string    `json:"description"`                      // 描述，匹配时返回给用户
+	Action       string    `json:"action" gorm:"not null"`           // deny, warn
+	Modules      string    `json:"modules"`                          // 适用的模块，逗号分隔，为空时适用于所有模块
+	ArgsPattern  string    `json:"args_pattern"`                     // 匹配模块参数的正则表达式
+	HostPatterns string    `json:"host_patterns"`                    // 受限的主机模式，逗号分隔，支持通配符；目标主机模式中出现或解析后包含其全部主机时匹配
+	MaxHosts     int       `json:"max_hosts"`                        // 目标主机数超过该值时匹配，0表示不限制
+	Enabled      bool      `json:"enabled" gorm:"default:true"`      // 是否启用
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// PolicyAudit 表示策略规则阻止、警告或被确认越过的记录
+type PolicyAudit struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	UserID         uint       `json:"user_id" gorm:"not null;index"`    // 执行用户ID
+	Action         string     `json:"action" gorm:"not null;index"`     // blocked, warned, overridden
+	Rules          string     `json:"rules"`                            // 匹配的规则名称，逗号分隔
+	RuleIDs        string     `json:"-"`                                // 匹配的规则ID，逗号分隔，确认时比较
+	Reasons        string     `json:"reasons" gorm:"type:text"`         // 匹配原因，每行一条
+	Module         string     `json:"module"`                           // 模块名称
+	Args           string     `json:"args" gorm:"type:text"`            // 模块参数
+	Hosts          string     `json:"hosts"`                            // 目标主机模式
+	Limit          string     `json:"limit"`                            // --limit参数
+	HostCount      int        `json:"host_count"`                       // 目标主机数，-1表示无法确定
+	InventoryID    *uint      `json:"inventory_id,omitempty"`           // 使用的已保存inventory ID
+	InventoryHash  string     `json:"-"`                                // 解析后的inventory的SHA-256，确认时比较
+	TokenHash      string     `json:"-" gorm:"index"`                   // 确认令牌的SHA-256，只有warned记录有
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`             // 确认令牌过期时间
+	ConfirmedAt    *time.Time `json:"confirmed_at,omitempty"`           // 确认令牌使用时间，令牌只能使用一次
+	ConfirmationID *uint      `json:"confirmation_id,omitempty"`        // overridden记录使用的warned记录ID
+	ExecutionID    *uint      `json:"execution_id,omitempty"`           // overridden记录创建的执行ID
+	ScheduleID     *uint      `json:"schedule_id,omitempty"`            // 触发执行的定时任务ID
+	WorkflowRunID  *uint      `json:"workflow_run_id,omitempty"`        // 所属的工作流运行ID
+	CreatedAt      time.Time  `json:"created_at" gorm:"index"`
+}
+
+// JobTemplate 表示作业模板，绑定playbook、inventory和执行选项，启动时通过问卷收集额外变量
+type JobTemplate struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	Name           string    `json:"name" gorm:"not null;uniqueIndex"`     // 模板名称
+	Description    string    `json:"description"`                          // 描述
+	PlaybookID     uint      `json:"playbook_id" gorm:"not null;index"`    // 执行的playbook ID
+	InventoryID    *uint     `json:"inventory_id"`                         // 使用的inventory ID，为空时使用默认inventory
+	ExtraVars      string    `json:"extra_vars" gorm:"type:text"`          // 默认额外变量JSON格式，问卷答案覆盖同名变量
+	Tags           string    `json:"tags"`                                 // 标签
+	SkipTags       string    `json:"skip_tags"`                            // 跳过的标签
+	TimeoutSeconds int       `json:"timeout_seconds"`                      // 超时时间(秒)，0表示使用默认值
+	Check          bool      `json:"check"`                                // 以--check模式试运行
+	Diff           bool      `json:"diff"`                                 // 以--diff模式记录变更内容
+	Priority       int       `json:"priority"`                             // 队列优先级，只对管理员创建的模板生效
+	VaultIDs       string    `json:"vault_ids"`                            // 使用的vault ID名称，逗号分隔
+	SurveySpec     string    `json:"-" gorm:"type:text"`                   // 问卷问题JSON格式，密码类型的默认值已加密
+	Survey         []SurveyQuestion `json:"survey" gorm:"-"`               // 问卷问题，读取时从SurveySpec解析
+	UserID         uint      `json:"user_id" gorm:"not null;index"`        // 创建用户ID
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// SurveyQuestion 表示作业模板问卷中的一个问题，答案作为同名额外变量传给playbook
+type SurveyQuestion struct {
+	Variable    string      `json:"variable"`          // 额外变量名称
+	Label       string      `json:"label"`             // 显示的问题
+	Description string      `json:"description"`       // 说明
+	Type        string      `json:"type"`              // string, integer, choice, multi_choice, password, boolean
+	Required    bool        `json:"required"`          // 是否必须回答，有默认值时使用默认值
+	Default     interface{} `json:"default,omitempty"` // 默认值，密码类型读取时显示为掩码
+	Choices     []string    `json:"choices,omitempty"` // choice和multi_choice类型的选项
+	Min         *int        `json:"min,omitempty"`     // integer类型的最小值，string和password类型的最小长度
+	Max         *int        `json:"max,omitempty"`     // integer类型的最大值，string和password类型的最大长度
+}
+
+// Workflow 表示工作流，由adhoc或playbook节点和按执行结果触发的边组成的有向无环图
+type Workflow struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"not null;uniqueIndex"` // 工作流名称
+	Description string         `json:"description"`                      // 描述
+	Spec        string         `json:"-" gorm:"type:text"`               // 节点和边JSON格式
+	Nodes       []WorkflowNode `json:"nodes" gorm:"-"`                   // 节点，读取时从Spec解析
+	Edges       []WorkflowEdge `json:"edges" gorm:"-"`                   // 边，读取时从Spec解析
+	UserID      uint           `json:"user_id" gorm:"not null;index"`    // 创建用户ID
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// WorkflowNode 表示工作流中的一次adhoc或playbook执行
+type WorkflowNode struct {
+	ID          string                 `json:"id"`                     // 节点标识，在工作流内唯一，边通过该标识引用节点
+	Name        string                 `json:"name"`                   // 显示名称
+	Type        string                 `json:"type"`                   // adhoc, playbook
+	PlaybookID  uint                   `json:"playbook_id,omitempty"`  // playbook类型执行的playbook ID
+	Module      string                 `json:"module,omitempty"`       // adhoc类型的模块名称
+	Args        string                 `json:"args,omitempty"`         // adhoc类型的模块参数
+	Hosts       string                 `json:"hosts,omitempty"`        // adhoc类型的目标主机或组
+	InventoryID uint                   `json:"inventory_id,omitempty"` // 使用的inventory ID，为空时使用默认inventory
+	ExtraVars   map[string]interface{} `json:"extra_vars,omitempty"`   // 节点的额外变量，上游节点的产物覆盖同名变量
+	Tags        string                 `json:"tags,omitempty"`         // playbook类型的标签
+	SkipTags    string                 `json:"skip_tags,omitempty"`    // playbook类型跳过的标签
+}
+
+// WorkflowEdge 表示工作流中两个节点之间的依赖
+type WorkflowEdge struct {
+	From      string `json:"from"`      // 上游节点标识
+	To        string `json:"to"`        // 下游节点标识
+	Condition string `json:"condition"` // success, failure, always
+}
+
+// WorkflowSpec 工作流的节点和边，保存在Workflow.Spec和WorkflowRun.Spec中
+type WorkflowSpec struct {
+	Nodes []WorkflowNode `json:"nodes"`
+	Edges []WorkflowEdge `json:"edges"`
+}
+
+// WorkflowRun 表示一次工作流运行，每个节点的执行作为子执行记录
+type WorkflowRun struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	WorkflowID  uint       `json:"workflow_id" gorm:"not null;index"`  // 工作流ID
+	Name        string     `json:"name"`                               // 启动时的工作流名称
+	Status      string     `json:"status" gorm:"default:'running';index"` // running, success, failed, cancelled
+	Spec        string     `json:"-" gorm:"type:text"`                 // 启动时的节点和边快照，运行期间修改工作流不影响本次运行
+	Edges       []WorkflowEdge `json:"edges" gorm:"-"`                 // 边，读取时从Spec解析
+	ExtraVars   string     `json:"extra_vars" gorm:"type:text"`        // 启动时传入的额外变量JSON格式，优先于节点变量和产物
+	Error       string     `json:"error" gorm:"type:text"`             // 运行失败的原因
+	StartTime   *time.Time `json:"start_time"`                         // 开始时间
+	EndTime     *time.Time `json:"end_time"`                           // 结束时间
+	UserID      uint       `json:"user_id" gorm:"not null;index"`      // 启动用户ID，节点以该用户身份执行
+	CancelledBy *uint      `json:"cancelled_by"`                       // 取消运行的用户ID
+	CancelledAt *time.Time `json:"cancelled_at"`                       // 取消时间
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	
+	Nodes []WorkflowRunNode `json:"nodes,omitempty" gorm:"foreignKey:RunID"` // 每个节点的状态
+}
+
+// WorkflowRunNode 表示工作流运行中一个节点的状态
+type WorkflowRunNode struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	RunID         uint       `json:"run_id" gorm:"not null;index"`  // 工作流运行ID
+	NodeID        string     `json:"node_id" gorm:"not null"`       // 节点标识
+	Name          string     `json:"name"`                          // 节点名称
+	ExecutionType string     `json:"execution_type"`                // adhoc, playbook
+	ExecutionID   *uint      `json:"execution_id"`                  // 子执行记录ID，未执行时为空
+	Status        string     `json:"status" gorm:"default:'pending'"` // pending, running, success, failed, cancelled, skipped
+	Artifacts     string     `json:"artifacts" gorm:"type:text"`    // 节点执行产生的产物JSON格式
+	Error         string     `json:"error" gorm:"type:text"`        // 节点无法启动的原因
+	StartTime     *time.Time `json:"start_time"`                    // 开始时间
+	EndTime       *time.Time `json:"end_time"`                      // 结束时间
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// DynamicInventoryFilter 动态inventory的过滤条件，为空时包含全部服务器
+type DynamicInventoryFilter struct {
+	Groups []string `json:"groups"` // 服务器组名称
+	Tags   []string `json:"tags"`   // 服务器标签
+}
+
+// VaultSecret 表示一个命名的vault密码，密码加密保存，执行时以--vault-id <name>@<file>传给ansible
+type VaultSecret struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	Name              string    `json:"name" gorm:"not null;uniqueIndex"` // vault ID名称
+	Description       string    `json:"description"`                      // 描述
+	EncryptedPassword string    `json:"-" gorm:"type:text;not null"`      // 加密后的vault密码，不返回给客户端
+	UserID            uint      `json:"user_id" gorm:"not null"`          // 创建用户ID
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
 // AdhocExecutionRequest 表示adhoc命令执行请求
 type AdhocExecutionRequest struct {
 	Module    string            `json:"module" binding:"required"`              // ansible模块名称
 	Args      string            `json:"args"`                                   // 模块参数
 	Hosts     string            `json:"hosts" binding:"required"`               // 目标主机或组
-	Inventory string            `json:"inventory"`                              // inventory内容或ID
+	Limit     string            `json:"limit"`                                  // 限制执行的主机，传给--limit
+	InventoryID   uint          `json:"inventory_id"`                           // 已保存的inventory ID
+	InventoryName string        `json:"inventory_name"`                         // 已保存的inventory名称
+	Inventory string            `json:"inventory"`                              // inventory内容或ID，都未指定时使用默认inventory
+	DynamicInventory *DynamicInventoryFilter `json:"dynamic_inventory"`         // 使用受管服务器生成inventory，优先于inventory
 	ExtraVars map[string]interface{} `json:"extra_vars"`                       // 额外变量
+	TimeoutSeconds int          `json:"timeout_seconds" binding:"min=0"`        // 超时时间(秒)，受最大超时时间限制
+	Check     bool              `json:"check"`                                  // 以--check模式试运行
+	Diff      bool              `json:"diff"`                                   // 以--diff模式记录变更内容
+	Priority  int               `json:"priority" binding:"min=0,max=100"`       // 队列优先级，数值越大越先执行，只对管理员生效
+	VaultIDs  []string          `json:"vault_ids"`                              // 使用的vault密码名称
+	Confirm   string            `json:"confirm"`                                // 匹配警告规则时返回的确认令牌
+	ScheduleID *uint            `json:"-"`                                      // 触发执行的定时任务ID，仅由调度器设置
+	WorkflowRunID *uint         `json:"-"`                                      // 所属的工作流运行ID，仅由工作流设置
+	RelaunchOfID *uint          `json:"-"`                                      // 重新执行的原执行ID，仅由重新执行设置
+	RelaunchMode string         `json:"-"`                                      // 重新执行的主机范围，仅由重新执行设置
+	
+	snapshot *resolvedInventory // 重新执行时使用原执行的inventory，不再解析请求中的inventory
 }
 
 // PlaybookExecutionRequest 表示playbook执行请求
 type PlaybookExecutionRequest struct {
-	PlaybookID uint              `json:"playbook_id" binding:"required"`        // playbook ID
-	Inventory  string            `json:"inventory"`                              // inventory内容或ID
+	PlaybookID uint              `json:"playbook_id"`                            // playbook ID (由路由参数提供)
+	InventoryID   uint           `json:"inventory_id"`                           // 已保存的inventory ID
+	InventoryName string         `json:"inventory_name"`                         // 已保存的inventory名称
+	Inventory  string            `json:"inventory"`                              // inventory内容或ID，都未指定时使用默认inventory
+	DynamicInventory *DynamicInventoryFilter `json:"dynamic_inventory"`         // 使用受管服务器生成inventory，优先于inventory
 	ExtraVars  map[string]interface{} `json:"extra_vars"`                       // 额外变量
+	Revision   int               `json:"revision" binding:"min=0"`               // 执行的playbook版本号，0表示当前版本
+	ProjectID  uint              `json:"project_id"`                             // 项目ID (由路由参数提供，执行单文件playbook时忽略)
+	Playbook   string            `json:"playbook"`                               // 项目中的入口playbook路径，为空时使用项目的默认入口
+	CommitSHA  string            `json:"commit_sha"`                             // git项目执行的提交，为空时使用最近一次同步的提交
 	Tags       string            `json:"tags"`                                   // 标签
 	SkipTags   string            `json:"skip_tags"`                              // 跳过的标签
+	Limit      string            `json:"limit"`                                  // 限制执行的主机，传给--limit
+	TimeoutSeconds int           `json:"timeout_seconds" binding:"min=0"`        // 超时时间(秒)，受最大超时时间限制
+	Check      bool              `json:"check"`                                  // 以--check模式试运行
+	Diff       bool              `json:"diff"`                                   // 以--diff模式记录变更内容
+	Priority   int               `json:"priority" binding:"min=0,max=100"`       // 队列优先级，数值越大越先执行，只对管理员生效
+	VaultIDs   []string          `json:"vault_ids"`                              // 使用的vault密码名称
+	ScheduleID *uint             `json:"-"`                                      // 触发执行的定时任务ID，仅由调度器设置
+	JobTemplateID *uint          `json:"-"`                                      // 启动执行的作业模板ID，仅由作业模板设置
+	WorkflowRunID *uint          `json:"-"`                                      // 所属的工作流运行ID，仅由工作流设置
+	SurveyAnswers map[string]interface{} `json:"-"`                              // 已掩码的问卷答案，仅由作业模板设置
+	SecretVars []string          `json:"-"`                                      // 需要加密保存的额外变量名称，仅由作业模板设置
+	SealedVars map[string]string `json:"sealed_vars,omitempty"`                  // 入队时加密的额外变量，执行时解密后合并到extra_vars
+	RelaunchOfID *uint           `json:"-"`                                      // 重新执行的原执行ID，仅由重新执行设置
+	RelaunchMode string          `json:"-"`                                      // 重新执行的主机范围，仅由重新执行设置
+	
+	snapshot *resolvedInventory // 重新执行时使用原执行的inventory，不再解析请求中的inventory
 }
 
 // InventoryRequest 表示inventory创建/更新请求
@@ -95,10 +650,16 @@ type InventoryRequest struct {
 	Name        string `json:"name" binding:"required"`
 	Description string `json:"description"`
 	Type        string `json:"type"`
-	Content     string `json:"content" binding:"required"`
+	Content     string `json:"content"` // static类型必填，dynamic类型为JSON格式的过滤条件
 	IsDefault   bool   `json:"is_default"`
 }
 
+// InventoryVarsRequest 表示group_vars/host_vars保存请求
+type InventoryVarsRequest struct {
+	Format  string `json:"format" binding:"omitempty,oneof=yaml json"` // 为空时使用yaml
+	Content string `json:"content"`
+}
+
 // PlaybookRequest 表示playbook创建/更新请求
 type PlaybookRequest struct {
 	Name        string `json:"name" binding:"required"`
@@ -106,12 +667,242 @@ type PlaybookRequest struct {
 	FileName    string `json:"file_name" binding:"required"`
 	Content     string `json:"content" binding:"required"`
 	Tags        string `json:"tags"`
+	Message     string `json:"message"` // 本次保存的变更说明
 }
 
-// ExecutionStats 表示执行统计信息
-type ExecutionStats struct {
+// ProjectRequest 表示项目创建/更新请求
+type ProjectRequest struct {
+	Name            string `json:"name" binding:"required"`
+	Description     string `json:"description"`
+	DefaultPlaybook string `json:"default_playbook"`
+	SourceType      string `json:"source_type" binding:"omitempty,oneof=files git"` // 为空时创建为files，更新时保持不变
+	GitURL          string `json:"git_url"`
+	GitRef          string `json:"git_ref"`
+	DeployKey       string `json:"deploy_key"`                   // 为空时保留原有密钥
+	SyncInterval    int    `json:"sync_interval" binding:"min=0"` // 自动同步间隔(分钟)
+}
+
+// ProjectFileRequest 表示项目文件写入请求
+type ProjectFileRequest struct {
+	Content string `json:"content"`
+}
+
+// PlaybookValidateRequest 表示playbook校验请求
+type PlaybookValidateRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// PlaybookRestoreRequest 表示恢复playbook历史版本的请求
+type PlaybookRestoreRequest struct {
+	Message string `json:"message"` // 变更说明，为空时自动生成
+}
+
+// PlaybookRevisionDiff 表示两个playbook版本之间的差异
+type PlaybookRevisionDiff struct {
+	PlaybookID uint   `json:"playbook_id"`
+	From       int    `json:"from"`
+	To         int    `json:"to"`
+	FromHash   string `json:"from_hash"`
+	ToHash     string `json:"to_hash"`
+	Diff       string `json:"diff"` // unified diff格式，内容相同时为空
+}
+
+// ScheduleRequest 表示定时任务创建/更新请求
+type ScheduleRequest struct {
+	Name           string                 `json:"name" binding:"required"`
+	Description    string                 `json:"description"`
+	CronExpression string                 `json:"cron_expression" binding:"required"`
+	Timezone       string                 `json:"timezone"`                                   // 为空时使用UTC
+	Enabled        *bool                  `json:"enabled"`                                    // 为空时启用
+	Type           string                 `json:"type" binding:"required,oneof=adhoc playbook"`
+	PlaybookID     uint                   `json:"playbook_id"`                                // playbook类型必填
+	Module         string                 `json:"module"`                                     // adhoc类型必填
+	Args           string                 `json:"args"`
+	Hosts          string                 `json:"hosts"`                                      // adhoc类型必填
+	InventoryID    uint                   `json:"inventory_id"`
+	ExtraVars      map[string]interface{} `json:"extra_vars"`
+	CatchUpPolicy  string                 `json:"catch_up_policy" binding:"omitempty,oneof=skip once all"` // 为空时使用skip
+}
+
+// JobTemplateRequest 表示作业模板创建/更新请求
+type JobTemplateRequest struct {
+	Name           string                 `json:"name" binding:"required"`
+	Description    string                 `json:"description"`
+	PlaybookID     uint                   `json:"playbook_id" binding:"required"`
+	InventoryID    uint                   `json:"inventory_id"`
+	ExtraVars      map[string]interface{} `json:"extra_vars"`
+	Tags           string                 `json:"tags"`
+	SkipTags       string                 `json:"skip_tags"`
+	TimeoutSeconds int                    `json:"timeout_seconds" binding:"min=0"`
+	Check          bool                   `json:"check"`
+	Diff           bool                   `json:"diff"`
+	Priority       int                    `json:"priority" binding:"min=0,max=100"`
+	VaultIDs       []string               `json:"vault_ids"`
+	Survey         []SurveyQuestion       `json:"survey"` // 密码类型的默认值为掩码时保持原值
+}
+
+// JobTemplateLaunchRequest 表示作业模板启动请求
+type JobTemplateLaunchRequest struct {
+	Answers map[string]interface{} `json:"answers"` // 问卷答案，键为变量名称
+}
+
+// AnsibleModuleRequest 表示模块创建/更新请求
+type AnsibleModuleRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Category    string `json:"category" binding:"required"`
+	Description string `json:"description"`
+	Enabled     *bool  `json:"enabled"` // 为空时启用
+}
+
+// ModuleGrantRequest 表示设置角色或用户模块授权的请求
+type ModuleGrantRequest struct {
+	SubjectType string   `json:"subject_type" binding:"required,oneof=role user"`
+	Subject     string   `json:"subject" binding:"required"` // 角色名称或用户ID
+	Modules     []string `json:"modules"`
+	Categories  []string `json:"categories"`
+}
+
+// ExecutionRelaunchRequest 表示重新执行请求
+type ExecutionRelaunchRequest struct {
+	Mode    string `json:"mode" binding:"omitempty,oneof=all failed_hosts"` // 为空时为all
+	Confirm string `json:"confirm"`                                         // adhoc命令匹配警告规则时返回的确认令牌
+}
+
+// ApprovalDecisionRequest 表示批准或拒绝执行的请求
+type ApprovalDecisionRequest struct {
+	Comment string `json:"comment" binding:"required"` // 审批意见
+}
+
+// ProtectedTargetRequest 表示设置服务器组、inventory、playbook或项目是否受保护的请求
+type ProtectedTargetRequest struct {
+	Type      string `json:"type" binding:"required,oneof=server_group inventory playbook project"`
+	ID        uint   `json:"id" binding:"required"`
+	Protected bool   `json:"protected"`
+}
+
+// ProtectedTarget 表示一个受保护对象
+type ProtectedTarget struct {
+	Type string `json:"type"` // server_group, inventory, playbook, project
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// PolicyRuleRequest 表示策略规则创建/更新请求
+type PolicyRuleRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	Description  string   `json:"description"`
+	Action       string   `json:"action" binding:"required,oneof=deny warn"`
+	Modules      []string `json:"modules"`       // 为空时适用于所有模块
+	ArgsPattern  string   `json:"args_pattern"`  // 匹配模块参数的正则表达式
+	HostPatterns []string `json:"host_patterns"` // 禁止的主机模式
+	MaxHosts     int      `json:"max_hosts" binding:"min=0"`
+	Enabled      *bool    `json:"enabled"` // 为空时启用
+}
+
+// WorkflowRequest 表示工作流创建/更新请求
+type WorkflowRequest struct {
+	Name        string         `json:"name" binding:"required"`
+	Description string         `json:"description"`
+	Nodes       []WorkflowNode `json:"nodes" binding:"required"`
+	Edges       []WorkflowEdge `json:"edges"`
+}
+
+// WorkflowLaunchRequest 表示工作流启动请求
+type WorkflowLaunchRequest struct {
+	ExtraVars map[string]interface{} `json:"extra_vars"` // 传给所有节点的额外变量
+}
+
+// ExecutionFilter 表示执行历史的查询条件，零值表示不过滤
+type ExecutionFilter struct {
+	Type        string     // adhoc, playbook
+	Statuses    []string   // 执行状态
+	Module      string     // adhoc模块名称
+	PlaybookID  uint       // playbook ID
+	ProjectID   uint       // 项目ID
+	InventoryID uint       // inventory ID
+	Host        string     // 主机名，支持*和?通配符
+	UserID      uint       // 执行用户ID
+	From        *time.Time // 创建时间下限(包含)
+	To          *time.Time // 创建时间上限(不包含)
+	Search      string     // 在命令、参数、playbook名称和路径中搜索
+	Sort        string     // 排序字段
+	Desc        bool       // 是否降序
+}
+
+// ExecutionSummary 表示执行历史中的一条adhoc或playbook执行
+type ExecutionSummary struct {
+	Type          string     `json:"type"`           // adhoc, playbook
+	ID            uint       `json:"id"`
+	Name          string     `json:"name"`           // adhoc为执行的命令，playbook为playbook或项目名称
+	Module        string     `json:"module"`         // adhoc模块名称
+	Args          string     `json:"args"`           // adhoc模块参数
+	PlaybookID    uint       `json:"playbook_id"`
+	ProjectID     *uint      `json:"project_id"`
+	PlaybookPath  string     `json:"playbook_path"`
+	InventoryID   *uint      `json:"inventory_id"`
+	Hosts         string     `json:"hosts"`          // adhoc目标主机或组
+	Limit         string     `json:"limit"`
+	Tags          string     `json:"tags"`
+	Status        string     `json:"status"`
+	ExitCode      int        `json:"exit_code"`
+	DryRun        bool       `json:"dry_run"`
+	StartTime     *time.Time `json:"start_time"`
+	EndTime       *time.Time `json:"end_time"`
+	Duration      int        `json:"duration"`
+	ScheduleID    *uint      `json:"schedule_id"`
+	WorkflowRunID *uint      `json:"workflow_run_id"`
+	RelaunchOfID  *uint      `json:"relaunch_of_id"`
+	UserID        uint       `json:"user_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+
+	ApprovalStatus    string     `json:"approval_status,omitempty"`
+	ProtectedTargets  string     `json:"protected_targets,omitempty"`
+	ApprovalExpiresAt *time.Time `json:"approval_expires_at,omitempty"`
+}
+
+// ExecutionCounts 表示一类执行的统计数量
+type ExecutionCounts struct {
 	TotalExecutions     int64 `json:"total_executions"`
 	SuccessfulExecutions int64 `json:"successful_executions"`
 	FailedExecutions    int64 `json:"failed_executions"`
 	RunningExecutions   int64 `json:"running_executions"`
-}
\ No newline at end of file
+	DryRunExecutions    int64 `json:"dry_run_executions"` // 试运行次数，不计入成功次数
+}
+
+// ExecutionStats 表示执行统计信息，总数包含adhoc和playbook执行
+type ExecutionStats struct {
+	ExecutionCounts
+	ByType map[string]ExecutionCounts `json:"by_type"` // 按执行类型(adhoc, playbook)分别统计
+}
+
+// VaultSecretRequest 表示vault密码创建/更新请求
+type VaultSecretRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Password    string `json:"password"` // 创建时必填，更新时为空表示保持不变
+}
+
+// VaultEncryptRequest 表示使用vault密码加密字符串的请求
+type VaultEncryptRequest struct {
+	VaultID   string `json:"vault_id" binding:"required"` // vault密码名称
+	Plaintext string `json:"plaintext"`
+	Name      string `json:"name"` // 变量名，设置时YAML输出为name: !vault |
+}
+
+// VaultDecryptRequest 表示解密vault内容的请求
+type VaultDecryptRequest struct {
+	VaultID    string `json:"vault_id"` // vault密码名称，为空时按密文中的vault ID查找或依次尝试所有vault密码
+	Ciphertext string `json:"ciphertext" binding:"required"`
+}
+
+// VaultEncryptResult 表示字符串加密结果
+type VaultEncryptResult struct {
+	Ciphertext string `json:"ciphertext"` // $ANSIBLE_VAULT格式的密文
+	YAML       string `json:"yaml"`       // 与ansible-vault encrypt_string相同的YAML片段
+}
+
+// VaultDecryptResult 表示解密结果
+type VaultDecryptResult struct {
+	VaultID   string `json:"vault_id"` // 解密成功使用的vault密码名称
+	Plaintext string `json:"plaintext"`
+}
@@ -0,0 +1,370 @@
+package ansible
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"server-manager/internal/user"
+)
+
+var (
+	ErrInvalidModule    = errors.New("invalid module")
+	ErrModuleNotAllowed = errors.New("module not allowed")
+)
+
+// adminRole 管理员角色可以使用所有启用的模块
+const adminRole = "admin"
+
+// moduleNamePattern 模块名称，可以是集合的完全限定名称，以.*结尾时表示集合下的所有模块
+var moduleNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+(\.[A-Za-z0-9_]+)*(\.\*)?$`)
+
+// moduleCategoryPattern 模块分类名称
+var moduleCategoryPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// builtinModulePrefixes 内置模块的集合前缀，带前缀的名称与短名称等同
+var builtinModulePrefixes = []string{"ansible.builtin.", "ansible.legacy."}
+
+// defaultModules 模块表为空时写入的默认模块
+var defaultModules = []AnsibleModule{
+	{Name: "shell", Category: "commands", Description: "Execute shell commands"},
+	{Name: "command", Category: "commands", Description: "Execute commands without shell"},
+	{Name: "copy", Category: "files", Description: "Copy files to remote locations"},
+	{Name: "file", Category: "files", Description: "Manage files and file properties"},
+	{Name: "template", Category: "files", Description: "Process Jinja2 templates"},
+	{Name: "lineinfile", Category: "files", Description: "Manage lines in text files"},
+	{Name: "replace", Category: "files", Description: "Replace text in files"},
+	{Name: "service", Category: "system", Description: "Manage services"},
+	{Name: "user", Category: "system", Description: "Manage user accounts"},
+	{Name: "group", Category: "system", Description: "Manage groups"},
+	{Name: "cron", Category: "system", Description: "Manage cron entries"},
+	{Name: "mount", Category: "system", Description: "Manage mounted filesystems"},
+	{Name: "package", Category: "packages", Description: "Manage packages"},
+	{Name: "yum", Category: "packages", Description: "Manage packages with yum"},
+	{Name: "apt", Category: "packages", Description: "Manage packages with apt"},
+	{Name: "git", Category: "source", Description: "Deploy software from git repositories"},
+	{Name: "ping", Category: "diagnostics", Description: "Test connection to hosts"},
+	{Name: "setup", Category: "diagnostics", Description: "Gather facts about remote hosts"},
+	{Name: "debug", Category: "diagnostics", Description: "Print statements during execution"},
+}
+
+// EnsureDefaultModules 模块表为空时写入默认模块；没有任何授权时将默认模块的全部分类授予user角色，
+// 保持升级前所有用户可用的模块不变
+func (s *AnsibleService) EnsureDefaultModules() error {
+	var count int64
+	if err := s.db.Model(&AnsibleModule{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		modules := make([]AnsibleModule, len(defaultModules))
+		copy(modules, defaultModules)
+		var categories []string
+		for i := range modules {
+			modules[i].Enabled = true
+			if !containsString(categories, modules[i].Category) {
+				categories = append(categories, modules[i].Category)
+			}
+		}
+		if err := tx.Create(&modules).Error; err != nil {
+			return fmt.Errorf("create default modules failed: %v", err)
+		}
+	
+		var grants int64
+		if err := tx.Model(&ModuleGrant{}).Count(&grants).Error; err != nil {
+			return err
+		}
+		if grants > 0 {
+			return nil
+		}
+		grant := &ModuleGrant{SubjectType: ModuleGrantRole, Subject: "user", Categories: strings.Join(categories, ",")}
+		if err := tx.Create(grant).Error; err != nil {
+			return fmt.Errorf("create default module grant failed: %v", err)
+		}
+		return nil
+	})
+}
+
+// validateModuleName 校验模块名称的格式
+func validateModuleName(name string) error {
+	if !moduleNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: invalid module name %q", ErrInvalidModule, name)
+	}
+	return nil
+}
+
+// normalizeModuleName 去掉内置模块的集合前缀
+func normalizeModuleName(name string) string {
+	for _, prefix := range builtinModulePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return strings.TrimPrefix(name, prefix)
+		}
+	}
+	return name
+}
+
+// moduleMatches 判断模块是否匹配允许列表中的条目，条目以.*结尾时按集合前缀匹配
+func moduleMatches(entry, module string) bool {
+	entry = normalizeModuleName(entry)
+	module = normalizeModuleName(module)
+	if strings.HasSuffix(entry, ".*") {
+		return strings.HasPrefix(module, strings.TrimSuffix(entry, "*"))
+	}
+	return entry == module
+}
+
+// ListModules 列出所有模块
+func (s *AnsibleService) ListModules() ([]AnsibleModule, error) {
+	modules := []AnsibleModule{}
+	if err := s.db.Order("category ASC, name ASC").Find(&modules).Error; err != nil {
+		return nil, err
+	}
+	return modules, nil
+}
+
+// CreateModule 添加模块
+func (s *AnsibleService) CreateModule(req *AnsibleModuleRequest) (*AnsibleModule, error) {
+	module := &AnsibleModule{}
+	if err := applyModuleRequest(module, req); err != nil {
+		return nil, err
+	}
+	
+	if err := s.db.Create(module).Error; err != nil {
+		return nil, err
+	}
+	return module, nil
+}
+
+// UpdateModule 更新模块
+func (s *AnsibleService) UpdateModule(id uint, req *AnsibleModuleRequest) (*AnsibleModule, error) {
+	var module AnsibleModule
+	if err := s.db.First(&module, id).Error; err != nil {
+		return nil, err
+	}
+	
+	if err := applyModuleRequest(&module, req); err != nil {
+		return nil, err
+	}
+	
+	if err := s.db.Save(&module).Error; err != nil {
+		return nil, err
+	}
+	return &module, nil
+}
+
+// applyModuleRequest 校验请求并写入模块字段
+func applyModuleRequest(module *AnsibleModule, req *AnsibleModuleRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if err := validateModuleName(name); err != nil {
+		return err
+	}
+	category := strings.TrimSpace(req.Category)
+	if !moduleCategoryPattern.MatchString(category) {
+		return fmt.Errorf("%w: invalid category %q", ErrInvalidModule, category)
+	}
+	
+	module.Name = normalizeModuleName(name)
+	module.Category = category
+	module.Description = req.Description
+	module.Enabled = req.Enabled == nil || *req.Enabled
+	return nil
+}
+
+// DeleteModule 删除模块，授权中的模块名称保留但不再生效
+func (s *AnsibleService) DeleteModule(id uint) error {
+	result := s.db.Delete(&AnsibleModule{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	
+	return nil
+}
+
+// ListModuleGrants 列出所有模块授权
+func (s *AnsibleService) ListModuleGrants() ([]ModuleGrant, error) {
+	grants := []ModuleGrant{}
+	if err := s.db.Order("subject_type ASC, subject ASC").Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// SaveModuleGrant 创建或替换角色或用户的模块授权，模块和分类必须已存在
+func (s *AnsibleService) SaveModuleGrant(req *ModuleGrantRequest) (*ModuleGrant, error) {
+	subject := strings.TrimSpace(req.Subject)
+	switch req.SubjectType {
+	case ModuleGrantRole:
+		if !moduleCategoryPattern.MatchString(subject) {
+			return nil, fmt.Errorf("%w: invalid role %q", ErrInvalidModule, subject)
+		}
+	case ModuleGrantUser:
+		if _, err := strconv.ParseUint(subject, 10, 32); err != nil {
+			return nil, fmt.Errorf("%w: invalid user ID %q", ErrInvalidModule, subject)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported subject type %q", ErrInvalidModule, req.SubjectType)
+	}
+	
+	modules, err := s.ListModules()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	categories := make(map[string]bool)
+	for _, module := range modules {
+		names[module.Name] = true
+		categories[module.Category] = true
+	}
+	
+	var grantedModules, grantedCategories []string
+	for _, name := range req.Modules {
+		name = normalizeModuleName(strings.TrimSpace(name))
+		if !names[name] {
+			return nil, fmt.Errorf("%w: module %q is not in the allowlist", ErrInvalidModule, name)
+		}
+		if !containsString(grantedModules, name) {
+			grantedModules = append(grantedModules, name)
+		}
+	}
+	for _, category := range req.Categories {
+		category = strings.TrimSpace(category)
+		if !categories[category] {
+			return nil, fmt.Errorf("%w: category %q has no modules", ErrInvalidModule, category)
+		}
+		if !containsString(grantedCategories, category) {
+			grantedCategories = append(grantedCategories, category)
+		}
+	}
+	
+	var grant ModuleGrant
+	err = s.db.Where("subject_type = ? AND subject = ?", req.SubjectType, subject).First(&grant).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	grant.SubjectType = req.SubjectType
+	grant.Subject = subject
+	grant.Modules = strings.Join(grantedModules, ",")
+	grant.Categories = strings.Join(grantedCategories, ",")
+	
+	if err := s.db.Save(&grant).Error; err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// DeleteModuleGrant 删除模块授权
+func (s *AnsibleService) DeleteModuleGrant(id uint) error {
+	result := s.db.Delete(&ModuleGrant{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	
+	return nil
+}
+
+// AllowedModules 返回用户可以使用的启用模块：管理员可以使用全部，
+// 其他用户为其角色和本人被授予的模块及分类下模块的并集
+func (s *AnsibleService) AllowedModules(userID uint) ([]AnsibleModule, error) {
+	var modules []AnsibleModule
+	if err := s.db.Where("enabled = ?", true).Order("category ASC, name ASC").Find(&modules).Error; err != nil {
+		return nil, err
+	}
+	
+	role, err := s.userRole(userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == adminRole {
+		return modules, nil
+	}
+	
+	var grants []ModuleGrant
+	err = s.db.Where("(subject_type = ? AND subject = ?) OR (subject_type = ? AND subject = ?)",
+		ModuleGrantRole, role, ModuleGrantUser, strconv.FormatUint(uint64(userID), 10)).Find(&grants).Error
+	if err != nil {
+		return nil, fmt.Errorf("load module grants failed: %v", err)
+	}
+	
+	names := make(map[string]bool)
+	categories := make(map[string]bool)
+	for _, grant := range grants {
+		for _, name := range splitList(grant.Modules) {
+			names[name] = true
+		}
+		for _, category := range splitList(grant.Categories) {
+			categories[category] = true
+		}
+	}
+	
+	allowed := []AnsibleModule{}
+	for _, module := range modules {
+		if names[module.Name] || categories[module.Category] {
+			allowed = append(allowed, module)
+		}
+	}
+	return allowed, nil
+}
+
+// userRole 从用户表读取用户的当前角色，令牌中的角色可能已过期；用户不存在时返回空
+func (s *AnsibleService) userRole(userID uint) (string, error) {
+	var account user.User
+	err := s.db.Select("role").Where("id = ?", userID).First(&account).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", fmt.Errorf("get user role failed: %v", err)
+	}
+	return account.Role, nil
+}
+
+// checkOwnerOrAdmin 检查用户是记录的所有者或管理员，否则按记录不存在处理
+func (s *AnsibleService) checkOwnerOrAdmin(ownerID uint, userID uint) error {
+	if ownerID == userID {
+		return nil
+	}
+	role, err := s.userRole(userID)
+	if err != nil {
+		return err
+	}
+	if role != adminRole {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// checkModuleAllowed 检查用户是否可以使用模块
+func (s *AnsibleService) checkModuleAllowed(userID uint, module string) error {
+	allowed, err := s.AllowedModules(userID)
+	if err != nil {
+		return err
+	}
+	for _, entry := range allowed {
+		if moduleMatches(entry.Name, module) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrModuleNotAllowed, module)
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,188 @@
+package ansible
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrSyntaxCheckUnavailable 未安装ansible-playbook，无法执行--syntax-check
+var ErrSyntaxCheckUnavailable = errors.New("ansible-playbook syntax check unavailable")
+
+var (
+	// syntaxErrorLocation 匹配ansible错误信息中的位置
+	syntaxErrorLocation = regexp.MustCompile(`line (\d+), column (\d+)`)
+	// playbookImportKeys 导入其他playbook的play不需要hosts
+	playbookImportKeys = []string{"import_playbook", "ansible.builtin.import_playbook", "include"}
+	// playbookTaskListKeys play中必须为任务列表的字段
+	playbookTaskListKeys = []string{"pre_tasks", "roles", "tasks", "post_tasks", "handlers"}
+)
+
+// PlaybookError 表示playbook内容中的一处错误，格式与inventory错误相同
+type PlaybookError = InventoryError
+
+// PlaybookValidationError playbook校验失败，包含全部错误及行号
+type PlaybookValidationError struct {
+	Errors []PlaybookError
+}
+
+// Error 实现error接口
+func (e *PlaybookValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, item := range e.Errors {
+		messages[i] = item.String()
+	}
+	return "invalid playbook: " + strings.Join(messages, "; ")
+}
+
+// Unwrap 使errors.Is(err, ErrInvalidPlaybook)成立
+func (e *PlaybookValidationError) Unwrap() error {
+	return ErrInvalidPlaybook
+}
+
+// PlaybookValidationResult playbook校验结果
+type PlaybookValidationResult struct {
+	Valid         bool            `json:"valid"`
+	Errors        []PlaybookError `json:"errors"`
+	SyntaxChecked bool            `json:"syntax_checked"` // 是否执行了ansible-playbook --syntax-check
+}
+
+// ValidatePlaybookContent 检查playbook是否为play列表，且每个play都指定了hosts
+func ValidatePlaybookContent(content string) []PlaybookError {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return yamlErrors(err)
+	}
+	if len(doc.Content) == 0 {
+		return []PlaybookError{{Line: 1, Message: "playbook is empty"}}
+	}
+	
+	root := doc.Content[0]
+	if root.Kind != yaml.SequenceNode {
+		return []PlaybookError{{Line: root.Line, Column: root.Column, Message: "playbook must be a list of plays"}}
+	}
+	if len(root.Content) == 0 {
+		return []PlaybookError{{Line: root.Line, Column: root.Column, Message: "playbook contains no plays"}}
+	}
+	
+	var errs []PlaybookError
+	for i, play := range root.Content {
+		errs = append(errs, validatePlay(i+1, play)...)
+	}
+	return errs
+}
+
+// validatePlay 检查单个play的结构
+func validatePlay(index int, play *yaml.Node) []PlaybookError {
+	if play.Kind != yaml.MappingNode {
+		return []PlaybookError{{Line: play.Line, Column: play.Column, Message: fmt.Sprintf("play #%d must be a mapping", index)}}
+	}
+	
+	fields := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(play.Content); i += 2 {
+		fields[play.Content[i].Value] = play.Content[i+1]
+	}
+	
+	for _, key := range playbookImportKeys {
+		if _, ok := fields[key]; ok {
+			return nil
+		}
+	}
+	
+	var errs []PlaybookError
+	hosts, ok := fields["hosts"]
+	switch {
+	case !ok:
+		errs = append(errs, PlaybookError{Line: play.Line, Column: play.Column, Message: fmt.Sprintf("play #%d is missing required key: hosts", index)})
+	case hosts.Kind == yaml.ScalarNode && strings.TrimSpace(hosts.Value) == "":
+		errs = append(errs, PlaybookError{Line: hosts.Line, Column: hosts.Column, Message: fmt.Sprintf("hosts of play #%d is empty", index)})
+	case hosts.Kind == yaml.MappingNode:
+		errs = append(errs, PlaybookError{Line: hosts.Line, Column: hosts.Column, Message: fmt.Sprintf("hosts of play #%d must be a string or a list", index)})
+	}
+	
+	for _, key := range playbookTaskListKeys {
+		node, ok := fields[key]
+		if !ok || node.Tag == "!!null" {
+			continue
+		}
+		if node.Kind != yaml.SequenceNode {
+			errs = append(errs, PlaybookError{Line: node.Line, Column: node.Column, Message: fmt.Sprintf("%s of play #%d must be a list", key, index)})
+			continue
+		}
+		if key == "roles" {
+			continue
+		}
+		for _, task := range node.Content {
+			if task.Kind != yaml.MappingNode {
+				errs = append(errs, PlaybookError{Line: task.Line, Column: task.Column, Message: fmt.Sprintf("each entry in %s of play #%d must be a mapping", key, index)})
+			}
+		}
+	}
+	
+	return errs
+}
+
+// ValidatePlaybook 校验playbook内容，ansible可用时同时执行ansible-playbook --syntax-check
+func (s *AnsibleService) ValidatePlaybook(ctx context.Context, content string) (*PlaybookValidationResult, error) {
+	result := &PlaybookValidationResult{Errors: ValidatePlaybookContent(content)}
+	
+	// 结构不合法时ansible也无法解析，不再执行语法检查
+	if len(result.Errors) == 0 {
+		output, err := s.executor.SyntaxCheckPlaybook(ctx, content)
+		switch {
+		case errors.Is(err, ErrSyntaxCheckUnavailable):
+		case err != nil && output == "":
+			return nil, fmt.Errorf("syntax check failed: %v", err)
+		case err != nil:
+			result.SyntaxChecked = true
+			result.Errors = syntaxCheckErrors(output)
+		default:
+			result.SyntaxChecked = true
+		}
+	}
+	
+	result.Valid = len(result.Errors) == 0
+	if result.Errors == nil {
+		result.Errors = []PlaybookError{}
+	}
+	return result, nil
+}
+
+// validatePlaybook 保存前校验playbook，失败时返回*PlaybookValidationError
+func (s *AnsibleService) validatePlaybook(ctx context.Context, content string) error {
+	result, err := s.ValidatePlaybook(ctx, content)
+	if err != nil {
+		return err
+	}
+	if !result.Valid {
+		return &PlaybookValidationError{Errors: result.Errors}
+	}
+	return nil
+}
+
+// syntaxCheckErrors 从ansible-playbook --syntax-check的输出中提取错误信息和位置
+func syntaxCheckErrors(output string) []PlaybookError {
+	var message string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "ERROR!") || strings.HasPrefix(line, "[ERROR]:") {
+			message = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "ERROR!"), "[ERROR]:"))
+			break
+		}
+	}
+	if message == "" {
+		message = strings.TrimSpace(output)
+	}
+	
+	item := PlaybookError{Line: 1, Message: message}
+	if match := syntaxErrorLocation.FindStringSubmatch(output); match != nil {
+		item.Line, _ = strconv.Atoi(match[1])
+		item.Column, _ = strconv.Atoi(match[2])
+	}
+	return []PlaybookError{item}
+}
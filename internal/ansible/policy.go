@@ -0,0 +1,539 @@
+package ansible
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidPolicyRule          = errors.New("invalid policy rule")
+	ErrPolicyDenied               = errors.New("command blocked by policy")
+	ErrPolicyConfirmationRequired = errors.New("command requires confirmation")
+)
+
+// policyConfirmationTTL 警告规则确认令牌的有效期
+const policyConfirmationTTL = 10 * time.Minute
+
+// PolicyMatch 表示一条匹配的策略规则
+type PolicyMatch struct {
+	RuleID uint   `json:"rule_id"`
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+}
+
+// PolicyViolation 表示命令被策略阻止或需要确认，需要确认时包含令牌
+type PolicyViolation struct {
+	Matches   []PolicyMatch `json:"matches"`
+	HostCount int           `json:"host_count"`           // 目标主机数，-1表示无法确定
+	Token     string        `json:"token,omitempty"`      // 确认令牌，在第二次请求的confirm字段中提供
+	ExpiresAt *time.Time    `json:"expires_at,omitempty"` // 确认令牌过期时间
+}
+
+func (e *PolicyViolation) Error() string {
+	reasons := make([]string, len(e.Matches))
+	for i, match := range e.Matches {
+		reasons[i] = fmt.Sprintf("%s (%s)", match.Name, match.Reason)
+	}
+	return fmt.Sprintf("%v: %s", e.Unwrap(), strings.Join(reasons, "; "))
+}
+
+func (e *PolicyViolation) Unwrap() error {
+	if e.Token != "" {
+		return ErrPolicyConfirmationRequired
+	}
+	return ErrPolicyDenied
+}
+
+// ListPolicyRules 列出所有策略规则
+func (s *AnsibleService) ListPolicyRules() ([]PolicyRule, error) {
+	rules := []PolicyRule{}
+	if err := s.db.Order("name ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// CreatePolicyRule 创建策略规则
+func (s *AnsibleService) CreatePolicyRule(req *PolicyRuleRequest) (*PolicyRule, error) {
+	rule := &PolicyRule{}
+	if err := applyPolicyRuleRequest(rule, req); err != nil {
+		return nil, err
+	}
+	
+	if err := s.db.Create(rule).Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// UpdatePolicyRule 更新策略规则
+func (s *AnsibleService) UpdatePolicyRule(id uint, req *PolicyRuleRequest) (*PolicyRule, error) {
+	var rule PolicyRule
+	if err := s.db.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	
+	if err := applyPolicyRuleRequest(&rule, req); err != nil {
+		return nil, err
+	}
+	
+	if err := s.db.Save(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// DeletePolicyRule 删除策略规则，审计记录中保留规则名称
+func (s *AnsibleService) DeletePolicyRule(id uint) error {
+	result := s.db.Delete(&PolicyRule{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	
+	return nil
+}
+
+// applyPolicyRuleRequest 校验请求并写入规则字段，规则至少需要一个条件
+func applyPolicyRuleRequest(rule *PolicyRule, req *PolicyRuleRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPolicyRule)
+	}
+	if req.Action != PolicyActionDeny && req.Action != PolicyActionWarn {
+		return fmt.Errorf("%w: unsupported action %q", ErrInvalidPolicyRule, req.Action)
+	}
+	
+	var modules []string
+	for _, module := range req.Modules {
+		module = strings.TrimSpace(module)
+		if err := validateModuleName(module); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPolicyRule, err)
+		}
+		modules = append(modules, normalizeModuleName(module))
+	}
+	
+	if req.ArgsPattern != "" {
+		if _, err := regexp.Compile(req.ArgsPattern); err != nil {
+			return fmt.Errorf("%w: invalid args pattern: %v", ErrInvalidPolicyRule, err)
+		}
+	}
+	
+	var hostPatterns []string
+	for _, pattern := range req.HostPatterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || strings.ContainsAny(pattern, ",:") {
+			return fmt.Errorf("%w: invalid host pattern %q", ErrInvalidPolicyRule, pattern)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: invalid host pattern %q", ErrInvalidPolicyRule, pattern)
+		}
+		hostPatterns = append(hostPatterns, pattern)
+	}
+	
+	if len(modules) == 0 && req.ArgsPattern == "" && len(hostPatterns) == 0 && req.MaxHosts == 0 {
+		return fmt.Errorf("%w: at least one of modules, args_pattern, host_patterns or max_hosts is required", ErrInvalidPolicyRule)
+	}
+	
+	rule.Name = name
+	rule.Description = req.Description
+	rule.Action = req.Action
+	rule.Modules = strings.Join(modules, ",")
+	rule.ArgsPattern = req.ArgsPattern
+	rule.HostPatterns = strings.Join(hostPatterns, ",")
+	rule.MaxHosts = req.MaxHosts
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	return nil
+}
+
+// ListPolicyAudits 列出策略审计记录，action或userID为空时不过滤
+func (s *AnsibleService) ListPolicyAudits(action string, userID uint, offset, limit int) ([]PolicyAudit, int64, error) {
+	query := s.db.Model(&PolicyAudit{})
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	
+	// 获取总数
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	// 获取分页数据
+	audits := []PolicyAudit{}
+	if err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&audits).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	return audits, total, nil
+}
+
+// checkAdhocPolicy 在adhoc命令入队前检查策略规则。匹配拒绝规则时阻止执行；匹配警告规则时
+// 签发确认令牌，请求中带有效令牌时返回对应的warned记录，入队时由recordPolicyOverride消费。
+// 定时任务和工作流触发的执行无人确认，匹配警告规则时同样阻止
+func (s *AnsibleService) checkAdhocPolicy(userID uint, req *AdhocExecutionRequest, inventory *resolvedInventory) (*PolicyAudit, error) {
+	var rules []PolicyRule
+	if err := s.db.Where("enabled = ?", true).Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("load policy rules failed: %v", err)
+	}
+	
+	var targets *policyTargets
+	var denies, warns []PolicyMatch
+	for _, rule := range rules {
+		if (rule.MaxHosts > 0 || rule.HostPatterns != "") && targets == nil {
+			targets = s.resolvePolicyTargets(inventory, req.Hosts, req.Limit)
+		}
+		reason, ok := matchPolicyRule(&rule, req, targets)
+		if !ok {
+			continue
+		}
+		match := PolicyMatch{RuleID: rule.ID, Name: rule.Name, Action: rule.Action, Reason: reason}
+		if rule.Action == PolicyActionDeny {
+			denies = append(denies, match)
+		} else {
+			warns = append(warns, match)
+		}
+	}
+	if targets == nil && len(denies)+len(warns) > 0 {
+		// 审计记录中记录目标主机数
+		targets = s.resolvePolicyTargets(inventory, req.Hosts, req.Limit)
+	}
+	hostCount := targets.count()
+	
+	automated := req.ScheduleID != nil || req.WorkflowRunID != nil
+	if len(denies) > 0 || (len(warns) > 0 && automated) {
+		matches := append(denies, warns...)
+		audit := newPolicyAudit(userID, PolicyAuditBlocked, req, inventory, hostCount, matches)
+		if err := s.db.Create(audit).Error; err != nil {
+			return nil, fmt.Errorf("create policy audit failed: %v", err)
+		}
+		return nil, &PolicyViolation{Matches: matches, HostCount: hostCount}
+	}
+	if len(warns) == 0 {
+		return nil, nil
+	}
+	
+	if req.Confirm != "" {
+		confirmation, err := s.findPolicyConfirmation(userID, req, inventory, warns)
+		if err != nil {
+			return nil, err
+		}
+		if confirmation != nil {
+			confirmation.HostCount = hostCount
+			return confirmation, nil
+		}
+	}
+	
+	// 没有确认令牌或令牌无效时签发新的令牌
+	token, err := newPolicyToken()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(policyConfirmationTTL)
+	audit := newPolicyAudit(userID, PolicyAuditWarned, req, inventory, hostCount, warns)
+	audit.TokenHash = hashPolicyToken(token)
+	audit.ExpiresAt = &expiresAt
+	if err := s.db.Create(audit).Error; err != nil {
+		return nil, fmt.Errorf("create policy audit failed: %v", err)
+	}
+	return nil, &PolicyViolation{Matches: warns, HostCount: hostCount, Token: token, ExpiresAt: &expiresAt}
+}
+
+// findPolicyConfirmation 查找确认令牌对应的warned记录，令牌必须属于当前用户、未使用未过期，
+// 且针对相同的命令和inventory签发，当前匹配的警告规则都已被确认；不满足时返回nil
+func (s *AnsibleService) findPolicyConfirmation(userID uint, req *AdhocExecutionRequest, inventory *resolvedInventory, warns []PolicyMatch) (*PolicyAudit, error) {
+	var confirmation PolicyAudit
+	err := s.db.Where("token_hash = ? AND user_id = ? AND action = ? AND confirmed_at IS NULL AND expires_at > ?",
+		hashPolicyToken(req.Confirm), userID, PolicyAuditWarned, time.Now()).First(&confirmation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get policy confirmation failed: %v", err)
+	}
+	
+	if confirmation.Module != req.Module || confirmation.Args != req.Args ||
+		confirmation.Hosts != req.Hosts || confirmation.Limit != req.Limit {
+		return nil, nil
+	}
+	// 相同的主机模式在不同的inventory中可能指向不同的主机
+	if !sameUintPtr(confirmation.InventoryID, inventory.ID) || confirmation.InventoryHash != hashPolicyInventory(inventory) {
+		return nil, nil
+	}
+	confirmed := splitList(confirmation.RuleIDs)
+	for _, match := range warns {
+		if !containsString(confirmed, strconv.FormatUint(uint64(match.RuleID), 10)) {
+			return nil, nil
+		}
+	}
+	return &confirmation, nil
+}
+
+// recordPolicyOverride 在创建执行记录的事务中消费确认令牌并记录越过警告规则的执行，
+// 令牌已被并发请求使用时返回错误
+func recordPolicyOverride(tx *gorm.DB, confirmation *PolicyAudit, executionID uint) error {
+	now := time.Now()
+	result := tx.Model(&PolicyAudit{}).
+		Where("id = ? AND confirmed_at IS NULL", confirmation.ID).
+		Update("confirmed_at", now)
+	if result.Error != nil {
+		return fmt.Errorf("confirm policy warning failed: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: confirmation token has already been used", ErrPolicyConfirmationRequired)
+	}
+	
+	confirmationID := confirmation.ID
+	override := &PolicyAudit{
+		UserID:         confirmation.UserID,
+		Action:         PolicyAuditOverridden,
+		Rules:          confirmation.Rules,
+		RuleIDs:        confirmation.RuleIDs,
+		Reasons:        confirmation.Reasons,
+		Module:         confirmation.Module,
+		Args:           confirmation.Args,
+		Hosts:          confirmation.Hosts,
+		Limit:          confirmation.Limit,
+		HostCount:      confirmation.HostCount,
+		InventoryID:    confirmation.InventoryID,
+		InventoryHash:  confirmation.InventoryHash,
+		ConfirmationID: &confirmationID,
+		ExecutionID:    &executionID,
+	}
+	if err := tx.Create(override).Error; err != nil {
+		return fmt.Errorf("create policy audit failed: %v", err)
+	}
+	return nil
+}
+
+// matchPolicyRule 判断规则是否匹配命令，规则中设置的条件全部满足时返回匹配原因。
+// 规则设置了主机条件时targets不为nil
+func matchPolicyRule(rule *PolicyRule, req *AdhocExecutionRequest, targets *policyTargets) (string, bool) {
+	var reasons []string
+	
+	if rule.Modules != "" {
+		matched := false
+		for _, entry := range splitList(rule.Modules) {
+			if moduleMatches(entry, req.Module) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return "", false
+		}
+		reasons = append(reasons, fmt.Sprintf("module %s", req.Module))
+	}
+	
+	if rule.ArgsPattern != "" {
+		pattern, err := regexp.Compile(rule.ArgsPattern)
+		if err != nil || !pattern.MatchString(req.Args) {
+			return "", false
+		}
+		reasons = append(reasons, fmt.Sprintf("args match %q", rule.ArgsPattern))
+	}
+	
+	if rule.HostPatterns != "" {
+		reason, ok := matchRestrictedHosts(splitList(rule.HostPatterns), req.Hosts, targets)
+		if !ok {
+			return "", false
+		}
+		reasons = append(reasons, reason)
+	}
+	
+	if rule.MaxHosts > 0 {
+		hostCount := targets.count()
+		switch {
+		case hostCount < 0:
+			// 无法确定目标主机数时按超过限制处理
+			reasons = append(reasons, fmt.Sprintf("target host count could not be determined, limit is %d", rule.MaxHosts))
+		case hostCount > rule.MaxHosts:
+			reasons = append(reasons, fmt.Sprintf("targets %d hosts, limit is %d", hostCount, rule.MaxHosts))
+		default:
+			return "", false
+		}
+	}
+	
+	if rule.Description != "" {
+		reasons = append(reasons, rule.Description)
+	}
+	return strings.Join(reasons, ", "), true
+}
+
+// matchRestrictedHosts 检查目标主机是否涉及受限的模式：目标主机模式中直接出现受限模式（*等同于all，排除项不参与匹配），
+// 或者解析后的目标主机包含受限模式匹配的全部主机，避免通过正则、通配符或组别名绕过限制；无法解析inventory时按受限处理
+func matchRestrictedHosts(restricted []string, hosts string, targets *policyTargets) (string, bool) {
+	terms := strings.FieldsFunc(hosts, func(r rune) bool { return r == ',' || r == ':' })
+	for _, term := range terms {
+		term = strings.TrimPrefix(strings.TrimSpace(term), "&")
+		if term == "" || strings.HasPrefix(term, "!") {
+			continue
+		}
+		if term == "*" {
+			term = inventoryAllGroup
+		}
+		for _, pattern := range restricted {
+			if ok, _ := path.Match(pattern, term); ok {
+				return fmt.Sprintf("host pattern %q is restricted", term), true
+			}
+		}
+	}
+	
+	if targets.inventory == nil {
+		// 与最大主机数一致，无法确定目标主机时按受限处理
+		return "target hosts could not be determined", true
+	}
+	
+	selected := make(map[string]bool, len(targets.hosts))
+	for _, host := range targets.hosts {
+		selected[host] = true
+	}
+	for _, pattern := range restricted {
+		covered := targets.inventory.MatchHosts(pattern)
+		if len(covered) == 0 {
+			continue
+		}
+		all := true
+		for _, host := range covered {
+			if !selected[host] {
+				all = false
+				break
+			}
+		}
+		if all {
+			return fmt.Sprintf("targets all hosts of restricted pattern %q", pattern), true
+		}
+	}
+	return "", false
+}
+
+// policyTargets 表示策略检查时解析出的目标主机
+type policyTargets struct {
+	inventory *ParsedInventory // 解析后的inventory，无法解析时为nil
+	hosts     []string         // 目标主机模式和--limit共同匹配的主机
+}
+
+// count 返回目标主机数，无法解析inventory时返回-1
+func (t *policyTargets) count() int {
+	if t == nil || t.inventory == nil {
+		return -1
+	}
+	return len(t.hosts)
+}
+
+// resolvePolicyTargets 解析目标主机，无法解析inventory时inventory为nil
+func (s *AnsibleService) resolvePolicyTargets(inventory *resolvedInventory, hosts, limit string) *policyTargets {
+	parsed, matched, err := s.targetHosts(inventory, hosts, limit)
+	if err != nil {
+		return &policyTargets{}
+	}
+	return &policyTargets{inventory: parsed, hosts: matched}
+}
+
+// targetHosts 按inventory计算目标主机模式和--limit共同匹配的主机，返回解析后的inventory和主机名
+func (s *AnsibleService) targetHosts(inventory *resolvedInventory, hosts, limit string) (*ParsedInventory, []string, error) {
+	content := inventory.Content
+	if inventory.Dynamic != nil {
+		dynamic, err := s.BuildDynamicInventory(inventory.Dynamic)
+		if err != nil {
+			return nil, nil, err
+		}
+		content = dynamic.INI()
+	}
+	
+	parsed, err := ParseInventory(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	matched := parsed.MatchHosts(hosts)
+	if limit == "" {
+		return parsed, matched, nil
+	}
+	
+	limited := make(map[string]bool)
+	for _, host := range parsed.MatchHosts(limit) {
+		limited[host] = true
+	}
+	targets := []string{}
+	for _, host := range matched {
+		if limited[host] {
+			targets = append(targets, host)
+		}
+	}
+	return parsed, targets, nil
+}
+
+// newPolicyAudit 根据匹配的规则创建审计记录
+func newPolicyAudit(userID uint, action string, req *AdhocExecutionRequest, inventory *resolvedInventory, hostCount int, matches []PolicyMatch) *PolicyAudit {
+	names := make([]string, len(matches))
+	ids := make([]string, len(matches))
+	reasons := make([]string, len(matches))
+	for i, match := range matches {
+		names[i] = match.Name
+		ids[i] = strconv.FormatUint(uint64(match.RuleID), 10)
+		reasons[i] = fmt.Sprintf("%s: %s", match.Name, match.Reason)
+	}
+	return &PolicyAudit{
+		UserID:        userID,
+		Action:        action,
+		Rules:         strings.Join(names, ","),
+		RuleIDs:       strings.Join(ids, ","),
+		Reasons:       strings.Join(reasons, "\n"),
+		Module:        req.Module,
+		Args:          req.Args,
+		Hosts:         req.Hosts,
+		Limit:         req.Limit,
+		HostCount:     hostCount,
+		InventoryID:   inventory.ID,
+		InventoryHash: hashPolicyInventory(inventory),
+		ScheduleID:    req.ScheduleID,
+		WorkflowRunID: req.WorkflowRunID,
+	}
+}
+
+// newPolicyToken 生成随机确认令牌
+func newPolicyToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate confirmation token failed: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashPolicyInventory 计算解析后的inventory内容和动态过滤条件的SHA-256，确认令牌只对签发时的inventory有效
+func hashPolicyInventory(inventory *resolvedInventory) string {
+	filter, _ := dynamicFilterJSON(inventory.Dynamic)
+	sum := sha256.Sum256([]byte(inventory.Content + "\x00" + filter))
+	return hex.EncodeToString(sum[:])
+}
+
+// sameUintPtr 判断两个可为空的ID是否相同
+func sameUintPtr(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// hashPolicyToken 计算确认令牌的SHA-256，数据库中只保存哈希
+func hashPolicyToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
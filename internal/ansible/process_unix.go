@@ -0,0 +1,19 @@
+//go:build !windows
+
+package ansible
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup 让ansible在独立的进程组中运行，取消时终止整个进程组(包括派生的ssh子进程)
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		if cmd.Process == nil {
+			return nil
+		}
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package ansible
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup Windows下没有进程组，取消时只终止ansible主进程
+func setProcessGroup(cmd *exec.Cmd) {
+}
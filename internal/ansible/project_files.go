@@ -0,0 +1,583 @@
+package ansible
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxProjectArchiveSize 上传的项目压缩包最大大小
+	MaxProjectArchiveSize = 100 << 20
+	// maxProjectExtractSize 压缩包解压后的最大总大小
+	maxProjectExtractSize = 500 << 20
+	// maxProjectFiles 压缩包中最多包含的文件数量
+	maxProjectFiles = 10000
+	// maxProjectFileSize 通过文件API读取和写入的单个文件最大大小
+	maxProjectFileSize = 5 << 20
+	// projectGitDir 文件树和复制时忽略的git元数据目录
+	projectGitDir = ".git"
+)
+
+// cleanProjectPath 规范化相对项目根目录的路径，拒绝绝对路径和指向项目外的路径
+func cleanProjectPath(p string, allowEmpty bool) (string, error) {
+	p = strings.TrimSpace(filepath.ToSlash(p))
+	p = strings.TrimLeft(p, "/")
+	if p == "" {
+		if allowEmpty {
+			return "", nil
+		}
+		return "", fmt.Errorf("%w: path is required", ErrInvalidProjectPath)
+	}
+	
+	for _, part := range strings.Split(p, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("%w: %s", ErrInvalidProjectPath, p)
+		}
+	}
+	
+	cleaned := path.Clean(p)
+	if cleaned == "." {
+		if allowEmpty {
+			return "", nil
+		}
+		return "", fmt.Errorf("%w: path is required", ErrInvalidProjectPath)
+	}
+	if cleaned == projectGitDir || strings.HasPrefix(cleaned, projectGitDir+"/") {
+		return "", fmt.Errorf("%w: %s is reserved", ErrInvalidProjectPath, projectGitDir)
+	}
+	return cleaned, nil
+}
+
+// GetProjectTree 获取项目的文件树，按路径排序
+func (s *AnsibleService) GetProjectTree(id uint) ([]ProjectFile, error) {
+	if _, err := s.GetProject(id); err != nil {
+		return nil, err
+	}
+	
+	root := s.projectDir(id)
+	files := []ProjectFile{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		if d.IsDir() && d.Name() == projectGitDir {
+			return filepath.SkipDir
+		}
+	
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		file := ProjectFile{Path: filepath.ToSlash(rel), Type: "file", Size: info.Size(), ModifiedAt: info.ModTime()}
+		if d.IsDir() {
+			file.Type = "dir"
+			file.Size = 0
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("walk project dir failed: %v", err)
+	}
+	
+	return files, nil
+}
+
+// ReadProjectFile 读取项目中的文件内容，不能经由符号链接读取项目之外的文件
+func (s *AnsibleService) ReadProjectFile(id uint, filePath string) (string, error) {
+	if _, err := s.GetProject(id); err != nil {
+		return "", err
+	}
+	
+	rel, err := cleanProjectPath(filePath, false)
+	if err != nil {
+		return "", err
+	}
+	
+	root, err := os.OpenRoot(s.projectDir(id))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrProjectFileNotFound, rel)
+	}
+	defer root.Close()
+	
+	file, err := root.Open(rel)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrProjectFileNotFound, rel)
+	}
+	defer file.Close()
+	
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%w: %s", ErrProjectFileNotFound, rel)
+	}
+	if info.Size() > maxProjectFileSize {
+		return "", fmt.Errorf("%w: %s is too large to read through the API", ErrInvalidProjectPath, rel)
+	}
+	
+	data, err := io.ReadAll(io.LimitReader(file, maxProjectFileSize))
+	if err != nil {
+		return "", fmt.Errorf("read project file failed: %v", err)
+	}
+	return string(data), nil
+}
+
+// WriteProjectFile 创建或覆盖项目中的文件，自动创建上级目录；不能经由符号链接写入项目之外的文件
+func (s *AnsibleService) WriteProjectFile(id uint, userID uint, filePath, content string) error {
+	if err := s.editableProject(id, userID); err != nil {
+		return err
+	}
+	
+	rel, err := cleanProjectPath(filePath, false)
+	if err != nil {
+		return err
+	}
+	if len(content) > maxProjectFileSize {
+		return fmt.Errorf("%w: %s exceeds %d bytes", ErrInvalidProjectPath, rel, maxProjectFileSize)
+	}
+	
+	if err := os.MkdirAll(s.projectDir(id), 0755); err != nil {
+		return fmt.Errorf("create project dir failed: %v", err)
+	}
+	root, err := os.OpenRoot(s.projectDir(id))
+	if err != nil {
+		return fmt.Errorf("open project dir failed: %v", err)
+	}
+	defer root.Close()
+	
+	if info, err := root.Stat(rel); err == nil && info.IsDir() {
+		return fmt.Errorf("%w: %s is a directory", ErrInvalidProjectPath, rel)
+	}
+	if err := mkdirAllInRoot(root, path.Dir(rel)); err != nil {
+		return fmt.Errorf("%w: create dir for %s failed: %v", ErrInvalidProjectPath, rel, err)
+	}
+	
+	file, err := root.OpenFile(rel, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("%w: write %s failed: %v", ErrInvalidProjectPath, rel, err)
+	}
+	_, err = file.WriteString(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("write project file failed: %v", err)
+	}
+	
+	s.db.Model(&Project{}).Where("id = ?", id).Update("updated_at", time.Now())
+	return nil
+}
+
+// mkdirAllInRoot 在root中逐级创建目录，已存在的目录不报错
+func mkdirAllInRoot(root *os.Root, dir string) error {
+	if dir == "." || dir == "" {
+		return nil
+	}
+	
+	current := ""
+	for _, part := range strings.Split(dir, "/") {
+		current = path.Join(current, part)
+		if err := root.Mkdir(current, 0755); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteProjectFile 删除项目中的文件或目录
+func (s *AnsibleService) DeleteProjectFile(id uint, userID uint, filePath string) error {
+	if err := s.editableProject(id, userID); err != nil {
+		return err
+	}
+	
+	rel, err := cleanProjectPath(filePath, false)
+	if err != nil {
+		return err
+	}
+	
+	// 通过os.Root检查路径，上级目录中的符号链接不能指向项目之外
+	root, err := os.OpenRoot(s.projectDir(id))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrProjectFileNotFound, rel)
+	}
+	defer root.Close()
+	
+	info, err := root.Lstat(rel)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrProjectFileNotFound, rel)
+	}
+	if !info.IsDir() {
+		// 文件或符号链接本身，不跟随链接
+		if err := root.Remove(rel); err != nil {
+			return fmt.Errorf("delete project file failed: %v", err)
+		}
+		s.db.Model(&Project{}).Where("id = ?", id).Update("updated_at", time.Now())
+		return nil
+	}
+	
+	full := filepath.Join(s.projectDir(id), filepath.FromSlash(rel))
+	if err := os.RemoveAll(full); err != nil {
+		return fmt.Errorf("delete project file failed: %v", err)
+	}
+	
+	s.db.Model(&Project{}).Where("id = ?", id).Update("updated_at", time.Now())
+	return nil
+}
+
+// ListProjectPlaybooks 列出项目中可以作为入口的playbook，即内容为play列表的YAML文件
+func (s *AnsibleService) ListProjectPlaybooks(id uint) ([]string, error) {
+	if _, err := s.GetProject(id); err != nil {
+		return nil, err
+	}
+	return findPlaybooks(s.projectDir(id))
+}
+
+// findPlaybooks 在目录中查找内容为play列表的YAML文件，返回相对路径
+func findPlaybooks(root string) ([]string, error) {
+	playbooks := []string{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == projectGitDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(p); ext != ".yml" && ext != ".yaml" {
+			return nil
+		}
+	
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxProjectFileSize {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil
+		}
+		if len(ValidatePlaybookContent(string(data))) == 0 {
+			rel, _ := filepath.Rel(root, p)
+			playbooks = append(playbooks, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("walk project dir failed: %v", err)
+	}
+	
+	sort.Strings(playbooks)
+	return playbooks, nil
+}
+
+// UploadProjectArchive 将tar.gz或zip压缩包解压到项目目录，replace为true时先清空原有文件；
+// 压缩包中只有一个顶层目录时以该目录作为项目根目录
+func (s *AnsibleService) UploadProjectArchive(id uint, userID uint, archive io.Reader, replace bool) error {
+	if err := s.editableProject(id, userID); err != nil {
+		return err
+	}
+	
+	if err := os.MkdirAll(s.projectsDir, 0755); err != nil {
+		return fmt.Errorf("create projects dir failed: %v", err)
+	}
+	
+	// 先保存到临时文件，zip需要随机读取
+	archiveFile, err := os.CreateTemp(s.projectsDir, ".upload_*")
+	if err != nil {
+		return fmt.Errorf("create upload file failed: %v", err)
+	}
+	defer os.Remove(archiveFile.Name())
+	defer archiveFile.Close()
+	
+	size, err := io.Copy(archiveFile, io.LimitReader(archive, MaxProjectArchiveSize+1))
+	if err != nil {
+		return fmt.Errorf("save upload file failed: %v", err)
+	}
+	if size > MaxProjectArchiveSize {
+		return fmt.Errorf("%w: archive exceeds %d bytes", ErrInvalidArchive, MaxProjectArchiveSize)
+	}
+	
+	staging, err := os.MkdirTemp(s.projectsDir, ".extract_*")
+	if err != nil {
+		return fmt.Errorf("create extract dir failed: %v", err)
+	}
+	defer os.RemoveAll(staging)
+	
+	if err := extractArchive(archiveFile, size, staging); err != nil {
+		return err
+	}
+	
+	source := staging
+	if entries, err := os.ReadDir(staging); err == nil && len(entries) == 1 && entries[0].IsDir() {
+		source = filepath.Join(staging, entries[0].Name())
+	}
+	
+	target := s.projectDir(id)
+	if replace {
+		if err := os.RemoveAll(target); err != nil {
+			return fmt.Errorf("clear project dir failed: %v", err)
+		}
+	}
+	if err := copyProjectTree(source, target); err != nil {
+		return err
+	}
+	
+	s.db.Model(&Project{}).Where("id = ?", id).Update("updated_at", time.Now())
+	return nil
+}
+
+// extractArchive 根据文件头识别压缩包格式并解压到目标目录
+func extractArchive(file *os.File, size int64, dest string) error {
+	header := make([]byte, 4)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")):
+		return extractZip(file, size, dest)
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		return extractTarGz(file, dest)
+	default:
+		return fmt.Errorf("%w: only tar.gz and zip archives are supported", ErrInvalidArchive)
+	}
+}
+
+// extractTarGz 解压tar.gz压缩包
+func extractTarGz(r io.Reader, dest string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer gz.Close()
+	
+	tr := tar.NewReader(gz)
+	var total int64
+	files := 0
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+	
+		target, err := archiveEntryPath(dest, header.Name)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			continue
+		}
+	
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("create dir failed: %v", err)
+			}
+		case tar.TypeReg:
+			files++
+			total += header.Size
+			if err := checkExtractLimits(files, total); err != nil {
+				return err
+			}
+			if err := writeArchiveFile(target, tr, header.FileInfo().Mode()); err != nil {
+				return err
+			}
+		case tar.TypeXGlobalHeader:
+		default:
+			// 链接可能指向项目目录之外，不允许
+			return fmt.Errorf("%w: unsupported entry type for %s", ErrInvalidArchive, header.Name)
+		}
+	}
+}
+
+// extractZip 解压zip压缩包
+func extractZip(r io.ReaderAt, size int64, dest string) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	
+	var total int64
+	files := 0
+	for _, entry := range zr.File {
+		target, err := archiveEntryPath(dest, entry.Name)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			continue
+		}
+	
+		mode := entry.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("create dir failed: %v", err)
+			}
+		case mode.IsRegular():
+			files++
+			total += int64(entry.UncompressedSize64)
+			if err := checkExtractLimits(files, total); err != nil {
+				return err
+			}
+			rc, err := entry.Open()
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+			}
+			err = writeArchiveFile(target, rc, mode)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%w: unsupported entry type for %s", ErrInvalidArchive, entry.Name)
+		}
+	}
+	return nil
+}
+
+// archiveEntryPath 计算压缩包条目的解压路径，拒绝绝对路径和..，忽略git元数据
+func archiveEntryPath(dest, name string) (string, error) {
+	name = filepath.ToSlash(name)
+	if strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("%w: absolute path %s", ErrInvalidArchive, name)
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("%w: path %s escapes the project", ErrInvalidArchive, name)
+		}
+		if part == projectGitDir {
+			return "", nil
+		}
+	}
+	
+	cleaned := path.Clean(name)
+	if cleaned == "." {
+		return "", nil
+	}
+	return filepath.Join(dest, filepath.FromSlash(cleaned)), nil
+}
+
+// checkExtractLimits 限制解压后的文件数量和总大小
+func checkExtractLimits(files int, total int64) error {
+	if files > maxProjectFiles {
+		return fmt.Errorf("%w: archive contains more than %d files", ErrInvalidArchive, maxProjectFiles)
+	}
+	if total > maxProjectExtractSize {
+		return fmt.Errorf("%w: archive expands to more than %d bytes", ErrInvalidArchive, maxProjectExtractSize)
+	}
+	return nil
+}
+
+// writeArchiveFile 写入解压的文件，只保留可执行权限位
+func writeArchiveFile(target string, r io.Reader, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("create dir failed: %v", err)
+	}
+	
+	perm := fs.FileMode(0644)
+	if mode&0111 != 0 {
+		perm = 0755
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("create file failed: %v", err)
+	}
+	defer file.Close()
+	
+	// 声明的大小不可信，写入时再次限制
+	if _, err := io.Copy(file, io.LimitReader(r, maxProjectExtractSize+1)); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	return nil
+}
+
+// copyProjectTree 复制项目目录，只复制普通文件和目录，忽略git元数据和符号链接
+func copyProjectTree(src, dest string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+	
+		if d.IsDir() {
+			if d.Name() == projectGitDir && p != src {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+	
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		in, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+	
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return fmt.Errorf("copy project file failed: %v", err)
+		}
+		defer out.Close()
+	
+		if _, err := io.Copy(out, in); err != nil {
+			return fmt.Errorf("copy project file failed: %v", err)
+		}
+		return nil
+	})
+}
+
+// removeProjectSymlinks 删除项目目录中的符号链接，git元数据目录除外；
+// 与压缩包解压和copyProjectTree一致，项目中不保留可能指向项目之外的链接
+func removeProjectSymlinks(root string) error {
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == projectGitDir && p != root {
+			return filepath.SkipDir
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			if err := os.Remove(p); err != nil {
+				return fmt.Errorf("remove symlink failed: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
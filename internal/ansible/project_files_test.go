@@ -0,0 +1,185 @@
+package ansible
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newProjectTestService 创建使用内存数据库和临时项目目录的服务
+func newProjectTestService(t *testing.T) *AnsibleService {
+	t.Helper()
+	
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open database failed: %v", err)
+	}
+	if err := db.AutoMigrate(&Project{}); err != nil {
+		t.Fatalf("migrate database failed: %v", err)
+	}
+	
+	s := NewAnsibleService(db, nil)
+	s.SetProjectsDir(t.TempDir())
+	t.Cleanup(func() { s.stop(ErrServerShutdown) })
+	return s
+}
+
+// writeOutsideSecret 在项目目录之外写入一个文件，返回其路径
+func writeOutsideSecret(t *testing.T) string {
+	t.Helper()
+	
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(secret, []byte("top secret\n"), 0600); err != nil {
+		t.Fatalf("write secret failed: %v", err)
+	}
+	return secret
+}
+
+// runTestGit 在仓库目录中执行git命令
+func runTestGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	
+	args = append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v: %s", args, err, output)
+	}
+}
+
+func TestGitProjectSymlinks(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	
+	s := newProjectTestService(t)
+	secret := writeOutsideSecret(t)
+	
+	repo := t.TempDir()
+	runTestGit(t, repo, "init", "--quiet")
+	if err := os.WriteFile(filepath.Join(repo, "site.yml"), []byte("- hosts: all\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(repo, "secret")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../..", filepath.Join(repo, "up")); err != nil {
+		t.Fatal(err)
+	}
+	runTestGit(t, repo, "add", "--all")
+	runTestGit(t, repo, "commit", "--quiet", "-m", "initial")
+	
+	project := &Project{Name: "repo", SourceType: ProjectSourceGit, GitURL: repo, UserID: 1}
+	if err := s.db.Create(project).Error; err != nil {
+		t.Fatal(err)
+	}
+	synced, err := s.SyncProject(project.ID, 1)
+	if err != nil {
+		t.Fatalf("SyncProject() error = %v", err)
+	}
+	
+	// 同步后的工作区和执行时的检出目录中都不能有符号链接
+	checkout := filepath.Join(t.TempDir(), "checkout")
+	if err := checkoutGitCommit(context.Background(), s.projectDir(project.ID), synced.CommitSHA, checkout, filepath.Join(t.TempDir(), "index")); err != nil {
+		t.Fatalf("checkoutGitCommit() error = %v", err)
+	}
+	for _, dir := range []string{s.projectDir(project.ID), checkout} {
+		for _, name := range []string{"secret", "up"} {
+			info, err := os.Lstat(filepath.Join(dir, name))
+			if err == nil && info.Mode()&os.ModeSymlink != 0 {
+				t.Errorf("%s/%s is a symlink", dir, name)
+			}
+		}
+	}
+	
+	content, err := s.ReadProjectFile(project.ID, "secret")
+	if err == nil && content == "top secret\n" {
+		t.Error("ReadProjectFile() returned content outside the project")
+	}
+	if _, err := s.ReadProjectFile(project.ID, "site.yml"); err != nil {
+		t.Errorf("ReadProjectFile(site.yml) error = %v", err)
+	}
+}
+
+func TestProjectFileSymlinkEscape(t *testing.T) {
+	s := newProjectTestService(t)
+	secret := writeOutsideSecret(t)
+	
+	project := &Project{Name: "files", SourceType: ProjectSourceFiles, UserID: 1}
+	if err := s.db.Create(project).Error; err != nil {
+		t.Fatal(err)
+	}
+	dir := s.projectDir(project.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// 模拟从git改为files之前留下的符号链接
+	if err := os.Symlink(secret, filepath.Join(dir, "secret")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Dir(secret), filepath.Join(dir, "outside")); err != nil {
+		t.Fatal(err)
+	}
+	
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"read symlinked file", func() error {
+			_, err := s.ReadProjectFile(project.ID, "secret")
+			return err
+		}},
+		{"read through symlinked dir", func() error {
+			_, err := s.ReadProjectFile(project.ID, "outside/secret.txt")
+			return err
+		}},
+		{"write symlinked file", func() error {
+			return s.WriteProjectFile(project.ID, 1, "secret", "overwritten\n")
+		}},
+		{"write through symlinked dir", func() error {
+			return s.WriteProjectFile(project.ID, 1, "outside/secret.txt", "overwritten\n")
+		}},
+		{"create through symlinked dir", func() error {
+			return s.WriteProjectFile(project.ID, 1, "outside/new/file.yml", "created\n")
+		}},
+		{"delete through symlinked dir", func() error {
+			return s.DeleteProjectFile(project.ID, 1, "outside/secret.txt")
+		}},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); err == nil {
+				t.Error("call succeeded, want error")
+			}
+			data, err := os.ReadFile(secret)
+			if err != nil || string(data) != "top secret\n" {
+				t.Fatalf("file outside the project changed: %q, %v", data, err)
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(secret), "new")); !errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("directory created outside the project: %v", err)
+			}
+		})
+	}
+	
+	// 删除符号链接本身不影响链接目标
+	if err := s.DeleteProjectFile(project.ID, 1, "outside"); err != nil {
+		t.Fatalf("DeleteProjectFile(outside) error = %v", err)
+	}
+	if _, err := os.Stat(secret); err != nil {
+		t.Errorf("symlink target removed: %v", err)
+	}
+	
+	// 正常的文件读写不受影响
+	if err := s.WriteProjectFile(project.ID, 1, "roles/web/tasks/main.yml", "- ping:\n"); err != nil {
+		t.Fatalf("WriteProjectFile() error = %v", err)
+	}
+	content, err := s.ReadProjectFile(project.ID, "roles/web/tasks/main.yml")
+	if err != nil || content != "- ping:\n" {
+		t.Errorf("ReadProjectFile() = %q, %v", content, err)
+	}
+}
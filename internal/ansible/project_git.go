@@ -0,0 +1,269 @@
+package ansible
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidRepository   = errors.New("invalid git repository")
+	ErrRepositorySync      = errors.New("git repository sync failed")
+	ErrRepositoryNotSynced = errors.New("git repository has not been synced")
+	ErrProjectReadOnly     = errors.New("project files are managed by git")
+)
+
+const (
+	// gitSyncTimeout 单次同步(clone/fetch)的超时时间
+	gitSyncTimeout = 5 * time.Minute
+	// repositorySyncPollInterval 检查需要自动同步的项目的间隔
+	repositorySyncPollInterval = time.Minute
+	// gitKnownHostsFile 保存ssh仓库主机密钥的文件，首次连接时记录
+	gitKnownHostsFile = ".known_hosts"
+)
+
+// SyncProject 立即同步git项目，返回同步后的项目
+func (s *AnsibleService) SyncProject(id uint, userID uint) (*Project, error) {
+	project, err := s.ownedProject(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if project.SourceType != ProjectSourceGit {
+		return nil, fmt.Errorf("%w: project is not backed by a git repository", ErrInvalidRepository)
+	}
+	
+	if err := s.syncProject(project); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// syncProject 拉取仓库并将工作区切换到配置的分支或标签，同步结果保存到项目记录；
+// 同步串行执行，避免同一仓库被并发修改
+func (s *AnsibleService) syncProject(project *Project) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	
+	ctx, cancel := context.WithTimeout(s.ctx, gitSyncTimeout)
+	defer cancel()
+	
+	sha, syncErr := s.fetchRepository(ctx, project)
+	
+	now := time.Now()
+	updates := map[string]interface{}{"last_synced_at": now}
+	if syncErr != nil {
+		updates["last_sync_error"] = syncErr.Error()
+	} else {
+		updates["commit_sha"] = sha
+		updates["last_sync_error"] = ""
+	}
+	if err := s.db.Model(&Project{}).Where("id = ?", project.ID).Updates(updates).Error; err != nil {
+		return fmt.Errorf("update project sync status failed: %v", err)
+	}
+	
+	project.LastSyncedAt = &now
+	if syncErr != nil {
+		project.LastSyncError = syncErr.Error()
+		return fmt.Errorf("%w: %v", ErrRepositorySync, syncErr)
+	}
+	project.CommitSHA = sha
+	project.LastSyncError = ""
+	return nil
+}
+
+// fetchRepository 首次同步时clone仓库，之后fetch并检出配置的分支或标签，返回检出的提交SHA
+func (s *AnsibleService) fetchRepository(ctx context.Context, project *Project) (string, error) {
+	dir := s.projectDir(project.ID)
+	
+	env, cleanup, err := s.gitSyncEnv(project)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+	
+	if _, err := os.Stat(filepath.Join(dir, projectGitDir)); err != nil {
+		// clone要求目标目录为空
+		if err := os.RemoveAll(dir); err != nil {
+			return "", fmt.Errorf("clear project dir failed: %v", err)
+		}
+		if _, err := runGit(ctx, env, "", "clone", "--quiet", "--no-checkout", "--", project.GitURL, dir); err != nil {
+			return "", err
+		}
+	} else if _, err := runGit(ctx, env, dir, "remote", "set-url", "origin", project.GitURL); err != nil {
+		return "", err
+	}
+	
+	// 关闭自动gc，分支被强制推送后已排队执行引用的旧提交仍然可以检出
+	if _, err := runGit(ctx, env, dir, "-c", "gc.auto=0", "fetch", "--quiet", "--prune", "--force", "--tags",
+		"origin", "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+		return "", err
+	}
+	
+	sha, err := resolveGitRef(ctx, env, dir, project.GitRef)
+	if err != nil {
+		return "", err
+	}
+	
+	// 符号链接检出为普通文件，并删除之前的版本检出的链接，文件API和执行都不能经由链接访问项目之外的文件
+	if _, err := runGit(ctx, env, dir, "-c", "core.symlinks=false", "checkout", "--quiet", "--force", "--detach", sha); err != nil {
+		return "", err
+	}
+	if _, err := runGit(ctx, env, dir, "clean", "-ffdxq"); err != nil {
+		return "", err
+	}
+	if err := removeProjectSymlinks(dir); err != nil {
+		return "", err
+	}
+	return sha, nil
+}
+
+// resolveGitRef 将分支或标签解析为提交SHA，依次尝试远端分支、标签和提交SHA
+func resolveGitRef(ctx context.Context, env []string, dir, ref string) (string, error) {
+	candidates := []string{"refs/remotes/origin/HEAD"}
+	if ref != "" {
+		candidates = []string{"refs/remotes/origin/" + ref, "refs/tags/" + ref, ref}
+	}
+	
+	for _, candidate := range candidates {
+		sha, err := runGit(ctx, env, dir, "rev-parse", "--verify", "--quiet", candidate+"^{commit}")
+		if err == nil && sha != "" {
+			return sha, nil
+		}
+	}
+	
+	if ref == "" {
+		return "", fmt.Errorf("remote repository has no default branch")
+	}
+	return "", fmt.Errorf("branch or tag not found: %s", ref)
+}
+
+// gitSyncEnv 生成同步使用的环境变量；配置了部署密钥时写入临时文件供ssh使用，cleanup负责删除该文件
+func (s *AnsibleService) gitSyncEnv(project *Project) ([]string, func(), error) {
+	cleanup := func() {}
+	
+	root, err := filepath.Abs(s.projectsDir)
+	if err != nil {
+		return nil, cleanup, fmt.Errorf("resolve projects dir failed: %v", err)
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, cleanup, fmt.Errorf("create projects dir failed: %v", err)
+	}
+	
+	sshCommand := []string{"ssh", "-o", "BatchMode=yes", "-o", "StrictHostKeyChecking=accept-new",
+		"-o", "UserKnownHostsFile=" + shellQuote(filepath.Join(root, gitKnownHostsFile))}
+	
+	if project.DeployKey != "" {
+		keyFile, err := os.CreateTemp(root, ".deploy_key_*")
+		if err != nil {
+			return nil, cleanup, fmt.Errorf("create deploy key file failed: %v", err)
+		}
+		cleanup = func() { os.Remove(keyFile.Name()) }
+	
+		key := project.DeployKey
+		if !strings.HasSuffix(key, "\n") {
+			key += "\n"
+		}
+		_, err = keyFile.WriteString(key)
+		if closeErr := keyFile.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(keyFile.Name(), 0600)
+		}
+		if err != nil {
+			cleanup()
+			return nil, func() {}, fmt.Errorf("write deploy key file failed: %v", err)
+		}
+	
+		sshCommand = append(sshCommand, "-o", "IdentitiesOnly=yes", "-i", shellQuote(keyFile.Name()))
+	}
+	
+	return gitEnv("GIT_SSH_COMMAND=" + strings.Join(sshCommand, " ")), cleanup, nil
+}
+
+// shellQuote 使用单引号转义，GIT_SSH_COMMAND由shell解析
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// repositorySyncer 定期同步配置了自动同步间隔的git项目
+func (s *AnsibleService) repositorySyncer() {
+	defer s.wg.Done()
+	
+	ticker := time.NewTicker(repositorySyncPollInterval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.syncDueProjects(time.Now())
+		}
+	}
+}
+
+// syncDueProjects 同步距离上次同步已超过自动同步间隔的git项目
+func (s *AnsibleService) syncDueProjects(now time.Time) {
+	var projects []Project
+	if err := s.db.Where("source_type = ? AND sync_interval > 0", ProjectSourceGit).Find(&projects).Error; err != nil {
+		log.Printf("Load git projects failed: %v", err)
+		return
+	}
+	
+	for i := range projects {
+		if s.ctx.Err() != nil {
+			return
+		}
+		project := &projects[i]
+		if project.LastSyncedAt != nil && now.Sub(*project.LastSyncedAt) < time.Duration(project.SyncInterval)*time.Minute {
+			continue
+		}
+		if err := s.syncProject(project); err != nil {
+			log.Printf("Sync project %d failed: %v", project.ID, err)
+		}
+	}
+}
+
+// projectCommit 确定git项目执行使用的提交，未指定时使用最近一次同步的提交
+func (s *AnsibleService) projectCommit(ctx context.Context, project *Project, commit string) (string, error) {
+	if commit == "" {
+		commit = project.CommitSHA
+	}
+	if commit == "" {
+		return "", ErrRepositoryNotSynced
+	}
+	return resolveGitCommit(ctx, s.projectDir(project.ID), commit)
+}
+
+// applyGitSource 校验并应用项目的git配置，返回是否需要重新同步以及是否需要重新clone
+func applyGitSource(project *Project, req *ProjectRequest) (resync bool, reclone bool, err error) {
+	gitURL, err := validateGitURL(req.GitURL)
+	if err != nil {
+		return false, false, err
+	}
+	gitRef, err := validateGitRef(req.GitRef)
+	if err != nil {
+		return false, false, err
+	}
+	if req.DeployKey != "" && !strings.Contains(req.DeployKey, "PRIVATE KEY") {
+		return false, false, fmt.Errorf("%w: deploy key must be a PEM or OpenSSH private key", ErrInvalidRepository)
+	}
+	
+	reclone = project.SourceType != ProjectSourceGit || project.GitURL != gitURL
+	resync = reclone || project.GitRef != gitRef || (req.DeployKey != "" && req.DeployKey != project.DeployKey)
+	
+	project.SourceType = ProjectSourceGit
+	project.GitURL = gitURL
+	project.GitRef = gitRef
+	if req.DeployKey != "" {
+		project.DeployKey = req.DeployKey
+	}
+	project.SyncInterval = req.SyncInterval
+	return resync, reclone, nil
+}
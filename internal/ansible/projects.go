@@ -0,0 +1,308 @@
+package ansible
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidProjectPath  = errors.New("invalid project path")
+	ErrProjectFileNotFound = errors.New("project file not found")
+	ErrInvalidArchive      = errors.New("invalid project archive")
+)
+
+// defaultProjectsDir 未配置时项目文件的根目录
+const defaultProjectsDir = "projects"
+
+// SetProjectsDir 设置项目文件的根目录，每个项目保存在以项目ID命名的子目录中
+func (s *AnsibleService) SetProjectsDir(dir string) {
+	s.projectsDir = dir
+}
+
+// projectDir 返回项目的文件目录
+func (s *AnsibleService) projectDir(id uint) string {
+	return filepath.Join(s.projectsDir, strconv.FormatUint(uint64(id), 10))
+}
+
+// CreateProject 创建项目及其文件目录
+func (s *AnsibleService) CreateProject(userID uint, req *ProjectRequest) (*Project, error) {
+	defaultPlaybook, err := cleanProjectPath(req.DefaultPlaybook, true)
+	if err != nil {
+		return nil, err
+	}
+	
+	project := &Project{
+		Name:            req.Name,
+		Description:     req.Description,
+		DefaultPlaybook: defaultPlaybook,
+		SourceType:      ProjectSourceFiles,
+		UserID:          userID,
+	}
+	if req.SourceType == ProjectSourceGit {
+		if _, _, err := applyGitSource(project, req); err != nil {
+			return nil, err
+		}
+	}
+	
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(project).Error; err != nil {
+			return err
+		}
+		if err := os.MkdirAll(s.projectDir(project.ID), 0755); err != nil {
+			return fmt.Errorf("create project dir failed: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	// git项目创建时立即clone，仓库无法访问时不保留项目
+	if project.SourceType == ProjectSourceGit {
+		if err := s.syncProject(project); err != nil {
+			s.db.Delete(&Project{}, project.ID)
+			os.RemoveAll(s.projectDir(project.ID))
+			return nil, err
+		}
+	}
+	
+	return project, nil
+}
+
+// UpdateProject 更新项目信息
+func (s *AnsibleService) UpdateProject(id uint, userID uint, req *ProjectRequest) (*Project, error) {
+	var project Project
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&project).Error; err != nil {
+		return nil, err
+	}
+	
+	defaultPlaybook, err := cleanProjectPath(req.DefaultPlaybook, true)
+	if err != nil {
+		return nil, err
+	}
+	
+	// 未指定来源时保持不变，git项目只有提供了git_url才更新仓库配置；
+	// 从git改为files时保留当前文件，之后可以编辑和上传
+	var resync, reclone bool
+	switch req.SourceType {
+	case ProjectSourceGit:
+		resync, reclone, err = applyGitSource(&project, req)
+		if err != nil {
+			return nil, err
+		}
+	case ProjectSourceFiles:
+		project.SourceType = ProjectSourceFiles
+		project.GitURL = ""
+		project.GitRef = ""
+		project.DeployKey = ""
+		project.SyncInterval = 0
+		project.CommitSHA = ""
+		project.LastSyncedAt = nil
+		project.LastSyncError = ""
+	default:
+		if project.SourceType == ProjectSourceGit && req.GitURL != "" {
+			resync, reclone, err = applyGitSource(&project, req)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	
+	project.Name = req.Name
+	project.Description = req.Description
+	project.DefaultPlaybook = defaultPlaybook
+	
+	if err := s.db.Save(&project).Error; err != nil {
+		return nil, err
+	}
+	
+	// 改为files时删除git检出留下的符号链接，之后通过文件API编辑
+	if req.SourceType == ProjectSourceFiles {
+		if err := removeProjectSymlinks(s.projectDir(project.ID)); err != nil {
+			return nil, fmt.Errorf("clear project symlinks failed: %v", err)
+		}
+	}
+	
+	// 仓库地址变化或从files改为git时重新clone，原有文件会被替换
+	if reclone {
+		if err := os.RemoveAll(s.projectDir(project.ID)); err != nil {
+			return nil, fmt.Errorf("clear project dir failed: %v", err)
+		}
+	}
+	if resync {
+		if err := s.syncProject(&project); err != nil {
+			return nil, err
+		}
+	}
+	
+	return &project, nil
+}
+
+// DeleteProject 删除项目及其文件，已产生的执行记录保留
+func (s *AnsibleService) DeleteProject(id uint, userID uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&Project{})
+	if result.Error != nil {
+		return result.Error
+	}
+	
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	
+	if err := os.RemoveAll(s.projectDir(id)); err != nil {
+		log.Printf("Warning: remove project dir %d failed: %v", id, err)
+	}
+	
+	return nil
+}
+
+// GetProject 获取项目
+func (s *AnsibleService) GetProject(id uint) (*Project, error) {
+	var project Project
+	err := s.db.First(&project, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// ListProjects 列出项目
+func (s *AnsibleService) ListProjects(userID uint, offset, limit int) ([]Project, int64, error) {
+	var projects []Project
+	var total int64
+	
+	query := s.db.Model(&Project{}).Where("user_id = ?", userID)
+	
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	// 获取分页数据
+	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&projects).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	return projects, total, nil
+}
+
+// ownedProject 获取当前用户自己的项目，修改项目文件前使用
+func (s *AnsibleService) ownedProject(id uint, userID uint) (*Project, error) {
+	var project Project
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&project).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// editableProject 检查项目属于当前用户且文件可以修改，git项目的文件只能通过同步更新
+func (s *AnsibleService) editableProject(id uint, userID uint) error {
+	project, err := s.ownedProject(id, userID)
+	if err != nil {
+		return err
+	}
+	if project.SourceType == ProjectSourceGit {
+		return ErrProjectReadOnly
+	}
+	return nil
+}
+
+// ExecuteProject 执行项目中的入口playbook，执行进入队列后由worker异步执行
+func (s *AnsibleService) ExecuteProject(ctx context.Context, userID uint, projectID uint, req *PlaybookExecutionRequest) (*PlaybookExecution, error) {
+	project, err := s.GetProject(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get project failed: %w", err)
+	}
+	
+	// git项目在入队时确定提交，之后的同步不影响本次执行
+	var commit string
+	if project.SourceType == ProjectSourceGit {
+		commit, err = s.projectCommit(ctx, project, req.CommitSHA)
+		if err != nil {
+			return nil, err
+		}
+	}
+	
+	entry := req.Playbook
+	if entry == "" {
+		entry = project.DefaultPlaybook
+	}
+	entry, err = s.projectEntry(ctx, project, entry, commit)
+	if err != nil {
+		return nil, err
+	}
+	
+	req.PlaybookID = 0
+	req.Revision = 0
+	req.ProjectID = project.ID
+	req.Playbook = entry
+	req.CommitSHA = commit
+	
+	execution := &PlaybookExecution{
+		ProjectID:    &project.ID,
+		Name:         project.Name,
+		PlaybookPath: entry,
+		CommitSHA:    commit,
+	}
+	if err := s.enqueuePlaybookExecution(userID, execution, req); err != nil {
+		return nil, err
+	}
+	
+	return execution, nil
+}
+
+// projectEntry 校验入口playbook是项目中存在的YAML文件，返回规范化后的相对路径；
+// 指定提交时检查该提交中的文件
+func (s *AnsibleService) projectEntry(ctx context.Context, project *Project, entry, commit string) (string, error) {
+	if strings.TrimSpace(entry) == "" {
+		return "", fmt.Errorf("%w: entry playbook is required", ErrInvalidProjectPath)
+	}
+	
+	entry, err := cleanProjectPath(entry, false)
+	if err != nil {
+		return "", err
+	}
+	if ext := path.Ext(entry); ext != ".yml" && ext != ".yaml" {
+		return "", fmt.Errorf("%w: entry playbook must be a .yml or .yaml file", ErrInvalidProjectPath)
+	}
+	
+	if commit != "" {
+		if !gitBlobExists(ctx, s.projectDir(project.ID), commit, entry) {
+			return "", fmt.Errorf("%w: %s at commit %s", ErrProjectFileNotFound, entry, commit)
+		}
+		return entry, nil
+	}
+	
+	info, err := os.Stat(filepath.Join(s.projectDir(project.ID), filepath.FromSlash(entry)))
+	if err != nil || !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%w: %s", ErrProjectFileNotFound, entry)
+	}
+	return entry, nil
+}
+
+// projectPlaybookForRun 获取项目执行使用的playbook，并在运行选项中设置项目目录和检出的提交
+func (s *AnsibleService) projectPlaybookForRun(ctx context.Context, projectID uint, entry, commit string, opts *RunOptions) (*Playbook, error) {
+	project, err := s.GetProject(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get project failed: %v", err)
+	}
+	
+	entry, err = s.projectEntry(ctx, project, entry, commit)
+	if err != nil {
+		return nil, err
+	}
+	
+	opts.ProjectDir = s.projectDir(project.ID)
+	opts.ProjectCommit = commit
+	return &Playbook{Name: project.Name, FileName: entry}, nil
+}
@@ -0,0 +1,258 @@
+package ansible
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultWorkers 未配置时的worker数量
+	defaultWorkers = 4
+	// jobPollInterval worker空闲时轮询队列的间隔
+	jobPollInterval = 5 * time.Second
+)
+
+// Start 恢复上次运行遗留的任务并启动worker
+func (s *AnsibleService) Start(workers int) error {
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	
+	if err := s.recoverJobs(); err != nil {
+		return fmt.Errorf("recover execution jobs failed: %v", err)
+	}
+	
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	
+	// 定时任务调度器，服务停止期间错过的执行按各自的补偿策略处理
+	s.wg.Add(1)
+	go s.scheduler()
+	
+	// 定期同步配置了自动同步间隔的git项目
+	s.wg.Add(1)
+	go s.repositorySyncer()
+	
+	// 工作流引擎，继续推进服务停止前未结束的工作流运行
+	s.wg.Add(1)
+	go s.workflowRunner()
+	
+	// 拒绝超过审批期限的执行，包括服务停止期间过期的
+	s.wg.Add(1)
+	go s.approvalExpirer()
+	
+	log.Printf("Ansible execution queue started with %d workers", workers)
+	return nil
+}
+
+// queuePriority 返回执行使用的队列优先级，只有管理员可以指定优先级，其他用户的执行按默认优先级排队
+func (s *AnsibleService) queuePriority(userID uint, priority int) (int, error) {
+	if priority == 0 {
+		return 0, nil
+	}
+	role, err := s.userRole(userID)
+	if err != nil {
+		return 0, err
+	}
+	if role != adminRole {
+		return 0, nil
+	}
+	return priority, nil
+}
+
+// enqueueExecution 在事务中为执行创建队列任务；涉及受保护对象时任务保持held直到审批通过，并记录提交审批
+func (s *AnsibleService) enqueueExecution(tx *gorm.DB, kind string, executionID, userID uint, protected string, priority int, req interface{}) error {
+	if protected == "" {
+		return s.enqueueJob(tx, kind, executionID, priority, JobStatusQueued, req)
+	}
+	
+	if err := recordApproval(tx, kind, executionID, ApprovalRequested, &userID, "protected targets: "+protected); err != nil {
+		return err
+	}
+	return s.enqueueJob(tx, kind, executionID, priority, JobStatusHeld, req)
+}
+
+// enqueueJob 在事务中创建执行队列任务
+func (s *AnsibleService) enqueueJob(tx *gorm.DB, kind string, executionID uint, priority int, status string, req interface{}) error {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshal execution request failed: %v", err)
+	}
+	
+	job := &ExecutionJob{
+		ExecutionType: kind,
+		ExecutionID:   executionID,
+		Priority:      priority,
+		Status:        status,
+		Payload:       string(payload),
+	}
+	if err := tx.Create(job).Error; err != nil {
+		return fmt.Errorf("create execution job failed: %v", err)
+	}
+	return nil
+}
+
+// notifyWorkers 通知空闲的worker有新任务
+func (s *AnsibleService) notifyWorkers() {
+	select {
+	case s.jobs <- struct{}{}:
+	default:
+	}
+}
+
+// worker 按优先级和入队顺序依次执行队列中的任务
+func (s *AnsibleService) worker() {
+	defer s.wg.Done()
+	
+	for {
+		if s.ctx.Err() != nil {
+			return
+		}
+	
+		job, err := s.claimNextJob()
+		if err != nil {
+			log.Printf("Claim execution job failed: %v", err)
+		}
+	
+		if job == nil {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-s.jobs:
+			case <-time.After(jobPollInterval):
+			}
+			continue
+		}
+	
+		// 可能还有其他等待的任务，唤醒下一个空闲worker
+		s.notifyWorkers()
+		s.runJob(job)
+	}
+}
+
+// claimNextJob 领取优先级最高、最早入队的任务，队列为空时返回nil
+func (s *AnsibleService) claimNextJob() (*ExecutionJob, error) {
+	for {
+		// 使用Find而不是First，避免空队列时记录not found日志
+		var jobs []ExecutionJob
+		err := s.db.Where("status = ?", JobStatusQueued).Order("priority DESC, id ASC").Limit(1).Find(&jobs).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(jobs) == 0 {
+			return nil, nil
+		}
+		job := jobs[0]
+	
+		// 条件更新保证同一任务只被一个worker领取
+		now := time.Now()
+		result := s.db.Model(&ExecutionJob{}).Where("id = ? AND status = ?", job.ID, JobStatusQueued).Updates(map[string]interface{}{
+			"status":     JobStatusRunning,
+			"started_at": &now,
+		})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			return &job, nil
+		}
+	}
+}
+
+// runJob 执行任务并将其标记为完成
+func (s *AnsibleService) runJob(job *ExecutionJob) {
+	defer func() {
+		now := time.Now()
+		s.db.Model(&ExecutionJob{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+			"status":      JobStatusDone,
+			"finished_at": &now,
+			"payload":     "",
+		})
+	}()
+	
+	switch job.ExecutionType {
+	case ExecutionTypeAdhoc:
+		var req AdhocExecutionRequest
+		if err := json.Unmarshal([]byte(job.Payload), &req); err != nil {
+			s.failJob(&AdhocExecution{}, job, err)
+			return
+		}
+		s.executeAdhocJob(job.ExecutionID, &req)
+	case ExecutionTypePlaybook:
+		var req PlaybookExecutionRequest
+		if err := json.Unmarshal([]byte(job.Payload), &req); err != nil {
+			s.failJob(&PlaybookExecution{}, job, err)
+			return
+		}
+		s.executePlaybookJob(job.ExecutionID, &req)
+	default:
+		log.Printf("Unsupported execution job type: %s", job.ExecutionType)
+	}
+}
+
+// failJob 任务数据无法解析时将执行标记为失败
+func (s *AnsibleService) failJob(model interface{}, job *ExecutionJob, err error) {
+	now := time.Now()
+	s.db.Model(model).Where("id = ? AND status = ?", job.ExecutionID, StatusPending).Updates(map[string]interface{}{
+		"status":       StatusFailed,
+		"error_output": fmt.Sprintf("decode execution request failed: %v", err),
+		"end_time":     &now,
+	})
+	s.outputs.Close(outputKey(job.ExecutionType, job.ExecutionID), StatusFailed, 0)
+	s.notifyWorkflows()
+}
+
+// recoverJobs 处理上次运行遗留的任务：运行中的执行标记为interrupted，等待中的任务重新排队
+func (s *AnsibleService) recoverJobs() error {
+	now := time.Now()
+	
+	kinds := map[string]interface{}{
+		ExecutionTypeAdhoc:    &AdhocExecution{},
+		ExecutionTypePlaybook: &PlaybookExecution{},
+	}
+	for kind, model := range kinds {
+		if err := s.db.Model(model).Where("status = ?", StatusRunning).Updates(map[string]interface{}{
+			"status":   StatusInterrupted,
+			"end_time": &now,
+		}).Error; err != nil {
+			return err
+		}
+	
+		// 没有排队任务的等待中执行无法再被执行，同样标记为interrupted
+		queued := s.db.Model(&ExecutionJob{}).Select("execution_id").Where("execution_type = ? AND status = ?", kind, JobStatusQueued)
+		if err := s.db.Model(model).Where("status = ? AND id NOT IN (?)", StatusPending, queued).Updates(map[string]interface{}{
+			"status":   StatusInterrupted,
+			"end_time": &now,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	
+	if err := s.db.Model(&ExecutionJob{}).Where("status = ?", JobStatusRunning).Updates(map[string]interface{}{
+		"status":      JobStatusDone,
+		"finished_at": &now,
+		"payload":     "",
+	}).Error; err != nil {
+		return err
+	}
+	
+	// 为仍在排队和等待审批的任务重新创建输出流
+	var jobs []ExecutionJob
+	if err := s.db.Where("status IN ?", []string{JobStatusQueued, JobStatusHeld}).Find(&jobs).Error; err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		s.outputs.Open(outputKey(job.ExecutionType, job.ExecutionID))
+	}
+	
+	if len(jobs) > 0 {
+		log.Printf("Requeued %d pending ansible executions", len(jobs))
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package ansible
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrExecutionNotFinished = errors.New("execution has not finished")
+	ErrNoFailedHosts        = errors.New("no failed or unreachable hosts to relaunch")
+)
+
+// RelaunchAdhocExecution 按原执行的模块、参数、inventory快照和额外变量重新执行adhoc命令，
+// failed_hosts模式只对原执行中失败或不可达的主机执行
+func (s *AnsibleService) RelaunchAdhocExecution(ctx context.Context, id uint, userID uint, req *ExecutionRelaunchRequest) (*AdhocExecution, error) {
+	var original AdhocExecution
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&original).Error; err != nil {
+		return nil, err
+	}
+	
+	mode, limit, err := s.relaunchLimit(ExecutionTypeAdhoc, original.ID, original.Status, original.Limit, req.Mode)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := relaunchInventory(original.InventoryID, original.Inventory, original.DynamicInventory)
+	if err != nil {
+		return nil, err
+	}
+	extraVars, _, err := s.relaunchExtraVars(original.ExtraVars, "")
+	if err != nil {
+		return nil, err
+	}
+	
+	return s.ExecuteAdhocCommand(ctx, userID, &AdhocExecutionRequest{
+		Module:         original.Module,
+		Args:           original.Args,
+		Hosts:          original.Hosts,
+		Limit:          limit,
+		ExtraVars:      extraVars,
+		TimeoutSeconds: original.TimeoutSeconds,
+		Check:          original.DryRun,
+		Diff:           original.DiffMode,
+		VaultIDs:       splitVaultIDs(original.VaultIDs),
+		Confirm:        req.Confirm,
+		RelaunchOfID:   &original.ID,
+		RelaunchMode:   mode,
+		snapshot:       snapshot,
+	})
+}
+
+// RelaunchPlaybookExecution 按原执行的playbook版本或项目提交、inventory快照、额外变量和标签重新执行，
+// failed_hosts模式只对原执行中失败或不可达的主机执行
+func (s *AnsibleService) RelaunchPlaybookExecution(ctx context.Context, id uint, userID uint, req *ExecutionRelaunchRequest) (*PlaybookExecution, error) {
+	var original PlaybookExecution
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&original).Error; err != nil {
+		return nil, err
+	}
+	
+	mode, limit, err := s.relaunchLimit(ExecutionTypePlaybook, original.ID, original.Status, original.Limit, req.Mode)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := relaunchInventory(original.InventoryID, original.Inventory, original.DynamicInventory)
+	if err != nil {
+		return nil, err
+	}
+	extraVars, secretVars, err := s.relaunchExtraVars(original.ExtraVars, original.SealedVars)
+	if err != nil {
+		return nil, err
+	}
+	
+	relaunch := &PlaybookExecutionRequest{
+		ExtraVars:      extraVars,
+		Tags:           original.Tags,
+		SkipTags:       original.SkipTags,
+		Limit:          limit,
+		TimeoutSeconds: original.TimeoutSeconds,
+		Check:          original.DryRun,
+		Diff:           original.DiffMode,
+		VaultIDs:       splitVaultIDs(original.VaultIDs),
+		JobTemplateID:  original.JobTemplateID,
+		SecretVars:     secretVars,
+		RelaunchOfID:   &original.ID,
+		RelaunchMode:   mode,
+		snapshot:       snapshot,
+	}
+	if original.SurveyAnswers != "" {
+		if err := json.Unmarshal([]byte(original.SurveyAnswers), &relaunch.SurveyAnswers); err != nil {
+			return nil, fmt.Errorf("unmarshal survey answers failed: %v", err)
+		}
+	}
+	
+	if original.ProjectID != nil {
+		relaunch.Playbook = original.PlaybookPath
+		relaunch.CommitSHA = original.CommitSHA
+		return s.ExecuteProject(ctx, userID, *original.ProjectID, relaunch)
+	}
+	relaunch.Revision = original.PlaybookRevision
+	return s.ExecutePlaybook(ctx, userID, original.PlaybookID, relaunch)
+}
+
+// relaunchLimit 返回重新执行的模式和--limit参数，原执行必须已结束；
+// failed_hosts模式的主机取自原执行的结果，已在原limit范围内
+func (s *AnsibleService) relaunchLimit(kind string, id uint, status, limit, mode string) (string, string, error) {
+	if status == StatusPendingApproval || status == StatusPending || status == StatusRunning {
+		return "", "", ErrExecutionNotFinished
+	}
+	if mode == "" {
+		mode = RelaunchModeAll
+	}
+	if mode == RelaunchModeAll {
+		return mode, limit, nil
+	}
+	
+	hosts, err := s.failedHosts(kind, id)
+	if err != nil {
+		return "", "", err
+	}
+	if len(hosts) == 0 {
+		return "", "", ErrNoFailedHosts
+	}
+	return mode, strings.Join(hosts, ","), nil
+}
+
+// failedHosts 返回执行的汇总统计中失败或不可达的主机
+func (s *AnsibleService) failedHosts(kind string, id uint) ([]string, error) {
+	hosts := []string{}
+	err := s.db.Model(&HostRecap{}).
+		Where("execution_type = ? AND execution_id = ? AND (failed > 0 OR unreachable > 0)", kind, id).
+		Order("host ASC").Pluck("host", &hosts).Error
+	if err != nil {
+		return nil, fmt.Errorf("load host recap failed: %v", err)
+	}
+	return hosts, nil
+}
+
+// relaunchInventory 使用原执行的inventory快照；动态inventory的服务器凭据不保存，按原过滤条件重新生成。
+// 保留原inventory ID，执行时仍加载其group_vars和host_vars
+func relaunchInventory(inventoryID *uint, content, dynamic string) (*resolvedInventory, error) {
+	snapshot := &resolvedInventory{ID: inventoryID, Content: content}
+	if dynamic != "" {
+		filter, err := parseDynamicFilter(dynamic)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Content = ""
+		snapshot.Dynamic = filter
+	}
+	return snapshot, nil
+}
+
+// relaunchExtraVars 还原原执行的额外变量，解密保存的保密变量替换记录中的掩码，
+// 返回的保密变量名称在入队时重新加密
+func (s *AnsibleService) relaunchExtraVars(extraVarsJSON, sealedJSON string) (map[string]interface{}, []string, error) {
+	var extraVars map[string]interface{}
+	if extraVarsJSON != "" {
+		if err := json.Unmarshal([]byte(extraVarsJSON), &extraVars); err != nil {
+			return nil, nil, fmt.Errorf("unmarshal extra vars failed: %v", err)
+		}
+	}
+	if sealedJSON == "" {
+		return extraVars, nil, nil
+	}
+	
+	var sealed map[string]string
+	if err := json.Unmarshal([]byte(sealedJSON), &sealed); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal sealed vars failed: %v", err)
+	}
+	if extraVars == nil {
+		extraVars = make(map[string]interface{})
+	}
+	secretVars := sortedKeys(sealed)
+	for _, name := range secretVars {
+		value, err := s.openVaultPassword(sealed[name])
+		if err != nil {
+			return nil, nil, fmt.Errorf("decrypt extra var %s failed: %v", name, err)
+		}
+		extraVars[name] = value
+	}
+	return extraVars, secretVars, nil
+}
+
+// splitVaultIDs 拆分执行记录中逗号分隔的vault ID名称
+func splitVaultIDs(vaultIDs string) []string {
+	if vaultIDs == "" {
+		return nil
+	}
+	return strings.Split(vaultIDs, ",")
+}
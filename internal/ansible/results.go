@@ -0,0 +1,268 @@
+package ansible
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// eventsCallbackName 结构化结果回调插件名称
+	eventsCallbackName = "server_manager_events"
+	// eventsFileEnv 回调插件写入事件文件的环境变量
+	eventsFileEnv = "SERVER_MANAGER_EVENTS_FILE"
+	// maxEventLineSize 单条事件的最大长度
+	maxEventLineSize = 16 * 1024 * 1024
+)
+
+//go:embed callback_plugins/server_manager_events.py
+var eventsCallbackSource []byte
+
+// hostEvent 回调插件写入的事件，result和stats两种事件共用event字段
+type hostEvent struct {
+	Event string `json:"event"`
+	Host  string `json:"host"`
+}
+
+// hostResultEvent 单个任务结果事件
+type hostResultEvent struct {
+	Host    string `json:"host"`
+	Task    string `json:"task"`
+	Status  string `json:"status"`
+	Changed bool   `json:"changed"`
+	RC      *int   `json:"rc"`
+	Stdout  string `json:"stdout"`
+	Stderr  string `json:"stderr"`
+	Msg     string `json:"msg"`
+}
+
+// taskDiffEvent diff模式下的变更事件
+type taskDiffEvent struct {
+	Host         string `json:"host"`
+	Task         string `json:"task"`
+	BeforeHeader string `json:"before_header"`
+	AfterHeader  string `json:"after_header"`
+	Before       string `json:"before"`
+	After        string `json:"after"`
+	Prepared     string `json:"prepared"`
+}
+
+// artifactsEvent set_stats产生的执行产物事件
+type artifactsEvent struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+// callbackEvents 从事件文件解析出的结构化结果
+type callbackEvents struct {
+	HostResults []HostResult
+	Recap       []HostRecap
+	Diffs       []TaskDiff
+	Artifacts   map[string]interface{}
+}
+
+// hostStatsEvent 主机汇总统计事件
+type hostStatsEvent struct {
+	Host        string `json:"host"`
+	Ok          int    `json:"ok"`
+	Changed     int    `json:"changed"`
+	Failures    int    `json:"failures"`
+	Unreachable int    `json:"unreachable"`
+	Skipped     int    `json:"skipped"`
+	Rescued     int    `json:"rescued"`
+	Ignored     int    `json:"ignored"`
+}
+
+// installCallbackPlugin 将结构化结果回调插件写入临时目录，返回插件目录
+func installCallbackPlugin(tempDir string) string {
+	pluginDir := filepath.Join(tempDir, "callback_plugins")
+	// ansible按插件目录查找回调插件，使用绝对路径避免受工作目录影响
+	if abs, err := filepath.Abs(pluginDir); err == nil {
+		pluginDir = abs
+	}
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		log.Printf("Warning: create callback plugin dir failed: %v", err)
+		return pluginDir
+	}
+	
+	pluginFile := filepath.Join(pluginDir, eventsCallbackName+".py")
+	if err := os.WriteFile(pluginFile, eventsCallbackSource, 0644); err != nil {
+		log.Printf("Warning: write callback plugin failed: %v", err)
+	}
+	
+	return pluginDir
+}
+
+// parseEventsFile 解析回调插件写入的事件文件，返回主机任务结果、变更内容和汇总统计
+func parseEventsFile(path string) (*callbackEvents, error) {
+	events := &callbackEvents{}
+	
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return events, nil
+		}
+		return events, err
+	}
+	defer file.Close()
+	
+	// 无法解析的事件被跳过，不影响其余结果
+	var decodeErr error
+	
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxEventLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+	
+		var event hostEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			decodeErr = fmt.Errorf("decode event failed: %v", err)
+			continue
+		}
+	
+		switch event.Event {
+		case "result":
+			var e hostResultEvent
+			if err := json.Unmarshal(line, &e); err != nil {
+				decodeErr = fmt.Errorf("decode result event failed: %v", err)
+				continue
+			}
+			events.HostResults = append(events.HostResults, HostResult{
+				Host:    e.Host,
+				Task:    e.Task,
+				Status:  e.Status,
+				Changed: e.Changed,
+				RC:      e.RC,
+				Stdout:  e.Stdout,
+				Stderr:  e.Stderr,
+				Msg:     e.Msg,
+			})
+		case "stats":
+			var e hostStatsEvent
+			if err := json.Unmarshal(line, &e); err != nil {
+				decodeErr = fmt.Errorf("decode stats event failed: %v", err)
+				continue
+			}
+			events.Recap = append(events.Recap, HostRecap{
+				Host:        e.Host,
+				Ok:          e.Ok,
+				Changed:     e.Changed,
+				Failed:      e.Failures,
+				Unreachable: e.Unreachable,
+				Skipped:     e.Skipped,
+				Rescued:     e.Rescued,
+				Ignored:     e.Ignored,
+			})
+		case "diff":
+			var e taskDiffEvent
+			if err := json.Unmarshal(line, &e); err != nil {
+				decodeErr = fmt.Errorf("decode diff event failed: %v", err)
+				continue
+			}
+			events.Diffs = append(events.Diffs, TaskDiff{
+				Host:         e.Host,
+				Task:         e.Task,
+				BeforeHeader: e.BeforeHeader,
+				AfterHeader:  e.AfterHeader,
+				Before:       e.Before,
+				After:        e.After,
+				Prepared:     e.Prepared,
+			})
+		case "artifacts":
+			var e artifactsEvent
+			if err := json.Unmarshal(line, &e); err != nil {
+				decodeErr = fmt.Errorf("decode artifacts event failed: %v", err)
+				continue
+			}
+			events.Artifacts = e.Data
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return events, err
+	}
+	
+	// adhoc命令不一定产生汇总统计事件，此时根据任务结果计算
+	if len(events.Recap) == 0 && len(events.HostResults) > 0 {
+		events.Recap = summarizeResults(events.HostResults)
+	}
+	
+	return events, decodeErr
+}
+
+// summarizeResults 根据任务结果计算每个主机的汇总统计
+func summarizeResults(results []HostResult) []HostRecap {
+	byHost := make(map[string]*HostRecap)
+	var hosts []string
+	
+	for _, result := range results {
+		recap, exists := byHost[result.Host]
+		if !exists {
+			recap = &HostRecap{Host: result.Host}
+			byHost[result.Host] = recap
+			hosts = append(hosts, result.Host)
+		}
+	
+		switch result.Status {
+		case "ok":
+			recap.Ok++
+			if result.Changed {
+				recap.Changed++
+			}
+		case "failed":
+			recap.Failed++
+		case "ignored":
+			recap.Ignored++
+		case "unreachable":
+			recap.Unreachable++
+		case "skipped":
+			recap.Skipped++
+		}
+	}
+	
+	sort.Strings(hosts)
+	recap := make([]HostRecap, 0, len(hosts))
+	for _, host := range hosts {
+		recap = append(recap, *byHost[host])
+	}
+	return recap
+}
+
+// saveHostResults 保存执行的主机结果、变更内容和汇总统计
+func (s *AnsibleService) saveHostResults(kind string, id uint, result *ExecutionResult) {
+	if result == nil {
+		return
+	}
+	
+	for i := range result.HostResults {
+		result.HostResults[i].ExecutionType = kind
+		result.HostResults[i].ExecutionID = id
+	}
+	for i := range result.Recap {
+		result.Recap[i].ExecutionType = kind
+		result.Recap[i].ExecutionID = id
+	}
+	for i := range result.Diffs {
+		result.Diffs[i].ExecutionType = kind
+		result.Diffs[i].ExecutionID = id
+	}
+	
+	if len(result.HostResults) > 0 {
+		if err := s.db.CreateInBatches(result.HostResults, 100).Error; err != nil {
+			log.Printf("Save host results for %s execution %d failed: %v", kind, id, err)
+		}
+	}
+	if len(result.Recap) > 0 {
+		if err := s.db.CreateInBatches(result.Recap, 100).Error; err != nil {
+			log.Printf("Save host recap for %s execution %d failed: %v", kind, id, err)
+		}
+	}
+	if len(result.Diffs) > 0 {
+		if err := s.db.CreateInBatches(result.Diffs, 100).Error; err != nil {
+			log.Printf("Save task diffs for %s execution %d failed: %v", kind, id, err)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package ansible
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gorm.io/gorm"
+)
+
+// ErrRevisionNotFound playbook版本不存在
+var ErrRevisionNotFound = errors.New("playbook revision not found")
+
+// contentHash 计算playbook内容的SHA-256
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// createRevisionAttempts 并发创建版本时版本号冲突的最大尝试次数
+const createRevisionAttempts = 3
+
+// createRevision 在事务中为playbook当前内容创建新版本，并更新playbook的当前版本号；
+// 并发创建导致版本号唯一约束冲突时重新计算版本号
+func createRevision(tx *gorm.DB, playbook *Playbook, authorID uint, message string) (*PlaybookRevision, error) {
+	var revision *PlaybookRevision
+	var err error
+	for attempt := 1; attempt <= createRevisionAttempts; attempt++ {
+		// 嵌套事务使用savepoint，冲突时只回滚本次尝试
+		err = tx.Transaction(func(tx *gorm.DB) error {
+			var last int
+			if err := tx.Model(&PlaybookRevision{}).Where("playbook_id = ?", playbook.ID).
+				Select("COALESCE(MAX(revision), 0)").Scan(&last).Error; err != nil {
+				return fmt.Errorf("get latest revision failed: %v", err)
+			}
+	
+			revision = &PlaybookRevision{
+				PlaybookID:  playbook.ID,
+				Revision:    last + 1,
+				Content:     playbook.Content,
+				ContentHash: contentHash(playbook.Content),
+				Message:     message,
+				AuthorID:    authorID,
+			}
+			return tx.Create(revision).Error
+		})
+		if err == nil || !strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create playbook revision failed: %v", err)
+	}
+	
+	if err := tx.Model(playbook).UpdateColumn("revision", revision.Revision).Error; err != nil {
+		return nil, fmt.Errorf("update playbook revision failed: %v", err)
+	}
+	playbook.Revision = revision.Revision
+	
+	return revision, nil
+}
+
+// EnsurePlaybookRevisions 为启用版本管理前创建、还没有版本记录的playbook以当前内容创建第一个版本，启动时执行一次
+func (s *AnsibleService) EnsurePlaybookRevisions() error {
+	var playbooks []Playbook
+	if err := s.db.Where("revision = ?", 0).Find(&playbooks).Error; err != nil {
+		return fmt.Errorf("load playbooks without revisions failed: %v", err)
+	}
+	
+	for i := range playbooks {
+		playbook := &playbooks[i]
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			_, err := createRevision(tx, playbook, playbook.UserID, "Initial revision")
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveRevision 获取playbook的指定版本，版本号为0时使用当前版本
+func (s *AnsibleService) resolveRevision(playbook *Playbook, revision int) (*PlaybookRevision, error) {
+	if revision == 0 {
+		revision = playbook.Revision
+	}
+	
+	var result PlaybookRevision
+	err := s.db.Where("playbook_id = ? AND revision = ?", playbook.ID, revision).First(&result).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, fmt.Errorf("get playbook revision failed: %v", err)
+	}
+	return &result, nil
+}
+
+// ListPlaybookRevisions 列出playbook的版本，按版本号倒序，不包含内容
+func (s *AnsibleService) ListPlaybookRevisions(playbookID uint, offset, limit int) ([]PlaybookRevision, int64, error) {
+	if _, err := s.GetPlaybook(playbookID); err != nil {
+		return nil, 0, err
+	}
+	
+	var revisions []PlaybookRevision
+	var total int64
+	
+	query := s.db.Model(&PlaybookRevision{}).Where("playbook_id = ?", playbookID)
+	
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	// 获取分页数据
+	err := query.Omit("content").Order("revision DESC").Offset(offset).Limit(limit).Find(&revisions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	return revisions, total, nil
+}
+
+// GetPlaybookRevision 获取playbook指定版本的内容
+func (s *AnsibleService) GetPlaybookRevision(playbookID uint, revision int) (*PlaybookRevision, error) {
+	playbook, err := s.GetPlaybook(playbookID)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolveRevision(playbook, revision)
+}
+
+// DiffPlaybookRevisions 比较playbook的两个版本，to为0时使用当前版本，from为0时使用to的上一个版本
+func (s *AnsibleService) DiffPlaybookRevisions(playbookID uint, from, to int) (*PlaybookRevisionDiff, error) {
+	playbook, err := s.GetPlaybook(playbookID)
+	if err != nil {
+		return nil, err
+	}
+	
+	toRevision, err := s.resolveRevision(playbook, to)
+	if err != nil {
+		return nil, err
+	}
+	
+	if from == 0 {
+		from = toRevision.Revision - 1
+	}
+	if from < 1 {
+		return nil, fmt.Errorf("%w: revision %d has no previous revision", ErrRevisionNotFound, toRevision.Revision)
+	}
+	fromRevision, err := s.resolveRevision(playbook, from)
+	if err != nil {
+		return nil, err
+	}
+	
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(fromRevision.Content),
+		B:        difflib.SplitLines(toRevision.Content),
+		FromFile: fmt.Sprintf("%s@%d", playbook.FileName, fromRevision.Revision),
+		ToFile:   fmt.Sprintf("%s@%d", playbook.FileName, toRevision.Revision),
+		Context:  3,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("diff playbook revisions failed: %v", err)
+	}
+	
+	return &PlaybookRevisionDiff{
+		PlaybookID: playbook.ID,
+		From:       fromRevision.Revision,
+		To:         toRevision.Revision,
+		FromHash:   fromRevision.ContentHash,
+		ToHash:     toRevision.ContentHash,
+		Diff:       diff,
+	}, nil
+}
+
+// RestorePlaybookRevision 将playbook内容恢复为历史版本，恢复本身作为新版本保存，历史版本不会被修改
+func (s *AnsibleService) RestorePlaybookRevision(playbookID uint, revision int, userID uint, message string) (*Playbook, error) {
+	var playbook Playbook
+	if err := s.db.Where("id = ? AND user_id = ?", playbookID, userID).First(&playbook).Error; err != nil {
+		return nil, err
+	}
+	
+	if revision < 1 {
+		return nil, ErrRevisionNotFound
+	}
+	target, err := s.resolveRevision(&playbook, revision)
+	if err != nil {
+		return nil, err
+	}
+	
+	if message == "" {
+		message = fmt.Sprintf("Restore revision %d", target.Revision)
+	}
+	
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		playbook.Content = target.Content
+		if err := tx.Save(&playbook).Error; err != nil {
+			return err
+		}
+		_, err := createRevision(tx, &playbook, userID, message)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return &playbook, nil
+}
+
+// playbookForRun 获取执行使用的playbook，内容替换为指定版本的内容
+func (s *AnsibleService) playbookForRun(playbookID uint, revision int) (*Playbook, *PlaybookRevision, error) {
+	playbook, err := s.GetPlaybook(playbookID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get playbook failed: %w", err)
+	}
+	
+	rev, err := s.resolveRevision(playbook, revision)
+	if err != nil {
+		return nil, nil, err
+	}
+	
+	playbook.Content = rev.Content
+	return playbook, rev, nil
+}
@@ -0,0 +1,413 @@
+package ansible
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+const (
+	// schedulePollInterval 调度器检查到期定时任务的最长间隔
+	schedulePollInterval = time.Minute
+	// scheduleMisfireGrace 超过该时间仍未触发的执行视为错过，按补偿策略处理
+	scheduleMisfireGrace = time.Minute
+	// maxCatchUpRuns 补偿策略为all时单个定时任务一次最多补执行的次数
+	maxCatchUpRuns = 10
+)
+
+// cronParser 支持标准5段cron表达式和@daily、@every 1h等描述符
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// parseCronSchedule 解析cron表达式和时区，时区只能通过timezone字段指定
+func parseCronSchedule(expr, timezone string) (cron.Schedule, *time.Location, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "TZ=") || strings.HasPrefix(expr, "CRON_TZ=") {
+		return nil, nil, fmt.Errorf("%w: use the timezone field instead of a TZ prefix", ErrInvalidSchedule)
+	}
+	
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidSchedule, timezone)
+	}
+	
+	schedule, err := cronParser.Parse(expr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	return schedule, loc, nil
+}
+
+// nextScheduleRun 计算定时任务在after之后的下一次执行时间
+func nextScheduleRun(schedule *Schedule, after time.Time) (*time.Time, error) {
+	cronSchedule, loc, err := parseCronSchedule(schedule.CronExpression, schedule.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	
+	next := cronSchedule.Next(after.In(loc))
+	if next.IsZero() {
+		return nil, fmt.Errorf("%w: cron expression never fires", ErrInvalidSchedule)
+	}
+	next = next.UTC()
+	return &next, nil
+}
+
+// CreateSchedule 创建定时任务
+func (s *AnsibleService) CreateSchedule(userID uint, req *ScheduleRequest) (*Schedule, error) {
+	schedule := &Schedule{UserID: userID}
+	if err := s.applyScheduleRequest(schedule, req); err != nil {
+		return nil, err
+	}
+	
+	if err := s.db.Create(schedule).Error; err != nil {
+		return nil, err
+	}
+	
+	s.notifyScheduler()
+	return schedule, nil
+}
+
+// UpdateSchedule 更新定时任务，重新计算下一次执行时间
+func (s *AnsibleService) UpdateSchedule(id uint, userID uint, req *ScheduleRequest) (*Schedule, error) {
+	var schedule Schedule
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&schedule).Error; err != nil {
+		return nil, err
+	}
+	
+	if err := s.applyScheduleRequest(&schedule, req); err != nil {
+		return nil, err
+	}
+	
+	if err := s.db.Save(&schedule).Error; err != nil {
+		return nil, err
+	}
+	
+	s.notifyScheduler()
+	s.fillScheduleStatus(&schedule)
+	return &schedule, nil
+}
+
+// applyScheduleRequest 校验请求并写入定时任务字段
+func (s *AnsibleService) applyScheduleRequest(schedule *Schedule, req *ScheduleRequest) error {
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	if req.CatchUpPolicy == "" {
+		req.CatchUpPolicy = CatchUpSkip
+	}
+	if _, _, err := parseCronSchedule(req.CronExpression, req.Timezone); err != nil {
+		return err
+	}
+	
+	schedule.PlaybookID = nil
+	schedule.Module, schedule.Args, schedule.Hosts = "", "", ""
+	
+	switch req.Type {
+	case ExecutionTypeAdhoc:
+		if err := ValidateAdhocRequest(&AdhocExecutionRequest{Module: req.Module, Args: req.Args, Hosts: req.Hosts}); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+		}
+		// 定时执行时仍会检查，这里提前发现创建者无权使用的模块
+		if err := s.checkModuleAllowed(schedule.UserID, req.Module); err != nil {
+			return err
+		}
+		schedule.Module, schedule.Args, schedule.Hosts = req.Module, req.Args, req.Hosts
+	case ExecutionTypePlaybook:
+		if req.PlaybookID == 0 {
+			return fmt.Errorf("%w: playbook_id is required", ErrInvalidSchedule)
+		}
+		if _, err := s.GetPlaybook(req.PlaybookID); err != nil {
+			return fmt.Errorf("%w: playbook %d not found", ErrInvalidSchedule, req.PlaybookID)
+		}
+		playbookID := req.PlaybookID
+		schedule.PlaybookID = &playbookID
+	default:
+		return fmt.Errorf("%w: unsupported type %q", ErrInvalidSchedule, req.Type)
+	}
+	
+	// 定时执行以创建者身份进行，只能使用创建者自己的inventory
+	schedule.InventoryID = nil
+	if req.InventoryID != 0 {
+		if _, err := s.resolveInventory(schedule.UserID, req.InventoryID, "", "", nil); err != nil {
+			return fmt.Errorf("%w: inventory %d: %v", ErrInvalidSchedule, req.InventoryID, err)
+		}
+		inventoryID := req.InventoryID
+		schedule.InventoryID = &inventoryID
+	}
+	
+	schedule.ExtraVars = ""
+	if req.ExtraVars != nil {
+		extraVarsJSON, err := json.Marshal(req.ExtraVars)
+		if err != nil {
+			return fmt.Errorf("marshal extra vars failed: %v", err)
+		}
+		schedule.ExtraVars = string(extraVarsJSON)
+	}
+	
+	schedule.Name = req.Name
+	schedule.Description = req.Description
+	schedule.CronExpression = strings.TrimSpace(req.CronExpression)
+	schedule.Timezone = req.Timezone
+	schedule.Type = req.Type
+	schedule.CatchUpPolicy = req.CatchUpPolicy
+	schedule.Enabled = req.Enabled == nil || *req.Enabled
+	
+	// 未启用的定时任务不计算执行时间，重新启用时从当前时间开始计算，不补偿停用期间的执行
+	schedule.NextRunAt = nil
+	if schedule.Enabled {
+		next, err := nextScheduleRun(schedule, time.Now())
+		if err != nil {
+			return err
+		}
+		schedule.NextRunAt = next
+	}
+	
+	return nil
+}
+
+// DeleteSchedule 删除定时任务，已产生的执行记录保留
+func (s *AnsibleService) DeleteSchedule(id uint, userID uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&Schedule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	
+	s.notifyScheduler()
+	return nil
+}
+
+// GetSchedule 获取定时任务及最近一次执行的状态
+func (s *AnsibleService) GetSchedule(id uint) (*Schedule, error) {
+	var schedule Schedule
+	err := s.db.First(&schedule, id).Error
+	if err != nil {
+		return nil, err
+	}
+	s.fillScheduleStatus(&schedule)
+	return &schedule, nil
+}
+
+// ListSchedules 列出定时任务
+func (s *AnsibleService) ListSchedules(userID uint, offset, limit int) ([]Schedule, int64, error) {
+	var schedules []Schedule
+	var total int64
+	
+	query := s.db.Model(&Schedule{}).Where("user_id = ?", userID)
+	
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	// 获取分页数据
+	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&schedules).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	for i := range schedules {
+		s.fillScheduleStatus(&schedules[i])
+	}
+	
+	return schedules, total, nil
+}
+
+// fillScheduleStatus 从最近一次触发的执行记录读取执行结果
+func (s *AnsibleService) fillScheduleStatus(schedule *Schedule) {
+	if schedule.LastExecutionID == nil {
+		return
+	}
+	
+	model, err := executionModel(schedule.Type)
+	if err != nil {
+		return
+	}
+	
+	var status string
+	s.db.Model(model).Where("id = ?", *schedule.LastExecutionID).Select("status").Scan(&status)
+	schedule.LastStatus = status
+}
+
+// notifyScheduler 通知调度器定时任务有变化，重新计算等待时间
+func (s *AnsibleService) notifyScheduler() {
+	select {
+	case s.schedules <- struct{}{}:
+	default:
+	}
+}
+
+// scheduler 定时检查到期的定时任务并触发执行
+func (s *AnsibleService) scheduler() {
+	defer s.wg.Done()
+	
+	for {
+		wait := s.runDueSchedules(time.Now())
+	
+		timer := time.NewTimer(wait)
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-s.schedules:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// runDueSchedules 触发所有到期的定时任务，返回距离下一个定时任务到期的等待时间
+func (s *AnsibleService) runDueSchedules(now time.Time) time.Duration {
+	var due []Schedule
+	// 时间以UTC保存，sqlite按字符串比较时间
+	if err := s.db.Where("enabled = ? AND next_run_at <= ?", true, now.UTC()).Order("next_run_at ASC").Find(&due).Error; err != nil {
+		log.Printf("Load due schedules failed: %v", err)
+		return schedulePollInterval
+	}
+	
+	for i := range due {
+		if s.ctx.Err() != nil {
+			return schedulePollInterval
+		}
+		s.fireSchedule(&due[i], now)
+	}
+	
+	wait := schedulePollInterval
+	var next Schedule
+	err := s.db.Where("enabled = ? AND next_run_at IS NOT NULL", true).Order("next_run_at ASC").First(&next).Error
+	if err == nil {
+		if d := next.NextRunAt.Sub(time.Now()); d < wait {
+			wait = d
+		}
+	}
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait
+}
+
+// fireSchedule 按补偿策略触发到期的定时任务并计算下一次执行时间
+func (s *AnsibleService) fireSchedule(schedule *Schedule, now time.Time) {
+	cronSchedule, loc, err := parseCronSchedule(schedule.CronExpression, schedule.Timezone)
+	if err != nil {
+		// 无法解析的定时任务停止调度，避免反复触发
+		log.Printf("Schedule %d disabled: %v", schedule.ID, err)
+		s.db.Model(&Schedule{}).Where("id = ?", schedule.ID).Updates(map[string]interface{}{
+			"next_run_at": nil,
+			"last_error":  err.Error(),
+		})
+		return
+	}
+	
+	// 统计next_run_at到当前时间之间应执行的次数，超过宽限时间的视为错过
+	occurrences, late := 0, 0
+	for t := *schedule.NextRunAt; !t.After(now) && occurrences < maxCatchUpRuns; t = cronSchedule.Next(t.In(loc)) {
+		occurrences++
+		if now.Sub(t) > scheduleMisfireGrace {
+			late++
+		}
+	}
+	
+	runs := 1
+	if late > 0 {
+		switch schedule.CatchUpPolicy {
+		case CatchUpAll:
+			runs = occurrences
+		case CatchUpOnce:
+			runs = 1
+		default:
+			// 跳过错过的执行，只保留宽限时间内按时到期的一次
+			runs = 0
+			if occurrences > late {
+				runs = 1
+			}
+			log.Printf("Schedule %d skipped %d missed runs", schedule.ID, late)
+		}
+	}
+	
+	// 先更新下一次执行时间，触发失败也不会在下一轮重复触发
+	next := cronSchedule.Next(now.In(loc)).UTC()
+	updates := map[string]interface{}{"next_run_at": &next}
+	if next.IsZero() {
+		updates["next_run_at"] = nil
+	}
+	if err := s.db.Model(&Schedule{}).Where("id = ?", schedule.ID).Updates(updates).Error; err != nil {
+		log.Printf("Update schedule %d next run failed: %v", schedule.ID, err)
+		return
+	}
+	
+	for i := 0; i < runs; i++ {
+		updates := map[string]interface{}{"last_run_at": now.UTC()}
+	
+		executionID, err := s.triggerSchedule(schedule)
+		if err != nil {
+			log.Printf("Trigger schedule %d failed: %v", schedule.ID, err)
+			updates["last_execution_id"] = nil
+			updates["last_error"] = err.Error()
+		} else {
+			updates["last_execution_id"] = executionID
+			updates["last_error"] = ""
+		}
+	
+		s.db.Model(&Schedule{}).Where("id = ?", schedule.ID).Updates(updates)
+	}
+}
+
+// triggerSchedule 以定时任务创建者的身份提交一次执行，返回执行记录ID
+func (s *AnsibleService) triggerSchedule(schedule *Schedule) (uint, error) {
+	var extraVars map[string]interface{}
+	if schedule.ExtraVars != "" {
+		if err := json.Unmarshal([]byte(schedule.ExtraVars), &extraVars); err != nil {
+			return 0, fmt.Errorf("unmarshal extra vars failed: %v", err)
+		}
+	}
+	
+	var inventoryID uint
+	if schedule.InventoryID != nil {
+		inventoryID = *schedule.InventoryID
+	}
+	scheduleID := schedule.ID
+	
+	switch schedule.Type {
+	case ExecutionTypeAdhoc:
+		execution, err := s.ExecuteAdhocCommand(s.ctx, schedule.UserID, &AdhocExecutionRequest{
+			Module:      schedule.Module,
+			Args:        schedule.Args,
+			Hosts:       schedule.Hosts,
+			InventoryID: inventoryID,
+			ExtraVars:   extraVars,
+			ScheduleID:  &scheduleID,
+		})
+		if err != nil {
+			return 0, err
+		}
+		return execution.ID, nil
+	case ExecutionTypePlaybook:
+		if schedule.PlaybookID == nil {
+			return 0, errors.New("schedule has no playbook")
+		}
+		execution, err := s.ExecutePlaybook(s.ctx, schedule.UserID, *schedule.PlaybookID, &PlaybookExecutionRequest{
+			InventoryID: inventoryID,
+			ExtraVars:   extraVars,
+			ScheduleID:  &scheduleID,
+		})
+		if err != nil {
+			return 0, err
+		}
+		return execution.ID, nil
+	default:
+		return 0, fmt.Errorf("unsupported schedule type: %s", schedule.Type)
+	}
+}
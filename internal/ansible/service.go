@@ -3,11 +3,26 @@ package ansible
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrExecutionCancelled  = errors.New("execution cancelled")
+	ErrExecutionNotRunning = errors.New("execution is not running")
+	ErrServerShutdown      = errors.New("server shutting down")
+	ErrInvalidInventory    = errors.New("invalid inventory")
+	ErrInventoryNotFound   = errors.New("inventory not found")
+	ErrInvalidSchedule     = errors.New("invalid schedule")
+	ErrInvalidPlaybook     = errors.New("invalid playbook")
+)
+
 // Service 定义ansible服务接口
 type Service interface {
 	// Adhoc命令相关
@@ -15,6 +30,19 @@ type Service interface {
 	GetAdhocExecution(id uint) (*AdhocExecution, error)
 	ListAdhocExecutions(userID uint, offset, limit int) ([]AdhocExecution, int64, error)
 	
+	// Playbook执行相关
+	ExecutePlaybook(ctx context.Context, userID uint, playbookID uint, req *PlaybookExecutionRequest) (*PlaybookExecution, error)
+	GetPlaybookExecution(id uint) (*PlaybookExecution, error)
+	ListPlaybookExecutions(userID uint, offset, limit int) ([]PlaybookExecution, int64, error)
+	
+	// 执行输出与控制
+	StreamExecution(kind string, id uint) (*OutputSubscription, error)
+	CancelExecution(kind string, id uint, userID uint) error
+	ListExecutions(filter *ExecutionFilter, offset, limit int) ([]ExecutionSummary, int64, error)
+	ExportExecutions(filter *ExecutionFilter) ([]ExecutionSummary, error)
+	RelaunchAdhocExecution(ctx context.Context, id uint, userID uint, req *ExecutionRelaunchRequest) (*AdhocExecution, error)
+	RelaunchPlaybookExecution(ctx context.Context, id uint, userID uint, req *ExecutionRelaunchRequest) (*PlaybookExecution, error)
+	
 	// Inventory管理相关
 	CreateInventory(userID uint, req *InventoryRequest) (*Inventory, error)
 	UpdateInventory(id uint, userID uint, req *InventoryRequest) (*Inventory, error)
@@ -22,6 +50,12 @@ type Service interface {
 	GetInventory(id uint) (*Inventory, error)
 	ListInventories(userID uint, offset, limit int) ([]Inventory, int64, error)
 	GetDefaultInventory(userID uint) (*Inventory, error)
+	GetDynamicInventory(filter *DynamicInventoryFilter) (map[string]interface{}, error)
+	GetInventoryGraph(id uint, userID uint) (*InventoryGraph, error)
+	ListInventoryVars(inventoryID uint, kind string) ([]InventoryVars, error)
+	GetInventoryVars(inventoryID uint, kind, name string) (*InventoryVars, error)
+	SaveInventoryVars(inventoryID uint, userID uint, kind, name string, req *InventoryVarsRequest) (*InventoryVars, error)
+	DeleteInventoryVars(inventoryID uint, userID uint, kind, name string) error
 	
 	// Playbook管理相关
 	CreatePlaybook(userID uint, req *PlaybookRequest) (*Playbook, error)
@@ -29,44 +63,208 @@ type Service interface {
 	DeletePlaybook(id uint, userID uint) error
 	GetPlaybook(id uint) (*Playbook, error)
 	ListPlaybooks(userID uint, offset, limit int) ([]Playbook, int64, error)
+	ValidatePlaybook(ctx context.Context, content string) (*PlaybookValidationResult, error)
+	ListPlaybookRevisions(playbookID uint, offset, limit int) ([]PlaybookRevision, int64, error)
+	GetPlaybookRevision(playbookID uint, revision int) (*PlaybookRevision, error)
+	DiffPlaybookRevisions(playbookID uint, from, to int) (*PlaybookRevisionDiff, error)
+	RestorePlaybookRevision(playbookID uint, revision int, userID uint, message string) (*Playbook, error)
+	
+	// 项目相关
+	CreateProject(userID uint, req *ProjectRequest) (*Project, error)
+	UpdateProject(id uint, userID uint, req *ProjectRequest) (*Project, error)
+	DeleteProject(id uint, userID uint) error
+	GetProject(id uint) (*Project, error)
+	ListProjects(userID uint, offset, limit int) ([]Project, int64, error)
+	GetProjectTree(id uint) ([]ProjectFile, error)
+	ReadProjectFile(id uint, filePath string) (string, error)
+	WriteProjectFile(id uint, userID uint, filePath, content string) error
+	DeleteProjectFile(id uint, userID uint, filePath string) error
+	UploadProjectArchive(id uint, userID uint, archive io.Reader, replace bool) error
+	ListProjectPlaybooks(id uint) ([]string, error)
+	ExecuteProject(ctx context.Context, userID uint, projectID uint, req *PlaybookExecutionRequest) (*PlaybookExecution, error)
+	SyncProject(id uint, userID uint) (*Project, error)
+	
+	// Vault相关
+	CreateVaultSecret(userID uint, req *VaultSecretRequest) (*VaultSecret, error)
+	UpdateVaultSecret(id uint, userID uint, req *VaultSecretRequest) (*VaultSecret, error)
+	DeleteVaultSecret(id uint, userID uint) error
+	GetVaultSecret(id uint) (*VaultSecret, error)
+	ListVaultSecrets(userID uint, offset, limit int) ([]VaultSecret, int64, error)
+	EncryptVaultString(userID uint, req *VaultEncryptRequest) (*VaultEncryptResult, error)
+	DecryptVaultString(userID uint, req *VaultDecryptRequest) (*VaultDecryptResult, error)
+	EncryptVaultData(userID uint, vaultID string, data []byte) (string, error)
+	DecryptVaultData(userID uint, vaultID string, vaulttext string) ([]byte, string, error)
+	
+	// 定时任务相关
+	CreateSchedule(userID uint, req *ScheduleRequest) (*Schedule, error)
+	UpdateSchedule(id uint, userID uint, req *ScheduleRequest) (*Schedule, error)
+	DeleteSchedule(id uint, userID uint) error
+	GetSchedule(id uint) (*Schedule, error)
+	ListSchedules(userID uint, offset, limit int) ([]Schedule, int64, error)
+	
+	// 作业模板相关
+	CreateJobTemplate(userID uint, req *JobTemplateRequest) (*JobTemplate, error)
+	UpdateJobTemplate(id uint, userID uint, req *JobTemplateRequest) (*JobTemplate, error)
+	DeleteJobTemplate(id uint, userID uint) error
+	GetJobTemplate(id uint) (*JobTemplate, error)
+	ListJobTemplates(userID uint, offset, limit int) ([]JobTemplate, int64, error)
+	LaunchJobTemplate(ctx context.Context, id uint, userID uint, req *JobTemplateLaunchRequest) (*PlaybookExecution, error)
+	
+	// 模块允许列表相关
+	ListModules() ([]AnsibleModule, error)
+	CreateModule(req *AnsibleModuleRequest) (*AnsibleModule, error)
+	UpdateModule(id uint, req *AnsibleModuleRequest) (*AnsibleModule, error)
+	DeleteModule(id uint) error
+	ListModuleGrants() ([]ModuleGrant, error)
+	SaveModuleGrant(req *ModuleGrantRequest) (*ModuleGrant, error)
+	DeleteModuleGrant(id uint) error
+	AllowedModules(userID uint) ([]AnsibleModule, error)
+	
+	// 命令策略相关
+	ListPolicyRules() ([]PolicyRule, error)
+	CreatePolicyRule(req *PolicyRuleRequest) (*PolicyRule, error)
+	UpdatePolicyRule(id uint, req *PolicyRuleRequest) (*PolicyRule, error)
+	DeletePolicyRule(id uint) error
+	ListPolicyAudits(action string, userID uint, offset, limit int) ([]PolicyAudit, int64, error)
+	
+	// 审批相关
+	ListPendingApprovals(userID uint, offset, limit int) ([]ExecutionSummary, int64, error)
+	ApproveExecution(kind string, id uint, userID uint, comment string) error
+	RejectExecution(kind string, id uint, userID uint, comment string) error
+	ListProtectedTargets() ([]ProtectedTarget, error)
+	SetProtectedTarget(req *ProtectedTargetRequest) error
+	
+	// 工作流相关
+	CreateWorkflow(userID uint, req *WorkflowRequest) (*Workflow, error)
+	UpdateWorkflow(id uint, userID uint, req *WorkflowRequest) (*Workflow, error)
+	DeleteWorkflow(id uint, userID uint) error
+	GetWorkflow(id uint) (*Workflow, error)
+	ListWorkflows(userID uint, offset, limit int) ([]Workflow, int64, error)
+	LaunchWorkflow(id uint, userID uint, req *WorkflowLaunchRequest) (*WorkflowRun, error)
+	GetWorkflowRun(id uint) (*WorkflowRun, error)
+	ListWorkflowRuns(workflowID uint, userID uint, offset, limit int) ([]WorkflowRun, int64, error)
+	CancelWorkflowRun(id uint, userID uint) error
 	
 	// 统计信息
 	GetExecutionStats(userID uint) (*ExecutionStats, error)
 	
 	// 系统检查
 	CheckAnsibleInstallation() error
+	
+	// 生命周期
+	Start(workers int) error
+	Shutdown(ctx context.Context) error
 }
 
 // AnsibleService ansible服务实现
 type AnsibleService struct {
 	db       *gorm.DB
 	executor CommandExecutor
+	outputs  *OutputHub
+	projectsDir string // 项目文件根目录
+	vaultKey    []byte // 加密保存vault密码的AES密钥
+	approvalTimeout time.Duration // 等待审批的期限
+	
+	ctx     context.Context         // 服务生命周期上下文，关闭时取消所有执行
+	stop    context.CancelCauseFunc
+	jobs    chan struct{}           // 通知worker有新任务入队
+	schedules chan struct{}         // 通知调度器定时任务有变化
+	workflows chan struct{}         // 通知工作流引擎有执行结束或工作流运行变化
+	mu      sync.Mutex
+	syncMu  sync.Mutex                         // 串行执行git项目同步
+	workflowMu sync.Mutex                      // 串行推进和取消工作流运行
+	running map[string]context.CancelCauseFunc // 正在执行的任务，键为outputKey
+	wg      sync.WaitGroup                     // 跟踪worker协程，用于优雅关闭
 }
 
 // NewAnsibleService 创建新的ansible服务
 func NewAnsibleService(db *gorm.DB, executor CommandExecutor) *AnsibleService {
+	ctx, stop := context.WithCancelCause(context.Background())
+	
 	return &AnsibleService{
 		db:       db,
 		executor: executor,
+		outputs:  NewOutputHub(),
+		projectsDir: defaultProjectsDir,
+		ctx:      ctx,
+		stop:     stop,
+		jobs:     make(chan struct{}, 1),
+		schedules: make(chan struct{}, 1),
+		workflows: make(chan struct{}, 1),
+		running:  make(map[string]context.CancelCauseFunc),
 	}
 }
 
-// ExecuteAdhocCommand 执行adhoc命令
+// ExecuteAdhocCommand 执行adhoc命令，命令进入执行队列后由worker异步执行
 func (s *AnsibleService) ExecuteAdhocCommand(ctx context.Context, userID uint, req *AdhocExecutionRequest) (*AdhocExecution, error) {
 	// 验证请求参数
 	if err := ValidateAdhocRequest(req); err != nil {
 		return nil, fmt.Errorf("invalid request: %v", err)
 	}
+	if err := s.checkModuleAllowed(userID, req.Module); err != nil {
+		return nil, err
+	}
+	
+	// 解析inventory，执行时使用解析后的内容
+	inventory, err := s.inventoryForRun(userID, req.snapshot, req.InventoryID, req.InventoryName, req.Inventory, req.DynamicInventory)
+	if err != nil {
+		return nil, err
+	}
+	req.Inventory = inventory.Content
+	req.DynamicInventory = inventory.Dynamic
+	if inventory.ID != nil {
+		// 执行时按inventory ID加载group_vars和host_vars
+		req.InventoryID = *inventory.ID
+	}
+	
+	// 检查命令策略，匹配警告规则时需要确认令牌
+	confirmation, err := s.checkAdhocPolicy(userID, req, inventory)
+	if err != nil {
+		return nil, err
+	}
+	req.Confirm = ""
+	
+	// 涉及受保护对象的执行需要审批后才进入执行队列
+	protected, err := s.protectedTargets(inventory, req.Hosts, req.Limit, nil)
+	if err != nil {
+		return nil, err
+	}
+	
+	// 只保存vault ID名称，密码在执行时解密
+	req.VaultIDs, err = s.resolveVaultIDs(userID, req.VaultIDs)
+	if err != nil {
+		return nil, err
+	}
 	
 	// 创建执行记录
 	execution := &AdhocExecution{
-		Command:   fmt.Sprintf("ansible %s -m %s", req.Hosts, req.Module),
-		Module:    req.Module,
-		Args:      req.Args,
-		Inventory: req.Inventory,
-		Hosts:     req.Hosts,
-		Status:    "pending",
-		UserID:    userID,
+		Command:        fmt.Sprintf("ansible %s -m %s", req.Hosts, req.Module),
+		Module:         req.Module,
+		Args:           req.Args,
+		InventoryID:    inventory.ID,
+		Inventory:      req.Inventory,
+		Hosts:          req.Hosts,
+		Limit:          req.Limit,
+		Status:         StatusPending,
+		UserID:         userID,
+		TimeoutSeconds: req.TimeoutSeconds,
+		DryRun:         req.Check,
+		DiffMode:       req.Diff,
+		VaultIDs:       strings.Join(req.VaultIDs, ","),
+		ScheduleID:     req.ScheduleID,
+		WorkflowRunID:  req.WorkflowRunID,
+		RelaunchOfID:   req.RelaunchOfID,
+		RelaunchMode:   req.RelaunchMode,
+	}
+	execution.DynamicInventory, err = dynamicFilterJSON(inventory.Dynamic)
+	if err != nil {
+		return nil, err
+	}
+	if len(protected) > 0 {
+		execution.Status = StatusPendingApproval
+		execution.ApprovalStatus = ApprovalPending
+		execution.ProtectedTargets = strings.Join(protected, ",")
+		execution.ApprovalExpiresAt = s.approvalDeadline()
 	}
 	
 	// 处理额外变量
@@ -78,76 +276,231 @@ func (s *AnsibleService) ExecuteAdhocCommand(ctx context.Context, userID uint, r
 		execution.ExtraVars = string(extraVarsJSON)
 	}
 	
-	// 保存到数据库
-	if err := s.db.Create(execution).Error; err != nil {
-		return nil, fmt.Errorf("create execution record failed: %v", err)
+	priority, err := s.queuePriority(userID, req.Priority)
+	if err != nil {
+		return nil, err
+	}
+	
+	// 保存执行记录并加入执行队列
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(execution).Error; err != nil {
+			return fmt.Errorf("create execution record failed: %v", err)
+		}
+		if confirmation != nil {
+			if err := recordPolicyOverride(tx, confirmation, execution.ID); err != nil {
+				return err
+			}
+		}
+		return s.enqueueExecution(tx, ExecutionTypeAdhoc, execution.ID, userID, execution.ProtectedTargets, priority, req)
+	})
+	if err != nil {
+		return nil, err
 	}
 	
-	// 异步执行命令
-	go s.executeAdhocAsync(ctx, execution, req)
+	// 输出流在入队时创建，观察者可以在任务开始前连接
+	s.outputs.Open(outputKey(ExecutionTypeAdhoc, execution.ID))
+	s.notifyWorkers()
 	
 	return execution, nil
 }
 
-// executeAdhocAsync 异步执行adhoc命令
-func (s *AnsibleService) executeAdhocAsync(ctx context.Context, execution *AdhocExecution, req *AdhocExecutionRequest) {
-	// 更新状态为运行中
+// executeAdhocJob 执行队列中的adhoc命令
+func (s *AnsibleService) executeAdhocJob(id uint, req *AdhocExecutionRequest) {
+	key := outputKey(ExecutionTypeAdhoc, id)
+	
+	// 更新状态为运行中，排队期间已被取消的任务不再启动
+	startTime := time.Now()
+	if !s.markExecutionRunning(&AdhocExecution{}, id, startTime) {
+		return
+	}
+	
+	ctx := s.startExecution(key)
+	
+	var result *ExecutionResult
+	opts := s.runOptions(key)
+	err := s.applyDynamicInventory(&AdhocExecution{}, id, req.DynamicInventory, &req.Inventory, opts)
+	if err == nil {
+		err = s.applyVaultPasswords(req.VaultIDs, opts)
+	}
+	if err == nil {
+		err = s.applyInventoryVars(req.InventoryID, opts)
+	}
+	if err == nil {
+		result, err = s.executor.ExecuteAdhoc(ctx, req, opts)
+	}
+	s.saveHostResults(ExecutionTypeAdhoc, id, result)
+	
+	s.finishExecution(&AdhocExecution{}, key, id, buildResultUpdates(ctx, startTime, result, err))
+}
+
+// executePlaybookJob 执行队列中的playbook
+func (s *AnsibleService) executePlaybookJob(id uint, req *PlaybookExecutionRequest) {
+	key := outputKey(ExecutionTypePlaybook, id)
+	
+	// 更新状态为运行中，排队期间已被取消的任务不再启动
 	startTime := time.Now()
-	s.updateExecutionStatus(execution.ID, "running", &startTime, nil)
+	if !s.markExecutionRunning(&PlaybookExecution{}, id, startTime) {
+		return
+	}
+	
+	ctx := s.startExecution(key)
+	
+	// 排队期间playbook或项目可能已被删除
+	var result *ExecutionResult
+	var playbook *Playbook
+	var err error
+	opts := s.runOptions(key)
+	if req.ProjectID != 0 {
+		playbook, err = s.projectPlaybookForRun(ctx, req.ProjectID, req.Playbook, req.CommitSHA, opts)
+	} else {
+		playbook, _, err = s.playbookForRun(req.PlaybookID, req.Revision)
+	}
+	if err == nil {
+		err = s.applyDynamicInventory(&PlaybookExecution{}, id, req.DynamicInventory, &req.Inventory, opts)
+	}
+	if err == nil {
+		err = s.applyVaultPasswords(req.VaultIDs, opts)
+	}
+	if err == nil {
+		err = s.applyInventoryVars(req.InventoryID, opts)
+	}
+	if err == nil {
+		err = s.applySealedVars(req, opts)
+	}
+	if err == nil {
+		result, err = s.executor.ExecutePlaybook(ctx, playbook, req, opts)
+	}
+	s.saveHostResults(ExecutionTypePlaybook, id, result)
+	
+	s.finishExecution(&PlaybookExecution{}, key, id, buildResultUpdates(ctx, startTime, result, err))
+}
+
+// applyDynamicInventory 执行前根据受管服务器生成inventory，并将生成的内容保存到执行记录
+// 服务器凭据只通过运行选项传给执行器，不会写入执行记录
+func (s *AnsibleService) applyDynamicInventory(model interface{}, id uint, filter *DynamicInventoryFilter, inventory *string, opts *RunOptions) error {
+	if filter == nil {
+		return nil
+	}
+	
+	content, credentials, err := s.renderDynamicInventory(filter)
+	if err != nil {
+		return fmt.Errorf("build dynamic inventory failed: %v", err)
+	}
+	*inventory = content
+	opts.Credentials = credentials
+	
+	s.db.Model(model).Where("id = ?", id).Update("inventory", content)
+	return nil
+}
+
+// markExecutionRunning 将等待中的执行标记为运行中，执行已不处于等待状态时返回false
+func (s *AnsibleService) markExecutionRunning(model interface{}, id uint, startTime time.Time) bool {
+	result := s.db.Model(model).Where("id = ? AND status = ?", id, StatusPending).Updates(map[string]interface{}{
+		"status":     StatusRunning,
+		"start_time": &startTime,
+	})
+	return result.Error == nil && result.RowsAffected > 0
+}
+
+// startExecution 登记正在运行的执行，返回可被CancelExecution或服务关闭取消的上下文
+func (s *AnsibleService) startExecution(key string) context.Context {
+	runCtx, cancel := context.WithCancelCause(s.ctx)
+	
+	s.mu.Lock()
+	s.running[key] = cancel
+	s.mu.Unlock()
+	
+	return runCtx
+}
+
+// runOptions 构建将输出实时发布到输出流的运行选项
+func (s *AnsibleService) runOptions(key string) *RunOptions {
+	return &RunOptions{
+		Output: func(stream, line string) {
+			s.outputs.Publish(key, stream, line)
+		},
+	}
+}
+
+// finishExecution 保存执行结果并结束输出流
+func (s *AnsibleService) finishExecution(model interface{}, key string, id uint, updates map[string]interface{}) {
+	s.db.Model(model).Where("id = ?", id).Updates(updates)
 	
-	// 执行命令
-	result, err := s.executor.ExecuteAdhoc(ctx, req)
+	s.mu.Lock()
+	if cancel, ok := s.running[key]; ok {
+		cancel(nil)
+		delete(s.running, key)
+	}
+	s.mu.Unlock()
+	
+	status, _ := updates["status"].(string)
+	exitCode, _ := updates["exit_code"].(int)
+	s.outputs.Close(key, status, exitCode)
 	
+	// 执行可能属于某个工作流运行，由工作流引擎启动后续节点
+	s.notifyWorkflows()
+}
+
+// buildResultUpdates 根据执行结果构建执行记录的更新字段，被取消的执行保留已产生的部分输出
+func buildResultUpdates(ctx context.Context, startTime time.Time, result *ExecutionResult, err error) map[string]interface{} {
 	endTime := time.Now()
 	
-	// 更新执行结果
 	updates := map[string]interface{}{
 		"end_time":     &endTime,
 		"duration":     int(endTime.Sub(startTime).Seconds()),
 		"output":       "",
 		"error_output": "",
 		"exit_code":    0,
-		"status":       "failed",
+		"status":       StatusFailed,
 	}
 	
 	if err != nil {
 		updates["error_output"] = err.Error()
-		updates["status"] = "failed"
+		updates["status"] = StatusFailed
 	} else {
 		updates["output"] = result.Output
 		updates["error_output"] = result.ErrorOutput
 		updates["exit_code"] = result.ExitCode
+		if len(result.Artifacts) > 0 {
+			if artifacts, err := json.Marshal(result.Artifacts); err == nil {
+				updates["artifacts"] = string(artifacts)
+			}
+		}
 		if result.Success {
-			updates["status"] = "success"
+			updates["status"] = StatusSuccess
 		} else {
-			updates["status"] = "failed"
+			updates["status"] = StatusFailed
 		}
 	}
 	
-	s.db.Model(&AdhocExecution{}).Where("id = ?", execution.ID).Updates(updates)
-}
-
-// updateExecutionStatus 更新执行状态
-func (s *AnsibleService) updateExecutionStatus(id uint, status string, startTime, endTime *time.Time) {
-	updates := map[string]interface{}{
-		"status": status,
+	if result != nil && result.TimedOut {
+		updates["status"] = StatusTimedOut
+		updates["error_output"] = appendLine(result.ErrorOutput, ErrExecutionTimedOut.Error())
 	}
 	
-	if startTime != nil {
-		updates["start_time"] = startTime
+	switch cause := context.Cause(ctx); {
+	case errors.Is(cause, ErrExecutionCancelled):
+		updates["status"] = StatusCancelled
+	case errors.Is(cause, ErrServerShutdown):
+		updates["status"] = StatusInterrupted
+		updates["error_output"] = appendLine(updates["error_output"].(string), cause.Error())
 	}
 	
-	if endTime != nil {
-		updates["end_time"] = endTime
+	return updates
+}
+
+// appendLine 在文本末尾追加一行
+func appendLine(text, line string) string {
+	if text == "" {
+		return line
 	}
-	
-	s.db.Model(&AdhocExecution{}).Where("id = ?", id).Updates(updates)
+	return text + "\n" + line
 }
 
 // GetAdhocExecution 获取adhoc执行记录
 func (s *AnsibleService) GetAdhocExecution(id uint) (*AdhocExecution, error) {
 	var execution AdhocExecution
-	err := s.db.First(&execution, id).Error
+	err := s.db.Preload("Approvals").Preload("HostResults").Preload("Recap").Preload("Diffs").First(&execution, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -175,8 +528,298 @@ func (s *AnsibleService) ListAdhocExecutions(userID uint, offset, limit int) ([]
 	return executions, total, nil
 }
 
+// ExecutePlaybook 执行playbook，playbook进入执行队列后由worker异步执行
+func (s *AnsibleService) ExecutePlaybook(ctx context.Context, userID uint, playbookID uint, req *PlaybookExecutionRequest) (*PlaybookExecution, error) {
+	// 记录执行的版本，排队期间playbook被修改也执行提交时的版本
+	playbook, revision, err := s.playbookForRun(playbookID, req.Revision)
+	if err != nil {
+		return nil, err
+	}
+	// 项目字段只由ExecuteProject设置，清空请求中的值，避免worker执行其他项目中的文件
+	req.PlaybookID = playbook.ID
+	req.Revision = revision.Revision
+	req.ProjectID = 0
+	req.Playbook = ""
+	req.CommitSHA = ""
+	
+	execution := &PlaybookExecution{
+		PlaybookID:         playbook.ID,
+		Name:               playbook.Name,
+		PlaybookPath:       playbook.FileName,
+		PlaybookRevisionID: &revision.ID,
+		PlaybookRevision:   revision.Revision,
+		PlaybookHash:       revision.ContentHash,
+	}
+	if err := s.enqueuePlaybookExecution(userID, execution, req); err != nil {
+		return nil, err
+	}
+	
+	return execution, nil
+}
+
+// enqueuePlaybookExecution 解析inventory，保存playbook执行记录并加入执行队列
+func (s *AnsibleService) enqueuePlaybookExecution(userID uint, execution *PlaybookExecution, req *PlaybookExecutionRequest) error {
+	// 解析inventory，执行时使用解析后的内容
+	inventory, err := s.inventoryForRun(userID, req.snapshot, req.InventoryID, req.InventoryName, req.Inventory, req.DynamicInventory)
+	if err != nil {
+		return err
+	}
+	req.Inventory = inventory.Content
+	req.DynamicInventory = inventory.Dynamic
+	if inventory.ID != nil {
+		// 执行时按inventory ID加载group_vars和host_vars
+		req.InventoryID = *inventory.ID
+	}
+	
+	// 只保存vault ID名称，密码在执行时解密
+	req.VaultIDs, err = s.resolveVaultIDs(userID, req.VaultIDs)
+	if err != nil {
+		return err
+	}
+	
+	// 涉及受保护对象的执行需要审批后才进入执行队列，playbook的目标主机按inventory中的全部主机计算
+	protected, err := s.protectedTargets(inventory, inventoryAllGroup, req.Limit, execution)
+	if err != nil {
+		return err
+	}
+	if len(protected) > 0 {
+		execution.Status = StatusPendingApproval
+		execution.ApprovalStatus = ApprovalPending
+		execution.ProtectedTargets = strings.Join(protected, ",")
+		execution.ApprovalExpiresAt = s.approvalDeadline()
+	}
+	
+	// 补全执行记录
+	execution.InventoryID = inventory.ID
+	execution.Inventory = req.Inventory
+	execution.DynamicInventory, err = dynamicFilterJSON(inventory.Dynamic)
+	if err != nil {
+		return err
+	}
+	execution.Limit = req.Limit
+	execution.Tags = req.Tags
+	execution.SkipTags = req.SkipTags
+	if execution.Status == "" {
+		execution.Status = StatusPending
+	}
+	execution.UserID = userID
+	execution.TimeoutSeconds = req.TimeoutSeconds
+	execution.DryRun = req.Check
+	execution.DiffMode = req.Diff
+	execution.VaultIDs = strings.Join(req.VaultIDs, ",")
+	execution.ScheduleID = req.ScheduleID
+	execution.JobTemplateID = req.JobTemplateID
+	execution.WorkflowRunID = req.WorkflowRunID
+	execution.RelaunchOfID = req.RelaunchOfID
+	execution.RelaunchMode = req.RelaunchMode
+	
+	// 处理额外变量，保密变量加密后入队，执行记录中只保存掩码
+	extraVars, err := s.sealSecretVars(req)
+	if err != nil {
+		return err
+	}
+	if extraVars != nil {
+		extraVarsJSON, err := json.Marshal(extraVars)
+		if err != nil {
+			return fmt.Errorf("marshal extra vars failed: %v", err)
+		}
+		execution.ExtraVars = string(extraVarsJSON)
+	}
+	if len(req.SealedVars) > 0 {
+		sealedJSON, err := json.Marshal(req.SealedVars)
+		if err != nil {
+			return fmt.Errorf("marshal sealed vars failed: %v", err)
+		}
+		execution.SealedVars = string(sealedJSON)
+	}
+	if req.SurveyAnswers != nil {
+		answersJSON, err := json.Marshal(req.SurveyAnswers)
+		if err != nil {
+			return fmt.Errorf("marshal survey answers failed: %v", err)
+		}
+		execution.SurveyAnswers = string(answersJSON)
+	}
+	
+	priority, err := s.queuePriority(userID, req.Priority)
+	if err != nil {
+		return err
+	}
+	
+	// 保存执行记录并加入执行队列
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(execution).Error; err != nil {
+			return fmt.Errorf("create execution record failed: %v", err)
+		}
+		return s.enqueueExecution(tx, ExecutionTypePlaybook, execution.ID, userID, execution.ProtectedTargets, priority, req)
+	})
+	if err != nil {
+		return err
+	}
+	
+	// 输出流在入队时创建，观察者可以在任务开始前连接
+	s.outputs.Open(outputKey(ExecutionTypePlaybook, execution.ID))
+	s.notifyWorkers()
+	
+	return nil
+}
+
+// GetPlaybookExecution 获取playbook执行记录
+func (s *AnsibleService) GetPlaybookExecution(id uint) (*PlaybookExecution, error) {
+	var execution PlaybookExecution
+	err := s.db.Preload("Approvals").Preload("HostResults").Preload("Recap").Preload("Diffs").First(&execution, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
+
+// ListPlaybookExecutions 列出playbook执行记录
+func (s *AnsibleService) ListPlaybookExecutions(userID uint, offset, limit int) ([]PlaybookExecution, int64, error) {
+	var executions []PlaybookExecution
+	var total int64
+	
+	query := s.db.Model(&PlaybookExecution{}).Where("user_id = ?", userID)
+	
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	// 获取分页数据
+	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&executions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	return executions, total, nil
+}
+
+// StreamExecution 订阅执行的实时输出，已结束的执行从数据库记录回放
+func (s *AnsibleService) StreamExecution(kind string, id uint) (*OutputSubscription, error) {
+	if subscription, ok := s.outputs.Subscribe(outputKey(kind, id)); ok {
+		return subscription, nil
+	}
+	
+	var history []OutputEvent
+	switch kind {
+	case ExecutionTypeAdhoc:
+		execution, err := s.GetAdhocExecution(id)
+		if err != nil {
+			return nil, err
+		}
+		history = historyFromRecord(execution.Output, execution.ErrorOutput, execution.Status, execution.ExitCode, execution.EndTime)
+	case ExecutionTypePlaybook:
+		execution, err := s.GetPlaybookExecution(id)
+		if err != nil {
+			return nil, err
+		}
+		history = historyFromRecord(execution.Output, execution.ErrorOutput, execution.Status, execution.ExitCode, execution.EndTime)
+	default:
+		return nil, fmt.Errorf("unsupported execution type: %s", kind)
+	}
+	
+	events := make(chan OutputEvent)
+	close(events)
+	
+	return &OutputSubscription{History: history, Events: events, Close: func() {}}, nil
+}
+
+// CancelExecution 取消等待中或运行中的执行，记录取消人并保留已产生的输出；只有提交执行的用户和管理员可以取消
+func (s *AnsibleService) CancelExecution(kind string, id uint, userID uint) error {
+	model, err := executionModel(kind)
+	if err != nil {
+		return err
+	}
+	
+	var record struct {
+		Status string
+		UserID uint
+	}
+	if err := s.db.Model(model).Where("id = ?", id).Take(&record).Error; err != nil {
+		return err
+	}
+	if err := s.checkOwnerOrAdmin(record.UserID, userID); err != nil {
+		return err
+	}
+	
+	if record.Status != StatusPending && record.Status != StatusRunning && record.Status != StatusPendingApproval {
+		return ErrExecutionNotRunning
+	}
+	
+	now := time.Now()
+	if err := s.db.Model(model).Where("id = ?", id).Updates(map[string]interface{}{
+		"cancelled_by": userID,
+		"cancelled_at": &now,
+	}).Error; err != nil {
+		return err
+	}
+	
+	// 等待审批的执行直接结束审批并标记为已取消
+	if record.Status == StatusPendingApproval {
+		err := s.closeApproval(kind, id, &userID, ApprovalCancelled, "")
+		if !errors.Is(err, ErrApprovalNotPending) {
+			return err
+		}
+		// 已被审批，按普通执行取消
+	}
+	
+	key := outputKey(kind, id)
+	
+	// 仍在队列中的执行直接标记为已取消，worker不会再启动它
+	result := s.db.Model(model).Where("id = ? AND status = ?", id, StatusPending).Updates(map[string]interface{}{
+		"status":   StatusCancelled,
+		"end_time": &now,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		s.outputs.Close(key, StatusCancelled, 0)
+		s.notifyWorkflows()
+		return nil
+	}
+	
+	s.mu.Lock()
+	cancel, ok := s.running[key]
+	s.mu.Unlock()
+	
+	if ok {
+		// 由执行协程终止进程并保存最终状态
+		cancel(ErrExecutionCancelled)
+		return nil
+	}
+	
+	// 没有对应的执行协程(例如服务重启后遗留的记录)，直接标记为已取消
+	if err := s.db.Model(model).Where("id = ? AND status = ?", id, StatusRunning).Updates(map[string]interface{}{
+		"status":   StatusCancelled,
+		"end_time": &now,
+	}).Error; err != nil {
+		return err
+	}
+	s.outputs.Close(key, StatusCancelled, 0)
+	s.notifyWorkflows()
+	
+	return nil
+}
+
+// executionModel 根据执行类型返回对应的模型
+func executionModel(kind string) (interface{}, error) {
+	switch kind {
+	case ExecutionTypeAdhoc:
+		return &AdhocExecution{}, nil
+	case ExecutionTypePlaybook:
+		return &PlaybookExecution{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported execution type: %s", kind)
+	}
+}
+
 // CreateInventory 创建inventory
 func (s *AnsibleService) CreateInventory(userID uint, req *InventoryRequest) (*Inventory, error) {
+	if err := validateInventoryRequest(req); err != nil {
+		return nil, err
+	}
+	
 	// 如果设置为默认，需要先取消其他默认inventory
 	if req.IsDefault {
 		s.db.Model(&Inventory{}).Where("user_id = ? AND is_default = ?", userID, true).Update("is_default", false)
@@ -192,7 +835,7 @@ func (s *AnsibleService) CreateInventory(userID uint, req *InventoryRequest) (*I
 	}
 	
 	if inventory.Type == "" {
-		inventory.Type = "static"
+		inventory.Type = InventoryTypeStatic
 	}
 	
 	if err := s.db.Create(inventory).Error; err != nil {
@@ -204,6 +847,10 @@ func (s *AnsibleService) CreateInventory(userID uint, req *InventoryRequest) (*I
 
 // UpdateInventory 更新inventory
 func (s *AnsibleService) UpdateInventory(id uint, userID uint, req *InventoryRequest) (*Inventory, error) {
+	if err := validateInventoryRequest(req); err != nil {
+		return nil, err
+	}
+	
 	var inventory Inventory
 	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&inventory).Error; err != nil {
 		return nil, err
@@ -221,7 +868,7 @@ func (s *AnsibleService) UpdateInventory(id uint, userID uint, req *InventoryReq
 	inventory.IsDefault = req.IsDefault
 	
 	if inventory.Type == "" {
-		inventory.Type = "static"
+		inventory.Type = InventoryTypeStatic
 	}
 	
 	if err := s.db.Save(&inventory).Error; err != nil {
@@ -231,6 +878,26 @@ func (s *AnsibleService) UpdateInventory(id uint, userID uint, req *InventoryReq
 	return &inventory, nil
 }
 
+// validateInventoryRequest 校验inventory类型和内容
+func validateInventoryRequest(req *InventoryRequest) error {
+	switch req.Type {
+	case "", InventoryTypeStatic:
+		if strings.TrimSpace(req.Content) == "" {
+			return fmt.Errorf("%w: content is required for static inventory", ErrInvalidInventory)
+		}
+		if _, err := ParseInventory(req.Content); err != nil {
+			return err
+		}
+	case InventoryTypeDynamic:
+		if _, err := parseDynamicFilter(req.Content); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: unsupported inventory type: %s", ErrInvalidInventory, req.Type)
+	}
+	return nil
+}
+
 // DeleteInventory 删除inventory
 func (s *AnsibleService) DeleteInventory(id uint, userID uint) error {
 	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&Inventory{})
@@ -242,6 +909,11 @@ func (s *AnsibleService) DeleteInventory(id uint, userID uint) error {
 		return gorm.ErrRecordNotFound
 	}
 	
+	// 删除inventory的group_vars和host_vars
+	if err := s.db.Where("inventory_id = ?", id).Delete(&InventoryVars{}).Error; err != nil {
+		return fmt.Errorf("delete inventory vars failed: %v", err)
+	}
+	
 	return nil
 }
 
@@ -286,8 +958,117 @@ func (s *AnsibleService) GetDefaultInventory(userID uint) (*Inventory, error) {
 	return &inventory, nil
 }
 
+// resolvedInventory 执行请求解析后的inventory
+type resolvedInventory struct {
+	ID      *uint                   // 已保存的inventory ID
+	Content string                  // inventory内容，dynamic类型在执行时生成
+	Dynamic *DynamicInventoryFilter // 动态inventory过滤条件
+}
+
+// inventoryForRun 重新执行时使用原执行的inventory，否则解析请求中的inventory
+func (s *AnsibleService) inventoryForRun(userID uint, snapshot *resolvedInventory, id uint, name, content string, dynamic *DynamicInventoryFilter) (*resolvedInventory, error) {
+	if snapshot != nil {
+		return snapshot, nil
+	}
+	return s.resolveInventory(userID, id, name, content, dynamic)
+}
+
+// dynamicFilterJSON 序列化动态inventory过滤条件，保存到执行记录用于重新执行
+func dynamicFilterJSON(filter *DynamicInventoryFilter) (string, error) {
+	if filter == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(filter)
+	if err != nil {
+		return "", fmt.Errorf("marshal dynamic inventory failed: %v", err)
+	}
+	return string(data), nil
+}
+
+// resolveInventory 解析执行请求中的inventory，依次使用动态过滤条件、ID、名称、直接提供的内容，
+// 都未指定时使用用户的默认inventory；只能使用当前用户自己的inventory
+func (s *AnsibleService) resolveInventory(userID uint, id uint, name, content string, dynamic *DynamicInventoryFilter) (*resolvedInventory, error) {
+	if dynamic != nil {
+		return &resolvedInventory{Dynamic: dynamic}, nil
+	}
+	
+	// 兼容在inventory字段中直接传入ID
+	content = strings.TrimSpace(content)
+	if id == 0 && name == "" && content != "" {
+		if parsed, err := strconv.ParseUint(content, 10, 32); err == nil {
+			id = uint(parsed)
+		} else {
+			return &resolvedInventory{Content: content}, nil
+		}
+	}
+	
+	var inventory Inventory
+	query := s.db.Where("user_id = ?", userID)
+	switch {
+	case id > 0:
+		query = query.Where("id = ?", id)
+	case name != "":
+		query = query.Where("name = ?", name)
+	default:
+		query = query.Where("is_default = ?", true)
+	}
+	
+	if err := query.First(&inventory).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("get inventory failed: %v", err)
+		}
+		if id == 0 && name == "" {
+			return nil, fmt.Errorf("%w: no inventory specified and no default inventory configured", ErrInvalidInventory)
+		}
+		return nil, ErrInventoryNotFound
+	}
+	
+	resolved := &resolvedInventory{ID: &inventory.ID, Content: inventory.Content}
+	if inventory.Type == InventoryTypeDynamic {
+		filter, err := parseDynamicFilter(inventory.Content)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Content = ""
+		resolved.Dynamic = filter
+	}
+	
+	return resolved, nil
+}
+
+// GetInventoryGraph 解析inventory，返回组树、每个组的主机以及合并后的主机变量
+func (s *AnsibleService) GetInventoryGraph(id uint, userID uint) (*InventoryGraph, error) {
+	var inventory Inventory
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&inventory).Error; err != nil {
+		return nil, err
+	}
+	
+	content := inventory.Content
+	if inventory.Type == InventoryTypeDynamic {
+		filter, err := parseDynamicFilter(inventory.Content)
+		if err != nil {
+			return nil, err
+		}
+		dynamic, err := s.BuildDynamicInventory(filter)
+		if err != nil {
+			return nil, err
+		}
+		content = dynamic.INI()
+	}
+	
+	parsed, err := ParseInventory(content)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Graph(), nil
+}
+
 // CreatePlaybook 创建playbook
 func (s *AnsibleService) CreatePlaybook(userID uint, req *PlaybookRequest) (*Playbook, error) {
+	if err := s.validatePlaybook(s.ctx, req.Content); err != nil {
+		return nil, err
+	}
+	
 	playbook := &Playbook{
 		Name:        req.Name,
 		Description: req.Description,
@@ -297,7 +1078,15 @@ func (s *AnsibleService) CreatePlaybook(userID uint, req *PlaybookRequest) (*Pla
 		UserID:      userID,
 	}
 	
-	if err := s.db.Create(playbook).Error; err != nil {
+	// playbook和第一个版本在同一事务中创建
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(playbook).Error; err != nil {
+			return err
+		}
+		_, err := createRevision(tx, playbook, userID, req.Message)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
 	
@@ -311,13 +1100,25 @@ func (s *AnsibleService) UpdatePlaybook(id uint, userID uint, req *PlaybookReque
 		return nil, err
 	}
 	
+	if err := s.validatePlaybook(s.ctx, req.Content); err != nil {
+		return nil, err
+	}
+	
 	playbook.Name = req.Name
 	playbook.Description = req.Description
 	playbook.FileName = req.FileName
 	playbook.Content = req.Content
 	playbook.Tags = req.Tags
 	
-	if err := s.db.Save(&playbook).Error; err != nil {
+	// 每次保存都产生新版本，历史内容不会被覆盖
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&playbook).Error; err != nil {
+			return err
+		}
+		_, err := createRevision(tx, &playbook, userID, req.Message)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
 	
@@ -369,26 +1170,85 @@ func (s *AnsibleService) ListPlaybooks(userID uint, offset, limit int) ([]Playbo
 	return playbooks, total, nil
 }
 
-// GetExecutionStats 获取执行统计信息
+// GetExecutionStats 获取adhoc和playbook执行的统计信息
 func (s *AnsibleService) GetExecutionStats(userID uint) (*ExecutionStats, error) {
-	var stats ExecutionStats
+	stats := &ExecutionStats{ByType: make(map[string]ExecutionCounts)}
+	
+	for _, kind := range []string{ExecutionTypeAdhoc, ExecutionTypePlaybook} {
+		model, err := executionModel(kind)
+		if err != nil {
+			return nil, err
+		}
+		counts, err := s.executionCounts(model, userID)
+		if err != nil {
+			return nil, err
+		}
+		stats.ByType[kind] = *counts
+	
+		stats.TotalExecutions += counts.TotalExecutions
+		stats.SuccessfulExecutions += counts.SuccessfulExecutions
+		stats.FailedExecutions += counts.FailedExecutions
+		stats.RunningExecutions += counts.RunningExecutions
+		stats.DryRunExecutions += counts.DryRunExecutions
+	}
+	
+	return stats, nil
+}
+
+// executionCounts 统计用户一类执行的数量
+func (s *AnsibleService) executionCounts(model interface{}, userID uint) (*ExecutionCounts, error) {
+	var counts ExecutionCounts
+	query := func() *gorm.DB {
+		return s.db.Model(model).Where("user_id = ?", userID)
+	}
 	
 	// 获取总执行数
-	s.db.Model(&AdhocExecution{}).Where("user_id = ?", userID).Count(&stats.TotalExecutions)
+	if err := query().Count(&counts.TotalExecutions).Error; err != nil {
+		return nil, err
+	}
 	
-	// 获取成功执行数
-	s.db.Model(&AdhocExecution{}).Where("user_id = ? AND status = ?", userID, "success").Count(&stats.SuccessfulExecutions)
+	// 获取成功执行数，试运行不计入
+	if err := query().Where("status = ? AND dry_run = ?", StatusSuccess, false).Count(&counts.SuccessfulExecutions).Error; err != nil {
+		return nil, err
+	}
 	
 	// 获取失败执行数
-	s.db.Model(&AdhocExecution{}).Where("user_id = ? AND status = ?", userID, "failed").Count(&stats.FailedExecutions)
+	if err := query().Where("status = ?", StatusFailed).Count(&counts.FailedExecutions).Error; err != nil {
+		return nil, err
+	}
 	
 	// 获取运行中执行数
-	s.db.Model(&AdhocExecution{}).Where("user_id = ? AND status = ?", userID, "running").Count(&stats.RunningExecutions)
+	if err := query().Where("status = ?", StatusRunning).Count(&counts.RunningExecutions).Error; err != nil {
+		return nil, err
+	}
 	
-	return &stats, nil
+	// 获取试运行执行数
+	if err := query().Where("dry_run = ?", true).Count(&counts.DryRunExecutions).Error; err != nil {
+		return nil, err
+	}
+	
+	return &counts, nil
 }
 
 // CheckAnsibleInstallation 检查ansible安装
 func (s *AnsibleService) CheckAnsibleInstallation() error {
 	return s.executor.CheckAnsibleInstallation()
-}
\ No newline at end of file
+}
+
+// Shutdown 停止worker并取消所有正在执行的任务，等待其保存结果，ctx用于限制等待时间
+func (s *AnsibleService) Shutdown(ctx context.Context) error {
+	s.stop(ErrServerShutdown)
+	
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("wait for running executions failed: %v", ctx.Err())
+	}
+}
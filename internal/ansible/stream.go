@@ -0,0 +1,219 @@
+package ansible
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 输出事件类型
+	OutputEventLine    = "output"
+	OutputEventStatus  = "status"
+	OutputEventDropped = "dropped" // 订阅者消费过慢被断开，Seq为已推送的最后一个事件序号
+
+	// outputSubscriberBuffer 每个订阅者的事件缓冲区大小
+	outputSubscriberBuffer = 256
+	// outputRetention 执行结束后输出流在内存中保留的时间
+	outputRetention = 5 * time.Minute
+)
+
+// OutputEvent 表示一条实时输出事件
+type OutputEvent struct {
+	Seq      int       `json:"seq"`                 // 事件序号
+	Type     string    `json:"type"`                // output, status, dropped
+	Stream   string    `json:"stream,omitempty"`    // stdout, stderr
+	Line     string    `json:"line,omitempty"`      // 输出行内容
+	Status   string    `json:"status,omitempty"`    // 最终状态 (仅status事件)
+	ExitCode int       `json:"exit_code,omitempty"` // 退出码 (仅status事件)
+	Time     time.Time `json:"time"`
+}
+
+// OutputSubscription 表示一个输出订阅
+type OutputSubscription struct {
+	History []OutputEvent      // 订阅前已产生的事件
+	Events  <-chan OutputEvent // 后续事件，以status或dropped事件结束后关闭
+	Close   func()             // 取消订阅
+}
+
+// outputStream 单个执行的输出流
+type outputStream struct {
+	events      []OutputEvent
+	subscribers map[chan OutputEvent]struct{}
+	finished    bool
+}
+
+// OutputHub 管理执行输出的广播，支持多个观察者和迟到的观察者
+type OutputHub struct {
+	mu      sync.Mutex
+	streams map[string]*outputStream
+}
+
+// NewOutputHub 创建输出广播中心
+func NewOutputHub() *OutputHub {
+	return &OutputHub{
+		streams: make(map[string]*outputStream),
+	}
+}
+
+// outputKey 生成执行输出流的键
+func outputKey(kind string, id uint) string {
+	return fmt.Sprintf("%s:%d", kind, id)
+}
+
+// Open 为执行创建输出流
+func (h *OutputHub) Open(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	
+	if _, exists := h.streams[key]; !exists {
+		h.streams[key] = &outputStream{
+			subscribers: make(map[chan OutputEvent]struct{}),
+		}
+	}
+}
+
+// Publish 发布一行输出
+func (h *OutputHub) Publish(key, stream, line string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	
+	s, exists := h.streams[key]
+	if !exists || s.finished {
+		return
+	}
+	
+	h.broadcast(s, OutputEvent{
+		Type:   OutputEventLine,
+		Stream: stream,
+		Line:   line,
+	})
+}
+
+// Close 发布最终状态并结束输出流
+func (h *OutputHub) Close(key, status string, exitCode int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	
+	s, exists := h.streams[key]
+	if !exists || s.finished {
+		return
+	}
+	
+	h.broadcast(s, OutputEvent{
+		Type:     OutputEventStatus,
+		Status:   status,
+		ExitCode: exitCode,
+	})
+	
+	s.finished = true
+	for ch := range s.subscribers {
+		close(ch)
+	}
+	s.subscribers = nil
+	
+	// 保留一段时间供迟到的观察者读取，之后由数据库记录提供输出
+	time.AfterFunc(outputRetention, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.streams[key] == s {
+			delete(h.streams, key)
+		}
+	})
+}
+
+// Subscribe 订阅输出流，流不存在时返回false
+func (h *OutputHub) Subscribe(key string) (*OutputSubscription, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	
+	s, exists := h.streams[key]
+	if !exists {
+		return nil, false
+	}
+	
+	history := make([]OutputEvent, len(s.events))
+	copy(history, s.events)
+	
+	ch := make(chan OutputEvent, outputSubscriberBuffer)
+	if s.finished {
+		close(ch)
+		return &OutputSubscription{History: history, Events: ch, Close: func() {}}, true
+	}
+	
+	s.subscribers[ch] = struct{}{}
+	
+	return &OutputSubscription{
+		History: history,
+		Events:  ch,
+		Close: func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if _, ok := s.subscribers[ch]; ok {
+				delete(s.subscribers, ch)
+				close(ch)
+			}
+		},
+	}, true
+}
+
+// broadcast 记录事件并推送给所有订阅者，调用方需持有锁
+func (h *OutputHub) broadcast(s *outputStream, event OutputEvent) {
+	event.Seq = len(s.events) + 1
+	event.Time = time.Now()
+	s.events = append(s.events, event)
+	
+	for ch := range s.subscribers {
+		// 保留最后一个缓冲位置给dropped事件，订阅者总能收到结束事件
+		if len(ch) < cap(ch)-1 {
+			ch <- event
+			continue
+		}
+	
+		// 订阅者消费过慢，断开以免阻塞执行，客户端可重新连接获取完整历史
+		ch <- OutputEvent{
+			Seq:  event.Seq - 1,
+			Type: OutputEventDropped,
+			Time: event.Time,
+		}
+		delete(s.subscribers, ch)
+		close(ch)
+	}
+}
+
+// historyFromRecord 根据已保存的执行记录构建输出事件，用于内存中已无输出流的执行
+func historyFromRecord(output, errorOutput, status string, exitCode int, finishedAt *time.Time) []OutputEvent {
+	var events []OutputEvent
+	eventTime := time.Now()
+	if finishedAt != nil {
+		eventTime = *finishedAt
+	}
+	
+	appendLines := func(stream, text string) {
+		if text == "" {
+			return
+		}
+		for _, line := range strings.Split(text, "\n") {
+			events = append(events, OutputEvent{
+				Seq:    len(events) + 1,
+				Type:   OutputEventLine,
+				Stream: stream,
+				Line:   line,
+				Time:   eventTime,
+			})
+		}
+	}
+	appendLines("stdout", output)
+	appendLines("stderr", errorOutput)
+	
+	events = append(events, OutputEvent{
+		Seq:      len(events) + 1,
+		Type:     OutputEventStatus,
+		Status:   status,
+		ExitCode: exitCode,
+		Time:     eventTime,
+	})
+	
+	return events
+}
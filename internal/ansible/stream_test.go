@@ -0,0 +1,65 @@
+package ansible
+
+import "testing"
+
+// drainEvents 读取订阅的全部后续事件，直到通道关闭
+func drainEvents(sub *OutputSubscription) []OutputEvent {
+	var events []OutputEvent
+	for event := range sub.Events {
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestOutputHubEndsWithStatus(t *testing.T) {
+	hub := NewOutputHub()
+	hub.Open("adhoc:1")
+	sub, ok := hub.Subscribe("adhoc:1")
+	if !ok {
+		t.Fatal("Subscribe() returned false")
+	}
+	
+	hub.Publish("adhoc:1", "stdout", "line")
+	hub.Close("adhoc:1", StatusFailed, 2)
+	
+	events := drainEvents(sub)
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	last := events[len(events)-1]
+	if last.Type != OutputEventStatus || last.Status != StatusFailed || last.ExitCode != 2 || last.Seq != 2 {
+		t.Errorf("last event = %+v, want status failed with exit code 2 and seq 2", last)
+	}
+}
+
+func TestOutputHubSlowSubscriberDropped(t *testing.T) {
+	hub := NewOutputHub()
+	hub.Open("playbook:1")
+	slow, _ := hub.Subscribe("playbook:1")
+	
+	for i := 0; i < outputSubscriberBuffer*2; i++ {
+		hub.Publish("playbook:1", "stdout", "line")
+	}
+	hub.Close("playbook:1", StatusSuccess, 0)
+	
+	events := drainEvents(slow)
+	if len(events) != outputSubscriberBuffer {
+		t.Fatalf("got %d events, want %d", len(events), outputSubscriberBuffer)
+	}
+	last := events[len(events)-1]
+	if last.Type != OutputEventDropped {
+		t.Fatalf("last event type = %q, want %q", last.Type, OutputEventDropped)
+	}
+	if want := events[len(events)-2].Seq; last.Seq != want {
+		t.Errorf("dropped event seq = %d, want last delivered seq %d", last.Seq, want)
+	}
+	
+	// 重新订阅可以从历史中获取完整输出和最终状态
+	again, ok := hub.Subscribe("playbook:1")
+	if !ok {
+		t.Fatal("Subscribe() after close returned false")
+	}
+	if n := len(again.History); n != outputSubscriberBuffer*2+1 || again.History[n-1].Type != OutputEventStatus {
+		t.Errorf("history has %d events ending with %+v", n, again.History[n-1])
+	}
+}
@@ -0,0 +1,252 @@
+package ansible
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidJobTemplate   = errors.New("invalid job template")
+	ErrInvalidSurveyAnswers = errors.New("invalid survey answers")
+)
+
+// surveyPasswordMask 密码类型的默认值和答案在接口和执行记录中显示的掩码
+const surveyPasswordMask = "$encrypted$"
+
+// SurveyError 表示一个问卷答案的校验错误
+type SurveyError struct {
+	Variable string `json:"variable"`
+	Message  string `json:"message"`
+}
+
+// SurveyValidationError 问卷答案校验失败，包含全部错误
+type SurveyValidationError struct {
+	Errors []SurveyError
+}
+
+// Error 实现error接口
+func (e *SurveyValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, item := range e.Errors {
+		messages[i] = item.Variable + ": " + item.Message
+	}
+	return fmt.Sprintf("%v: %s", ErrInvalidSurveyAnswers, strings.Join(messages, "; "))
+}
+
+// Unwrap 使errors.Is(err, ErrInvalidSurveyAnswers)成立
+func (e *SurveyValidationError) Unwrap() error {
+	return ErrInvalidSurveyAnswers
+}
+
+// validateSurveySpec 校验问卷问题定义，默认值按问题类型校验并规范化
+func validateSurveySpec(questions []SurveyQuestion) error {
+	seen := make(map[string]bool)
+	for i := range questions {
+		q := &questions[i]
+		q.Variable = strings.TrimSpace(q.Variable)
+		if !ansibleVarName.MatchString(q.Variable) {
+			return fmt.Errorf("%w: survey question %d has an invalid variable name %q", ErrInvalidJobTemplate, i+1, q.Variable)
+		}
+		if seen[q.Variable] {
+			return fmt.Errorf("%w: survey variable %q is defined more than once", ErrInvalidJobTemplate, q.Variable)
+		}
+		seen[q.Variable] = true
+		if q.Label == "" {
+			q.Label = q.Variable
+		}
+	
+		switch q.Type {
+		case SurveyTypeString, SurveyTypePassword, SurveyTypeInteger:
+			if len(q.Choices) > 0 {
+				return fmt.Errorf("%w: survey variable %q: choices are only allowed for choice questions", ErrInvalidJobTemplate, q.Variable)
+			}
+			if q.Min != nil && q.Max != nil && *q.Min > *q.Max {
+				return fmt.Errorf("%w: survey variable %q: min is greater than max", ErrInvalidJobTemplate, q.Variable)
+			}
+			if q.Type != SurveyTypeInteger && ((q.Min != nil && *q.Min < 0) || (q.Max != nil && *q.Max < 0)) {
+				return fmt.Errorf("%w: survey variable %q: length limits must not be negative", ErrInvalidJobTemplate, q.Variable)
+			}
+		case SurveyTypeChoice, SurveyTypeMultiChoice:
+			if len(q.Choices) == 0 {
+				return fmt.Errorf("%w: survey variable %q: choices are required", ErrInvalidJobTemplate, q.Variable)
+			}
+			choices := make(map[string]bool)
+			for _, choice := range q.Choices {
+				if choice == "" || choices[choice] {
+					return fmt.Errorf("%w: survey variable %q: choices must be unique and not empty", ErrInvalidJobTemplate, q.Variable)
+				}
+				choices[choice] = true
+			}
+			if q.Min != nil || q.Max != nil {
+				return fmt.Errorf("%w: survey variable %q: min and max are not allowed for choice questions", ErrInvalidJobTemplate, q.Variable)
+			}
+		case SurveyTypeBoolean:
+			if len(q.Choices) > 0 || q.Min != nil || q.Max != nil {
+				return fmt.Errorf("%w: survey variable %q: choices, min and max are not allowed for boolean questions", ErrInvalidJobTemplate, q.Variable)
+			}
+		default:
+			return fmt.Errorf("%w: survey variable %q has an unsupported type %q", ErrInvalidJobTemplate, q.Variable, q.Type)
+		}
+	
+		if q.Default != nil {
+			value, message := validateSurveyAnswer(q, q.Default)
+			if message != "" {
+				return fmt.Errorf("%w: survey variable %q: default %s", ErrInvalidJobTemplate, q.Variable, message)
+			}
+			q.Default = value
+		}
+	}
+	return nil
+}
+
+// validateSurveyAnswers 按问卷校验答案，未回答的问题使用默认值；
+// 返回需要合并到额外变量的值，密码类型的默认值需要已解密
+func validateSurveyAnswers(questions []SurveyQuestion, answers map[string]interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	var errs []SurveyError
+	
+	known := make(map[string]bool)
+	for i := range questions {
+		q := &questions[i]
+		known[q.Variable] = true
+	
+		value, ok := answers[q.Variable]
+		if !ok || value == nil {
+			value = q.Default
+		}
+		if value == nil {
+			if q.Required {
+				errs = append(errs, SurveyError{Variable: q.Variable, Message: "answer is required"})
+			}
+			continue
+		}
+	
+		normalized, message := validateSurveyAnswer(q, value)
+		if message == "" && q.Required && surveyAnswerEmpty(normalized) {
+			message = "answer is required"
+		}
+		if message != "" {
+			errs = append(errs, SurveyError{Variable: q.Variable, Message: message})
+			continue
+		}
+		values[q.Variable] = normalized
+	}
+	
+	for _, name := range sortedKeys(answers) {
+		if !known[name] {
+			errs = append(errs, SurveyError{Variable: name, Message: "not defined in the survey"})
+		}
+	}
+	
+	if len(errs) > 0 {
+		return nil, &SurveyValidationError{Errors: errs}
+	}
+	return values, nil
+}
+
+// validateSurveyAnswer 按问题类型校验单个答案，返回规范化后的值或错误说明
+func validateSurveyAnswer(q *SurveyQuestion, value interface{}) (interface{}, string) {
+	switch q.Type {
+	case SurveyTypeString, SurveyTypePassword:
+		text, ok := value.(string)
+		if !ok {
+			return nil, "must be a string"
+		}
+		length := utf8.RuneCountInString(text)
+		if q.Min != nil && length < *q.Min {
+			return nil, fmt.Sprintf("must be at least %d characters", *q.Min)
+		}
+		if q.Max != nil && length > *q.Max {
+			return nil, fmt.Sprintf("must be at most %d characters", *q.Max)
+		}
+		return text, ""
+	case SurveyTypeInteger:
+		var n int64
+		switch v := value.(type) {
+		case float64:
+			// JSON数字解码为float64
+			if v != math.Trunc(v) || math.Abs(v) > 1<<53 {
+				return nil, "must be an integer"
+			}
+			n = int64(v)
+		case int:
+			n = int64(v)
+		case int64:
+			n = v
+		default:
+			return nil, "must be an integer"
+		}
+		if q.Min != nil && n < int64(*q.Min) {
+			return nil, fmt.Sprintf("must be at least %d", *q.Min)
+		}
+		if q.Max != nil && n > int64(*q.Max) {
+			return nil, fmt.Sprintf("must be at most %d", *q.Max)
+		}
+		return n, ""
+	case SurveyTypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, "must be a boolean"
+		}
+		return b, ""
+	case SurveyTypeChoice:
+		choice, ok := value.(string)
+		if !ok || !containsString(q.Choices, choice) {
+			return nil, "must be one of " + strings.Join(q.Choices, ", ")
+		}
+		return choice, ""
+	case SurveyTypeMultiChoice:
+		var items []interface{}
+		switch v := value.(type) {
+		case []interface{}:
+			items = v
+		case []string:
+			for _, item := range v {
+				items = append(items, item)
+			}
+		default:
+			return nil, "must be a list"
+		}
+		selected := []string{}
+		for _, item := range items {
+			choice, ok := item.(string)
+			if !ok || !containsString(q.Choices, choice) {
+				return nil, "items must be among " + strings.Join(q.Choices, ", ")
+			}
+			if !containsString(selected, choice) {
+				selected = append(selected, choice)
+			}
+		}
+		return selected, ""
+	default:
+		return nil, fmt.Sprintf("unsupported question type %q", q.Type)
+	}
+}
+
+// surveyAnswerEmpty 判断必答问题的答案是否为空
+func surveyAnswerEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return v == ""
+	case []string:
+		return len(v) == 0
+	}
+	return false
+}
+
+// maskSurveyAnswers 返回用于记录的答案，密码类型替换为掩码
+func maskSurveyAnswers(questions []SurveyQuestion, values map[string]interface{}) map[string]interface{} {
+	masked := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		masked[name] = value
+	}
+	for _, q := range questions {
+		if _, ok := masked[q.Variable]; ok && q.Type == SurveyTypePassword {
+			masked[q.Variable] = surveyPasswordMask
+		}
+	}
+	return masked
+}
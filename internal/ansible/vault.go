@@ -0,0 +1,313 @@
+package ansible
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrVaultSecretNotFound = errors.New("vault secret not found")
+	ErrInvalidVaultSecret  = errors.New("invalid vault secret")
+)
+
+const (
+	// sealedVaultPasswordPrefix 加密保存的vault密码格式版本
+	sealedVaultPasswordPrefix = "v1:"
+	// MaxVaultFileSize 加密和解密文件的最大大小
+	MaxVaultFileSize = 10 << 20
+)
+
+// vaultSecretName vault ID名称会写入密文文件头和--vault-id参数，只允许安全字符
+var vaultSecretName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// SetVaultKey 设置加密保存vault密码使用的密钥，实际的AES密钥为其SHA-256
+func (s *AnsibleService) SetVaultKey(key string) {
+	sum := sha256.Sum256([]byte(key))
+	s.vaultKey = sum[:]
+}
+
+// sealVaultPassword 使用AES-GCM加密vault密码
+func (s *AnsibleService) sealVaultPassword(password string) (string, error) {
+	gcm, err := s.vaultCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce failed: %v", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(password), nil)
+	return sealedVaultPasswordPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openVaultPassword 解密保存的vault密码
+func (s *AnsibleService) openVaultPassword(sealed string) (string, error) {
+	gcm, err := s.vaultCipher()
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(sealed, sealedVaultPasswordPrefix) {
+		return "", fmt.Errorf("unsupported vault password format")
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedVaultPasswordPrefix))
+	if err != nil || len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("malformed vault password")
+	}
+	password, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		// 密钥变更后无法解密已保存的密码
+		return "", fmt.Errorf("decrypt vault password failed, the vault key may have changed")
+	}
+	return string(password), nil
+}
+
+// vaultCipher 根据配置的密钥创建AES-GCM
+func (s *AnsibleService) vaultCipher() (cipher.AEAD, error) {
+	if len(s.vaultKey) == 0 {
+		return nil, fmt.Errorf("vault key is not configured")
+	}
+	block, err := aes.NewCipher(s.vaultKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// CreateVaultSecret 创建vault密码
+func (s *AnsibleService) CreateVaultSecret(userID uint, req *VaultSecretRequest) (*VaultSecret, error) {
+	if !vaultSecretName.MatchString(req.Name) {
+		return nil, fmt.Errorf("%w: name may only contain letters, digits, '_', '-' and '.'", ErrInvalidVaultSecret)
+	}
+	if req.Password == "" {
+		return nil, fmt.Errorf("%w: password is required", ErrInvalidVaultSecret)
+	}
+	
+	sealed, err := s.sealVaultPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+	
+	secret := &VaultSecret{
+		Name:              req.Name,
+		Description:       req.Description,
+		EncryptedPassword: sealed,
+		UserID:            userID,
+	}
+	if err := s.db.Create(secret).Error; err != nil {
+		return nil, err
+	}
+	
+	return secret, nil
+}
+
+// UpdateVaultSecret 更新vault密码，密码为空时保持不变
+func (s *AnsibleService) UpdateVaultSecret(id uint, userID uint, req *VaultSecretRequest) (*VaultSecret, error) {
+	var secret VaultSecret
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&secret).Error; err != nil {
+		return nil, err
+	}
+	
+	if !vaultSecretName.MatchString(req.Name) {
+		return nil, fmt.Errorf("%w: name may only contain letters, digits, '_', '-' and '.'", ErrInvalidVaultSecret)
+	}
+	
+	secret.Name = req.Name
+	secret.Description = req.Description
+	if req.Password != "" {
+		sealed, err := s.sealVaultPassword(req.Password)
+		if err != nil {
+			return nil, err
+		}
+		secret.EncryptedPassword = sealed
+	}
+	
+	if err := s.db.Save(&secret).Error; err != nil {
+		return nil, err
+	}
+	
+	return &secret, nil
+}
+
+// DeleteVaultSecret 删除vault密码，使用该密码排队中的执行将会失败
+func (s *AnsibleService) DeleteVaultSecret(id uint, userID uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&VaultSecret{})
+	if result.Error != nil {
+		return result.Error
+	}
+	
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	
+	return nil
+}
+
+// GetVaultSecret 获取vault密码信息，不包含密码
+func (s *AnsibleService) GetVaultSecret(id uint) (*VaultSecret, error) {
+	var secret VaultSecret
+	err := s.db.First(&secret, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// ListVaultSecrets 列出vault密码
+func (s *AnsibleService) ListVaultSecrets(userID uint, offset, limit int) ([]VaultSecret, int64, error) {
+	var secrets []VaultSecret
+	var total int64
+	
+	query := s.db.Model(&VaultSecret{}).Where("user_id = ?", userID)
+	
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	// 获取分页数据
+	err := query.Order("name ASC").Offset(offset).Limit(limit).Find(&secrets).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	return secrets, total, nil
+}
+
+// ownedVaultSecret 按名称获取当前用户的vault密码
+func (s *AnsibleService) ownedVaultSecret(userID uint, name string) (*VaultSecret, error) {
+	var secret VaultSecret
+	err := s.db.Where("name = ? AND user_id = ?", name, userID).First(&secret).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrVaultSecretNotFound, name)
+		}
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// EncryptVaultString 使用vault密码加密字符串，输出与ansible-vault encrypt_string兼容
+func (s *AnsibleService) EncryptVaultString(userID uint, req *VaultEncryptRequest) (*VaultEncryptResult, error) {
+	vaulttext, err := s.EncryptVaultData(userID, req.VaultID, []byte(req.Plaintext))
+	if err != nil {
+		return nil, err
+	}
+	return &VaultEncryptResult{Ciphertext: vaulttext, YAML: vaultYAML(req.Name, vaulttext)}, nil
+}
+
+// DecryptVaultString 解密vault字符串，支持直接粘贴!vault YAML片段
+func (s *AnsibleService) DecryptVaultString(userID uint, req *VaultDecryptRequest) (*VaultDecryptResult, error) {
+	plaintext, vaultID, err := s.DecryptVaultData(userID, req.VaultID, req.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return &VaultDecryptResult{VaultID: vaultID, Plaintext: string(plaintext)}, nil
+}
+
+// EncryptVaultData 使用vault密码加密数据，密文文件头中写入vault ID
+func (s *AnsibleService) EncryptVaultData(userID uint, vaultID string, data []byte) (string, error) {
+	secret, err := s.ownedVaultSecret(userID, vaultID)
+	if err != nil {
+		return "", err
+	}
+	password, err := s.openVaultPassword(secret.EncryptedPassword)
+	if err != nil {
+		return "", err
+	}
+	return VaultEncrypt(data, password, secret.Name)
+}
+
+// DecryptVaultData 解密vault数据，返回明文和解密使用的vault ID；
+// 未指定vault ID时优先使用密文中的vault ID，否则依次尝试当前用户的所有vault密码
+func (s *AnsibleService) DecryptVaultData(userID uint, vaultID string, vaulttext string) ([]byte, string, error) {
+	label, err := VaultLabel(vaulttext)
+	if err != nil {
+		return nil, "", err
+	}
+	
+	var candidates []VaultSecret
+	switch {
+	case vaultID != "":
+		secret, err := s.ownedVaultSecret(userID, vaultID)
+		if err != nil {
+			return nil, "", err
+		}
+		candidates = append(candidates, *secret)
+	case label != "":
+		if secret, err := s.ownedVaultSecret(userID, label); err == nil {
+			candidates = append(candidates, *secret)
+			break
+		}
+		fallthrough
+	default:
+		if err := s.db.Where("user_id = ?", userID).Order("name ASC").Find(&candidates).Error; err != nil {
+			return nil, "", err
+		}
+	}
+	
+	for _, secret := range candidates {
+		password, err := s.openVaultPassword(secret.EncryptedPassword)
+		if err != nil {
+			return nil, "", err
+		}
+		plaintext, err := VaultDecrypt(vaulttext, password)
+		if errors.Is(err, ErrVaultDecrypt) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		return plaintext, secret.Name, nil
+	}
+	return nil, "", ErrVaultDecrypt
+}
+
+// resolveVaultIDs 校验执行请求中的vault ID属于当前用户，返回去重排序后的名称
+func (s *AnsibleService) resolveVaultIDs(userID uint, names []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var resolved []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, err := s.ownedVaultSecret(userID, name); err != nil {
+			return nil, err
+		}
+		seen[name] = true
+		resolved = append(resolved, name)
+	}
+	sort.Strings(resolved)
+	return resolved, nil
+}
+
+// applyVaultPasswords 执行前解密vault密码并通过运行选项传给执行器，密码不会写入执行记录或队列
+func (s *AnsibleService) applyVaultPasswords(names []string, opts *RunOptions) error {
+	if len(names) == 0 {
+		return nil
+	}
+	
+	opts.VaultPasswords = make(map[string]string, len(names))
+	for _, name := range names {
+		var secret VaultSecret
+		if err := s.db.Where("name = ?", name).First(&secret).Error; err != nil {
+			return fmt.Errorf("%w: %s", ErrVaultSecretNotFound, name)
+		}
+		password, err := s.openVaultPassword(secret.EncryptedPassword)
+		if err != nil {
+			return err
+		}
+		opts.VaultPasswords[name] = password
+	}
+	return nil
+}
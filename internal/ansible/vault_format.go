@@ -0,0 +1,207 @@
+package ansible
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidVaultData = errors.New("invalid vault data")
+	ErrVaultDecrypt     = errors.New("vault decryption failed")
+)
+
+const (
+	// vaultHeaderPrefix ansible-vault密文的文件头
+	vaultHeaderPrefix = "$ANSIBLE_VAULT"
+	// vaultCipher ansible-vault唯一支持的加密算法
+	vaultCipher = "AES256"
+	// vaultDefaultID 不写入文件头的默认vault ID
+	vaultDefaultID = "default"
+	// vaultKDFIterations ansible-vault使用的PBKDF2迭代次数
+	vaultKDFIterations = 10000
+	// vaultLineWidth 密文每行的宽度
+	vaultLineWidth = 80
+	// vaultYAMLIndent encrypt_string输出中密文的缩进
+	vaultYAMLIndent = "          "
+)
+
+// vaultKeys 根据密码和salt派生AES密钥、HMAC密钥和CTR初始向量
+func vaultKeys(password string, salt []byte) (aesKey, hmacKey, iv []byte, err error) {
+	derived, err := pbkdf2.Key(sha256.New, password, salt, vaultKDFIterations, 32+32+aes.BlockSize)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return derived[:32], derived[32:64], derived[64:], nil
+}
+
+// VaultEncrypt 使用与ansible-vault相同的格式(AES256)加密数据，label不为空且不是default时使用1.2格式写入vault ID
+func VaultEncrypt(plaintext []byte, password, label string) (string, error) {
+	if password == "" {
+		return "", fmt.Errorf("vault password is empty")
+	}
+	
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generate salt failed: %v", err)
+	}
+	aesKey, hmacKey, iv, err := vaultKeys(password, salt)
+	if err != nil {
+		return "", fmt.Errorf("derive vault key failed: %v", err)
+	}
+	
+	// ansible-vault在CTR模式下仍使用PKCS7填充
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCTR(block, iv).XORKeyStream(ciphertext, padded)
+	
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(ciphertext)
+	
+	body := strings.Join([]string{hex.EncodeToString(salt), hex.EncodeToString(mac.Sum(nil)), hex.EncodeToString(ciphertext)}, "\n")
+	encoded := hex.EncodeToString([]byte(body))
+	
+	header := vaultHeaderPrefix + ";1.1;" + vaultCipher
+	if label != "" && label != vaultDefaultID {
+		header = vaultHeaderPrefix + ";1.2;" + vaultCipher + ";" + label
+	}
+	
+	var b strings.Builder
+	b.WriteString(header)
+	b.WriteString("\n")
+	for i := 0; i < len(encoded); i += vaultLineWidth {
+		end := i + vaultLineWidth
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		b.WriteString(encoded[i:end])
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// VaultLabel 返回密文文件头中的vault ID，1.1格式没有vault ID
+func VaultLabel(vaulttext string) (string, error) {
+	label, _, err := parseVaultText(vaulttext)
+	return label, err
+}
+
+// VaultDecrypt 解密ansible-vault格式的密文，密码错误或数据被修改时返回ErrVaultDecrypt
+func VaultDecrypt(vaulttext, password string) ([]byte, error) {
+	_, body, err := parseVaultText(vaulttext)
+	if err != nil {
+		return nil, err
+	}
+	
+	parts := strings.Split(string(body), "\n")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed payload", ErrInvalidVaultData)
+	}
+	salt, err1 := hex.DecodeString(parts[0])
+	expectedMAC, err2 := hex.DecodeString(parts[1])
+	ciphertext, err3 := hex.DecodeString(parts[2])
+	if err1 != nil || err2 != nil || err3 != nil || len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: malformed payload", ErrInvalidVaultData)
+	}
+	
+	aesKey, hmacKey, iv, err := vaultKeys(password, salt)
+	if err != nil {
+		return nil, fmt.Errorf("derive vault key failed: %v", err)
+	}
+	
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(ciphertext)
+	if !hmac.Equal(mac.Sum(nil), expectedMAC) {
+		return nil, ErrVaultDecrypt
+	}
+	
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, err
+	}
+	padded := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, iv).XORKeyStream(padded, ciphertext)
+	
+	padding := int(padded[len(padded)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(padded) {
+		return nil, fmt.Errorf("%w: invalid padding", ErrInvalidVaultData)
+	}
+	return padded[:len(padded)-padding], nil
+}
+
+// parseVaultText 解析密文的文件头和内容，允许密文来自YAML中带缩进的!vault块
+func parseVaultText(vaulttext string) (string, []byte, error) {
+	start := strings.Index(vaulttext, vaultHeaderPrefix)
+	if start < 0 {
+		return "", nil, fmt.Errorf("%w: missing %s header", ErrInvalidVaultData, vaultHeaderPrefix)
+	}
+	
+	lines := strings.Split(vaulttext[start:], "\n")
+	header := strings.Split(strings.TrimSpace(lines[0]), ";")
+	if len(header) < 3 || header[0] != vaultHeaderPrefix {
+		return "", nil, fmt.Errorf("%w: malformed header", ErrInvalidVaultData)
+	}
+	if header[2] != vaultCipher {
+		return "", nil, fmt.Errorf("%w: unsupported cipher: %s", ErrInvalidVaultData, header[2])
+	}
+	
+	var label string
+	switch header[1] {
+	case "1.1":
+	case "1.2":
+		if len(header) < 4 {
+			return "", nil, fmt.Errorf("%w: missing vault id", ErrInvalidVaultData)
+		}
+		label = header[3]
+	default:
+		return "", nil, fmt.Errorf("%w: unsupported version: %s", ErrInvalidVaultData, header[1])
+	}
+	
+	// 密文到第一个非十六进制行为止，之后可能是YAML中的其他内容
+	var encoded strings.Builder
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.Trim(line, "0123456789abcdefABCDEF") != "" {
+			break
+		}
+		encoded.WriteString(line)
+	}
+	body, err := hex.DecodeString(encoded.String())
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidVaultData, err)
+	}
+	return label, body, nil
+}
+
+// vaultYAML 生成与ansible-vault encrypt_string相同的YAML片段
+func vaultYAML(name, vaulttext string) string {
+	var b strings.Builder
+	if name != "" {
+		b.WriteString(name)
+		b.WriteString(": ")
+	}
+	b.WriteString("!vault |\n")
+	for _, line := range strings.Split(strings.TrimRight(vaulttext, "\n"), "\n") {
+		b.WriteString(vaultYAMLIndent)
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
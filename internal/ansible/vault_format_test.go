@@ -0,0 +1,156 @@
+package ansible
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// 以下密文按ansible-vault的VaultAES256格式(PBKDF2-SHA256 10000次、AES-256-CTR、PKCS7填充、HMAC-SHA256)
+// 使用openssl命令行生成，不依赖本包的实现，密码为vaultFixturePassword
+const (
+	vaultFixturePassword  = "ansible-vault-fixture"
+	vaultFixturePlaintext = "db_password: s3cr3t\n"
+	vaultFixtureBody      = `37333635373237363635373232643664363136653631363736353732323037363631373536633734
+3230373436353733373432303733363136633734323132310a323030313861383563396233663735
+33356532396135353132303263366537643666633431396161663331653532666130333765643731
+6361353938373330350a343063613766656435396531656130366362316262363733666639373631
+33623032666631373562313966633730643930393964663239643533306664313334
+`
+)
+
+func TestVaultDecryptFixture(t *testing.T) {
+	tests := []struct {
+		name      string
+		vaulttext string
+		label     string
+	}{
+		{"version 1.1", "$ANSIBLE_VAULT;1.1;AES256\n" + vaultFixtureBody, ""},
+		{"version 1.2 with vault id", "$ANSIBLE_VAULT;1.2;AES256;prod\n" + vaultFixtureBody, "prod"},
+		{"crlf line endings", strings.ReplaceAll("$ANSIBLE_VAULT;1.1;AES256\n"+vaultFixtureBody, "\n", "\r\n"), ""},
+		{"yaml block", vaultYAML("secret", "$ANSIBLE_VAULT;1.2;AES256;prod\n"+vaultFixtureBody) + "other: value\n", "prod"},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext, err := VaultDecrypt(tt.vaulttext, vaultFixturePassword)
+			if err != nil {
+				t.Fatalf("VaultDecrypt() error = %v", err)
+			}
+			if string(plaintext) != vaultFixturePlaintext {
+				t.Errorf("VaultDecrypt() = %q, want %q", plaintext, vaultFixturePlaintext)
+			}
+	
+			label, err := VaultLabel(tt.vaulttext)
+			if err != nil {
+				t.Fatalf("VaultLabel() error = %v", err)
+			}
+			if label != tt.label {
+				t.Errorf("VaultLabel() = %q, want %q", label, tt.label)
+			}
+		})
+	}
+}
+
+func TestVaultEncryptRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		plaintext []byte
+		label     string
+		header    string
+	}{
+		{"empty", []byte{}, "", "$ANSIBLE_VAULT;1.1;AES256"},
+		{"short", []byte("secret"), "", "$ANSIBLE_VAULT;1.1;AES256"},
+		{"block aligned", bytes.Repeat([]byte("a"), 32), "", "$ANSIBLE_VAULT;1.1;AES256"},
+		{"default vault id", []byte("secret"), vaultDefaultID, "$ANSIBLE_VAULT;1.1;AES256"},
+		{"vault id", []byte("key: value\nlist:\n  - 1\n"), "prod", "$ANSIBLE_VAULT;1.2;AES256;prod"},
+		{"binary and unicode", append([]byte("密码\x00\xff"), bytes.Repeat([]byte{0x10}, 100)...), "dev", "$ANSIBLE_VAULT;1.2;AES256;dev"},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vaulttext, err := VaultEncrypt(tt.plaintext, "p@ss word", tt.label)
+			if err != nil {
+				t.Fatalf("VaultEncrypt() error = %v", err)
+			}
+	
+			lines := strings.Split(strings.TrimSuffix(vaulttext, "\n"), "\n")
+			if lines[0] != tt.header {
+				t.Errorf("header = %q, want %q", lines[0], tt.header)
+			}
+			for _, line := range lines[1:] {
+				if len(line) == 0 || len(line) > vaultLineWidth {
+					t.Errorf("line length = %d, want 1..%d", len(line), vaultLineWidth)
+				}
+			}
+			if !strings.HasSuffix(vaulttext, "\n") {
+				t.Error("vaulttext does not end with a newline")
+			}
+	
+			plaintext, err := VaultDecrypt(vaulttext, "p@ss word")
+			if err != nil {
+				t.Fatalf("VaultDecrypt() error = %v", err)
+			}
+			if !bytes.Equal(plaintext, tt.plaintext) {
+				t.Errorf("VaultDecrypt() = %q, want %q", plaintext, tt.plaintext)
+			}
+	
+			if _, err := VaultDecrypt(vaulttext, "wrong"); !errors.Is(err, ErrVaultDecrypt) {
+				t.Errorf("VaultDecrypt() with wrong password error = %v, want ErrVaultDecrypt", err)
+			}
+		})
+	}
+}
+
+func TestVaultEncryptUsesRandomSalt(t *testing.T) {
+	first, err := VaultEncrypt([]byte("secret"), "password", "")
+	if err != nil {
+		t.Fatalf("VaultEncrypt() error = %v", err)
+	}
+	second, err := VaultEncrypt([]byte("secret"), "password", "")
+	if err != nil {
+		t.Fatalf("VaultEncrypt() error = %v", err)
+	}
+	if first == second {
+		t.Error("VaultEncrypt() returned identical vaulttext for two calls")
+	}
+	
+	if _, err := VaultEncrypt([]byte("secret"), "", ""); err == nil {
+		t.Error("VaultEncrypt() with empty password succeeded")
+	}
+}
+
+func TestVaultDecryptInvalid(t *testing.T) {
+	// 修改密文中的一个字节，HMAC校验失败
+	tampered := []byte("$ANSIBLE_VAULT;1.1;AES256\n" + vaultFixtureBody)
+	last := bytes.LastIndexByte(tampered[:len(tampered)-1], '\n') + 1
+	if tampered[last] == '3' {
+		tampered[last] = '6'
+	} else {
+		tampered[last] = '3'
+	}
+	
+	tests := []struct {
+		name      string
+		vaulttext string
+		want      error
+	}{
+		{"missing header", vaultFixtureBody, ErrInvalidVaultData},
+		{"malformed header", "$ANSIBLE_VAULT;1.1\n" + vaultFixtureBody, ErrInvalidVaultData},
+		{"unsupported cipher", "$ANSIBLE_VAULT;1.1;AES\n" + vaultFixtureBody, ErrInvalidVaultData},
+		{"unsupported version", "$ANSIBLE_VAULT;2.0;AES256\n" + vaultFixtureBody, ErrInvalidVaultData},
+		{"missing vault id", "$ANSIBLE_VAULT;1.2;AES256\n" + vaultFixtureBody, ErrInvalidVaultData},
+		{"odd length hex", "$ANSIBLE_VAULT;1.1;AES256\nabc\n", ErrInvalidVaultData},
+		{"malformed payload", "$ANSIBLE_VAULT;1.1;AES256\n616263\n", ErrInvalidVaultData},
+		{"tampered ciphertext", string(tampered), ErrVaultDecrypt},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := VaultDecrypt(tt.vaulttext, vaultFixturePassword); !errors.Is(err, tt.want) {
+				t.Errorf("VaultDecrypt() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
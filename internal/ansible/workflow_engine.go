@@ -0,0 +1,325 @@
+package ansible
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// workflowPollInterval 工作流引擎检查子执行状态的最长间隔
+const workflowPollInterval = 5 * time.Second
+
+// notifyWorkflows 通知工作流引擎重新检查运行中的工作流
+func (s *AnsibleService) notifyWorkflows() {
+	select {
+	case s.workflows <- struct{}{}:
+	default:
+	}
+}
+
+// workflowRunner 在子执行结束时推进运行中的工作流，并定期检查以处理遗漏的通知
+func (s *AnsibleService) workflowRunner() {
+	defer s.wg.Done()
+	
+	for {
+		s.advanceWorkflowRuns()
+	
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.workflows:
+		case <-time.After(workflowPollInterval):
+		}
+	}
+}
+
+// advanceWorkflowRuns 推进所有运行中的工作流
+func (s *AnsibleService) advanceWorkflowRuns() {
+	var ids []uint
+	if err := s.db.Model(&WorkflowRun{}).Where("status = ?", StatusRunning).Pluck("id", &ids).Error; err != nil {
+		log.Printf("Load running workflows failed: %v", err)
+		return
+	}
+	
+	for _, id := range ids {
+		if s.ctx.Err() != nil {
+			return
+		}
+		if err := s.advanceWorkflowRun(id); err != nil {
+			log.Printf("Advance workflow run %d failed: %v", id, err)
+		}
+	}
+}
+
+// advanceWorkflowRun 同步子执行的状态，启动上游已结束且满足边条件的节点，
+// 跳过没有边被触发的节点，所有节点结束后结束工作流运行
+func (s *AnsibleService) advanceWorkflowRun(id uint) error {
+	s.workflowMu.Lock()
+	defer s.workflowMu.Unlock()
+	
+	var run WorkflowRun
+	if err := s.db.Preload("Nodes").First(&run, id).Error; err != nil {
+		return err
+	}
+	if run.Status != StatusRunning {
+		return nil
+	}
+	
+	spec, err := parseWorkflowSpec(run.Spec)
+	if err != nil {
+		return err
+	}
+	
+	nodes := make(map[string]*WorkflowRunNode, len(run.Nodes))
+	for i := range run.Nodes {
+		node := &run.Nodes[i]
+		nodes[node.NodeID] = node
+		if node.Status == StatusRunning && node.ExecutionID != nil {
+			s.syncWorkflowNode(node)
+		}
+	}
+	
+	// 跳过的节点可能使下游节点也被跳过，重复检查直到没有变化
+	for changed := true; changed; {
+		changed = false
+		for _, specNode := range spec.Nodes {
+			node := nodes[specNode.ID]
+			if node == nil || node.Status != StatusPending {
+				continue
+			}
+			ready, fire := workflowNodeReady(spec.Edges, nodes, specNode.ID)
+			if !ready {
+				continue
+			}
+			changed = true
+			if fire {
+				s.startWorkflowNode(&run, spec, specNode, nodes)
+			} else {
+				s.finishWorkflowNode(node, WorkflowNodeSkipped, "")
+			}
+		}
+	}
+	
+	for _, node := range nodes {
+		if node.Status == StatusPending || node.Status == StatusRunning {
+			return nil
+		}
+	}
+	
+	status, message := workflowRunResult(spec, nodes)
+	now := time.Now()
+	return s.db.Model(&WorkflowRun{}).Where("id = ? AND status = ?", run.ID, StatusRunning).Updates(map[string]interface{}{
+		"status":   status,
+		"error":    message,
+		"end_time": &now,
+	}).Error
+}
+
+// syncWorkflowNode 根据子执行记录更新运行中节点的状态和产物
+func (s *AnsibleService) syncWorkflowNode(node *WorkflowRunNode) {
+	model, err := executionModel(node.ExecutionType)
+	if err != nil {
+		s.finishWorkflowNode(node, StatusFailed, err.Error())
+		return
+	}
+	
+	var record struct {
+		Status    string
+		Artifacts string
+	}
+	if err := s.db.Model(model).Where("id = ?", *node.ExecutionID).Take(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.finishWorkflowNode(node, StatusFailed, "execution record not found")
+		}
+		return
+	}
+	
+	var status string
+	switch record.Status {
+	case StatusPendingApproval, StatusPending, StatusRunning:
+		return
+	case StatusSuccess:
+		status = StatusSuccess
+	case StatusCancelled:
+		status = StatusCancelled
+	default:
+		// failed、timed_out和interrupted都按失败处理，触发failure边
+		status = StatusFailed
+	}
+	node.Artifacts = record.Artifacts
+	s.finishWorkflowNode(node, status, "")
+}
+
+// workflowNodeReady 判断节点的所有上游节点是否已结束，以及是否有边被触发；没有上游节点的节点直接执行
+func workflowNodeReady(edges []WorkflowEdge, nodes map[string]*WorkflowRunNode, id string) (ready bool, fire bool) {
+	parents := 0
+	for _, edge := range edges {
+		if edge.To != id {
+			continue
+		}
+		parents++
+	
+		parent := nodes[edge.From]
+		if parent == nil {
+			continue
+		}
+		switch parent.Status {
+		case StatusPending, StatusRunning:
+			return false, false
+		case StatusSuccess:
+			fire = fire || edge.Condition == WorkflowEdgeSuccess || edge.Condition == WorkflowEdgeAlways
+		case StatusFailed:
+			fire = fire || edge.Condition == WorkflowEdgeFailure || edge.Condition == WorkflowEdgeAlways
+		}
+	}
+	return true, parents == 0 || fire
+}
+
+// startWorkflowNode 提交节点的执行；变量优先级从低到高为节点变量、上游节点的产物、启动时传入的变量
+func (s *AnsibleService) startWorkflowNode(run *WorkflowRun, spec *WorkflowSpec, specNode WorkflowNode, nodes map[string]*WorkflowRunNode) {
+	node := nodes[specNode.ID]
+	
+	extraVars := make(map[string]interface{})
+	for name, value := range specNode.ExtraVars {
+		extraVars[name] = value
+	}
+	for _, ancestor := range workflowAncestors(spec.Edges, nodes, specNode.ID) {
+		var artifacts map[string]interface{}
+		if err := json.Unmarshal([]byte(ancestor.Artifacts), &artifacts); err != nil {
+			continue
+		}
+		for name, value := range artifacts {
+			extraVars[name] = value
+		}
+	}
+	if run.ExtraVars != "" {
+		var runVars map[string]interface{}
+		if err := json.Unmarshal([]byte(run.ExtraVars), &runVars); err == nil {
+			for name, value := range runVars {
+				extraVars[name] = value
+			}
+		}
+	}
+	
+	runID := run.ID
+	var executionID uint
+	var err error
+	switch specNode.Type {
+	case ExecutionTypeAdhoc:
+		var execution *AdhocExecution
+		execution, err = s.ExecuteAdhocCommand(s.ctx, run.UserID, &AdhocExecutionRequest{
+			Module:        specNode.Module,
+			Args:          specNode.Args,
+			Hosts:         specNode.Hosts,
+			InventoryID:   specNode.InventoryID,
+			ExtraVars:     extraVars,
+			WorkflowRunID: &runID,
+		})
+		if err == nil {
+			executionID = execution.ID
+		}
+	case ExecutionTypePlaybook:
+		var execution *PlaybookExecution
+		execution, err = s.ExecutePlaybook(s.ctx, run.UserID, specNode.PlaybookID, &PlaybookExecutionRequest{
+			InventoryID:   specNode.InventoryID,
+			ExtraVars:     extraVars,
+			Tags:          specNode.Tags,
+			SkipTags:      specNode.SkipTags,
+			WorkflowRunID: &runID,
+		})
+		if err == nil {
+			executionID = execution.ID
+		}
+	default:
+		err = fmt.Errorf("unsupported node type: %s", specNode.Type)
+	}
+	if err != nil {
+		s.finishWorkflowNode(node, StatusFailed, fmt.Sprintf("start node failed: %v", err))
+		return
+	}
+	
+	now := time.Now()
+	node.Status = StatusRunning
+	node.ExecutionID = &executionID
+	node.StartTime = &now
+	s.db.Model(&WorkflowRunNode{}).Where("id = ?", node.ID).Updates(map[string]interface{}{
+		"status":       StatusRunning,
+		"execution_id": executionID,
+		"start_time":   &now,
+	})
+}
+
+// workflowAncestors 返回节点的所有上游节点中产生了产物的节点，按结束时间排序，后结束的产物覆盖先结束的
+func workflowAncestors(edges []WorkflowEdge, nodes map[string]*WorkflowRunNode, id string) []*WorkflowRunNode {
+	seen := map[string]bool{id: true}
+	queue := []string{id}
+	var ancestors []*WorkflowRunNode
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, edge := range edges {
+			if edge.To != current || seen[edge.From] {
+				continue
+			}
+			seen[edge.From] = true
+			queue = append(queue, edge.From)
+			if node := nodes[edge.From]; node != nil && node.Artifacts != "" && node.EndTime != nil {
+				ancestors = append(ancestors, node)
+			}
+		}
+	}
+	
+	sort.SliceStable(ancestors, func(i, j int) bool {
+		return ancestors[i].EndTime.Before(*ancestors[j].EndTime)
+	})
+	return ancestors
+}
+
+// finishWorkflowNode 结束节点并保存状态
+func (s *AnsibleService) finishWorkflowNode(node *WorkflowRunNode, status, message string) {
+	now := time.Now()
+	node.Status = status
+	node.Error = message
+	node.EndTime = &now
+	s.db.Model(&WorkflowRunNode{}).Where("id = ?", node.ID).Updates(map[string]interface{}{
+		"status":    status,
+		"error":     message,
+		"artifacts": node.Artifacts,
+		"end_time":  &now,
+	})
+}
+
+// workflowRunResult 计算所有节点结束后工作流运行的状态：
+// 失败的节点没有failure或always出边处理时，或有节点被单独取消时，运行失败
+func workflowRunResult(spec *WorkflowSpec, nodes map[string]*WorkflowRunNode) (string, string) {
+	for _, specNode := range spec.Nodes {
+		node := nodes[specNode.ID]
+		if node == nil {
+			continue
+		}
+		switch node.Status {
+		case StatusCancelled:
+			return StatusFailed, fmt.Sprintf("node %s was cancelled", node.NodeID)
+		case StatusFailed:
+			handled := false
+			for _, edge := range spec.Edges {
+				if edge.From == node.NodeID && (edge.Condition == WorkflowEdgeFailure || edge.Condition == WorkflowEdgeAlways) {
+					handled = true
+				}
+			}
+			if !handled {
+				message := fmt.Sprintf("node %s failed", node.NodeID)
+				if node.Error != "" {
+					message += ": " + node.Error
+				}
+				return StatusFailed, message
+			}
+		}
+	}
+	return StatusSuccess, ""
+}
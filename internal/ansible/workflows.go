@@ -0,0 +1,394 @@
+package ansible
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidWorkflow 工作流定义校验失败
+var ErrInvalidWorkflow = errors.New("invalid workflow")
+
+// workflowNodeID 节点标识只允许安全字符
+var workflowNodeID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// CreateWorkflow 创建工作流
+func (s *AnsibleService) CreateWorkflow(userID uint, req *WorkflowRequest) (*Workflow, error) {
+	workflow := &Workflow{UserID: userID}
+	if err := s.applyWorkflowRequest(workflow, req); err != nil {
+		return nil, err
+	}
+	
+	if err := s.db.Create(workflow).Error; err != nil {
+		return nil, err
+	}
+	
+	return workflow, nil
+}
+
+// UpdateWorkflow 更新工作流，运行中的工作流使用启动时的快照，不受影响
+func (s *AnsibleService) UpdateWorkflow(id uint, userID uint, req *WorkflowRequest) (*Workflow, error) {
+	var workflow Workflow
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&workflow).Error; err != nil {
+		return nil, err
+	}
+	
+	if err := s.applyWorkflowRequest(&workflow, req); err != nil {
+		return nil, err
+	}
+	
+	if err := s.db.Save(&workflow).Error; err != nil {
+		return nil, err
+	}
+	
+	return &workflow, nil
+}
+
+// applyWorkflowRequest 校验请求并写入工作流字段
+func (s *AnsibleService) applyWorkflowRequest(workflow *Workflow, req *WorkflowRequest) error {
+	spec := &WorkflowSpec{Nodes: req.Nodes, Edges: req.Edges}
+	if spec.Edges == nil {
+		spec.Edges = []WorkflowEdge{}
+	}
+	if err := s.validateWorkflowSpec(workflow.UserID, spec); err != nil {
+		return err
+	}
+	
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("marshal workflow failed: %v", err)
+	}
+	
+	workflow.Name = req.Name
+	workflow.Description = req.Description
+	workflow.Spec = string(data)
+	workflow.Nodes = spec.Nodes
+	workflow.Edges = spec.Edges
+	return nil
+}
+
+// validateWorkflowSpec 校验节点和边，工作流必须是有向无环图；inventory按工作流创建者校验
+func (s *AnsibleService) validateWorkflowSpec(userID uint, spec *WorkflowSpec) error {
+	if len(spec.Nodes) == 0 {
+		return fmt.Errorf("%w: at least one node is required", ErrInvalidWorkflow)
+	}
+	
+	nodes := make(map[string]bool)
+	for i := range spec.Nodes {
+		node := &spec.Nodes[i]
+		if !workflowNodeID.MatchString(node.ID) {
+			return fmt.Errorf("%w: node %d has an invalid id %q", ErrInvalidWorkflow, i+1, node.ID)
+		}
+		if nodes[node.ID] {
+			return fmt.Errorf("%w: node id %q is used more than once", ErrInvalidWorkflow, node.ID)
+		}
+		nodes[node.ID] = true
+		if node.Name == "" {
+			node.Name = node.ID
+		}
+	
+		switch node.Type {
+		case ExecutionTypeAdhoc:
+			if err := ValidateAdhocRequest(&AdhocExecutionRequest{Module: node.Module, Args: node.Args, Hosts: node.Hosts}); err != nil {
+				return fmt.Errorf("%w: node %q: %v", ErrInvalidWorkflow, node.ID, err)
+			}
+			if err := s.checkModuleAllowed(userID, node.Module); err != nil {
+				return fmt.Errorf("%w: node %q: %v", ErrInvalidWorkflow, node.ID, err)
+			}
+			node.PlaybookID = 0
+			node.Tags, node.SkipTags = "", ""
+		case ExecutionTypePlaybook:
+			if node.PlaybookID == 0 {
+				return fmt.Errorf("%w: node %q: playbook_id is required", ErrInvalidWorkflow, node.ID)
+			}
+			if _, err := s.GetPlaybook(node.PlaybookID); err != nil {
+				return fmt.Errorf("%w: node %q: playbook %d not found", ErrInvalidWorkflow, node.ID, node.PlaybookID)
+			}
+			node.Module, node.Args, node.Hosts = "", "", ""
+		default:
+			return fmt.Errorf("%w: node %q has an unsupported type %q", ErrInvalidWorkflow, node.ID, node.Type)
+		}
+	
+		if node.InventoryID != 0 {
+			if _, err := s.resolveInventory(userID, node.InventoryID, "", "", nil); err != nil {
+				return fmt.Errorf("%w: node %q: inventory %d: %v", ErrInvalidWorkflow, node.ID, node.InventoryID, err)
+			}
+		}
+	}
+	
+	edges := make(map[[2]string]bool)
+	for _, edge := range spec.Edges {
+		if !nodes[edge.From] || !nodes[edge.To] {
+			return fmt.Errorf("%w: edge %s -> %s references an unknown node", ErrInvalidWorkflow, edge.From, edge.To)
+		}
+		if edge.From == edge.To {
+			return fmt.Errorf("%w: node %q cannot depend on itself", ErrInvalidWorkflow, edge.From)
+		}
+		switch edge.Condition {
+		case WorkflowEdgeSuccess, WorkflowEdgeFailure, WorkflowEdgeAlways:
+		default:
+			return fmt.Errorf("%w: edge %s -> %s has an unsupported condition %q", ErrInvalidWorkflow, edge.From, edge.To, edge.Condition)
+		}
+		key := [2]string{edge.From, edge.To}
+		if edges[key] {
+			return fmt.Errorf("%w: edge %s -> %s is defined more than once", ErrInvalidWorkflow, edge.From, edge.To)
+		}
+		edges[key] = true
+	}
+	
+	// 按拓扑顺序移除入度为0的节点，剩余节点说明存在环
+	inDegree := make(map[string]int)
+	for _, edge := range spec.Edges {
+		inDegree[edge.To]++
+	}
+	var queue []string
+	for _, node := range spec.Nodes {
+		if inDegree[node.ID] == 0 {
+			queue = append(queue, node.ID)
+		}
+	}
+	visited := 0
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, edge := range spec.Edges {
+			if edge.From != id {
+				continue
+			}
+			inDegree[edge.To]--
+			if inDegree[edge.To] == 0 {
+				queue = append(queue, edge.To)
+			}
+		}
+	}
+	if visited != len(spec.Nodes) {
+		return fmt.Errorf("%w: edges must not form a cycle", ErrInvalidWorkflow)
+	}
+	
+	return nil
+}
+
+// parseWorkflowSpec 解析保存的节点和边
+func parseWorkflowSpec(data string) (*WorkflowSpec, error) {
+	spec := &WorkflowSpec{}
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), spec); err != nil {
+			return nil, fmt.Errorf("unmarshal workflow failed: %v", err)
+		}
+	}
+	if spec.Nodes == nil {
+		spec.Nodes = []WorkflowNode{}
+	}
+	if spec.Edges == nil {
+		spec.Edges = []WorkflowEdge{}
+	}
+	return spec, nil
+}
+
+// fillWorkflowSpec 解析保存的节点和边用于返回
+func fillWorkflowSpec(workflow *Workflow) error {
+	spec, err := parseWorkflowSpec(workflow.Spec)
+	if err != nil {
+		return err
+	}
+	workflow.Nodes = spec.Nodes
+	workflow.Edges = spec.Edges
+	return nil
+}
+
+// DeleteWorkflow 删除工作流，已产生的运行记录保留
+func (s *AnsibleService) DeleteWorkflow(id uint, userID uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&Workflow{})
+	if result.Error != nil {
+		return result.Error
+	}
+	
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	
+	return nil
+}
+
+// GetWorkflow 获取工作流
+func (s *AnsibleService) GetWorkflow(id uint) (*Workflow, error) {
+	var workflow Workflow
+	err := s.db.First(&workflow, id).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := fillWorkflowSpec(&workflow); err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// ListWorkflows 列出工作流
+func (s *AnsibleService) ListWorkflows(userID uint, offset, limit int) ([]Workflow, int64, error) {
+	var workflows []Workflow
+	var total int64
+	
+	query := s.db.Model(&Workflow{}).Where("user_id = ?", userID)
+	
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	// 获取分页数据
+	err := query.Order("name ASC").Offset(offset).Limit(limit).Find(&workflows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	for i := range workflows {
+		if err := fillWorkflowSpec(&workflows[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	
+	return workflows, total, nil
+}
+
+// LaunchWorkflow 启动工作流，保存节点和边的快照后立即启动没有上游依赖的节点
+func (s *AnsibleService) LaunchWorkflow(id uint, userID uint, req *WorkflowLaunchRequest) (*WorkflowRun, error) {
+	var workflow Workflow
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&workflow).Error; err != nil {
+		return nil, err
+	}
+	
+	spec, err := parseWorkflowSpec(workflow.Spec)
+	if err != nil {
+		return nil, err
+	}
+	
+	now := time.Now()
+	run := &WorkflowRun{
+		WorkflowID: workflow.ID,
+		Name:       workflow.Name,
+		Status:     StatusRunning,
+		Spec:       workflow.Spec,
+		StartTime:  &now,
+		UserID:     userID,
+	}
+	if req.ExtraVars != nil {
+		extraVarsJSON, err := json.Marshal(req.ExtraVars)
+		if err != nil {
+			return nil, fmt.Errorf("marshal extra vars failed: %v", err)
+		}
+		run.ExtraVars = string(extraVarsJSON)
+	}
+	
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(run).Error; err != nil {
+			return fmt.Errorf("create workflow run failed: %v", err)
+		}
+		for _, node := range spec.Nodes {
+			if err := tx.Create(&WorkflowRunNode{
+				RunID:         run.ID,
+				NodeID:        node.ID,
+				Name:          node.Name,
+				ExecutionType: node.Type,
+				Status:        StatusPending,
+			}).Error; err != nil {
+				return fmt.Errorf("create workflow run node failed: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	if err := s.advanceWorkflowRun(run.ID); err != nil {
+		return nil, err
+	}
+	return s.GetWorkflowRun(run.ID)
+}
+
+// GetWorkflowRun 获取工作流运行及每个节点的状态
+func (s *AnsibleService) GetWorkflowRun(id uint) (*WorkflowRun, error) {
+	var run WorkflowRun
+	err := s.db.Preload("Nodes", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).First(&run, id).Error
+	if err != nil {
+		return nil, err
+	}
+	
+	spec, err := parseWorkflowSpec(run.Spec)
+	if err != nil {
+		return nil, err
+	}
+	run.Edges = spec.Edges
+	return &run, nil
+}
+
+// ListWorkflowRuns 列出工作流的运行记录
+func (s *AnsibleService) ListWorkflowRuns(workflowID uint, userID uint, offset, limit int) ([]WorkflowRun, int64, error) {
+	var runs []WorkflowRun
+	var total int64
+	
+	query := s.db.Model(&WorkflowRun{}).Where("workflow_id = ? AND user_id = ?", workflowID, userID)
+	
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	// 获取分页数据
+	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&runs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	return runs, total, nil
+}
+
+// CancelWorkflowRun 取消工作流运行，取消运行中的子执行，未启动的节点不再启动；只有启动运行的用户和管理员可以取消
+func (s *AnsibleService) CancelWorkflowRun(id uint, userID uint) error {
+	s.workflowMu.Lock()
+	defer s.workflowMu.Unlock()
+	
+	var run WorkflowRun
+	if err := s.db.First(&run, id).Error; err != nil {
+		return err
+	}
+	if err := s.checkOwnerOrAdmin(run.UserID, userID); err != nil {
+		return err
+	}
+	if run.Status != StatusRunning {
+		return ErrExecutionNotRunning
+	}
+	
+	now := time.Now()
+	if err := s.db.Model(&WorkflowRun{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       StatusCancelled,
+		"cancelled_by": userID,
+		"cancelled_at": &now,
+		"end_time":     &now,
+	}).Error; err != nil {
+		return err
+	}
+	
+	var nodes []WorkflowRunNode
+	if err := s.db.Where("run_id = ? AND status IN ?", id, []string{StatusPending, StatusRunning}).Find(&nodes).Error; err != nil {
+		return err
+	}
+	for i := range nodes {
+		node := &nodes[i]
+		if node.Status == StatusRunning && node.ExecutionID != nil {
+			if err := s.CancelExecution(node.ExecutionType, *node.ExecutionID, userID); err != nil && !errors.Is(err, ErrExecutionNotRunning) {
+				return fmt.Errorf("cancel node %s failed: %v", node.NodeID, err)
+			}
+		}
+		s.finishWorkflowNode(node, StatusCancelled, "")
+	}
+	
+	return nil
+}
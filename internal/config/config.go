@@ -47,8 +47,12 @@ type AnsibleConfig struct {
 	Path       string `yaml:"path"`        // Ansible命令路径
 	WorkDir    string `yaml:"work_dir"`    // 工作目录
 	TempDir    string `yaml:"temp_dir"`    // 临时文件目录
-	Timeout    int    `yaml:"timeout"`     // 命令执行超时时间（秒）
+	Timeout    int    `yaml:"timeout"`     // 命令执行默认超时时间（秒）
+	MaxTimeout int    `yaml:"max_timeout"` // 单次执行允许的最大超时时间（秒）
+	Workers    int    `yaml:"workers"`     // 并发执行的worker数量
 	Verbose    bool   `yaml:"verbose"`     // 是否启用详细输出
+	VaultKey   string `yaml:"vault_key"`   // 加密保存vault密码的密钥，为空时使用JWT密钥
+	ApprovalTimeout int `yaml:"approval_timeout"` // 涉及受保护对象的执行等待审批的期限（秒）
 }
 
 func Load() (*Config, error) {
@@ -79,11 +83,15 @@ func Load() (*Config, error) {
 			TokenDuration: getEnvAsInt("TOKEN_DURATION", 24),
 		},
 		Ansible: AnsibleConfig{
-			Path:    getEnv("ANSIBLE_PATH", ""),
-			WorkDir: getEnv("ANSIBLE_WORK_DIR", "./"),
-			TempDir: getEnv("ANSIBLE_TEMP_DIR", ""),
-			Timeout: getEnvAsInt("ANSIBLE_TIMEOUT", 30),
-			Verbose: getEnvAsBool("ANSIBLE_VERBOSE", true),
+			Path:       getEnv("ANSIBLE_PATH", ""),
+			WorkDir:    getEnv("ANSIBLE_WORK_DIR", "./"),
+			TempDir:    getEnv("ANSIBLE_TEMP_DIR", ""),
+			Timeout:    getEnvAsInt("ANSIBLE_TIMEOUT", 300),
+			MaxTimeout: getEnvAsInt("ANSIBLE_MAX_TIMEOUT", 3600),
+			Workers:    getEnvAsInt("ANSIBLE_WORKERS", 4),
+			Verbose:    getEnvAsBool("ANSIBLE_VERBOSE", true),
+			VaultKey:   getEnv("ANSIBLE_VAULT_KEY", ""),
+			ApprovalTimeout: getEnvAsInt("ANSIBLE_APPROVAL_TIMEOUT", 86400),
 		},
 	}
 
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"server-manager/internal/auth"
 	"server-manager/internal/config"
 	"server-manager/internal/middleware"
@@ -25,9 +26,10 @@ import (
 )
 
 type Server struct {
-	config *config.Config
-	router *gin.Engine
-	db     *gorm.DB
+	config         *config.Config
+	router         *gin.Engine
+	db             *gorm.DB
+	ansibleService *ansible.AnsibleService
 }
 
 func New(cfg *config.Config) *Server {
@@ -56,6 +58,11 @@ func (s *Server) Start() error {
 	// 设置路由
 	s.setupRoutes()
 
+	// 启动ansible执行队列
+	if err := s.ansibleService.Start(s.config.Ansible.Workers); err != nil {
+		return fmt.Errorf("failed to start ansible service: %w", err)
+	}
+
 	// 创建HTTP服务器
 	srv := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", s.config.Server.Host, s.config.Server.Port),
@@ -81,6 +88,11 @@ func (s *Server) Start() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// 先取消正在执行的ansible任务并等待结果保存，输出流随之结束
+	if err := s.ansibleService.Shutdown(ctx); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
 		return fmt.Errorf("server forced to shutdown: %v", err)
 	}
@@ -104,8 +116,26 @@ func (s *Server) initDatabase() error {
 		&server_manager.ServerGroup{},
 		&ansible.AdhocExecution{},
 		&ansible.PlaybookExecution{},
+		&ansible.ExecutionJob{},
+		&ansible.HostResult{},
+		&ansible.HostRecap{},
+		&ansible.TaskDiff{},
+		&ansible.Schedule{},
+		&ansible.JobTemplate{},
+		&ansible.Workflow{},
+		&ansible.WorkflowRun{},
+		&ansible.WorkflowRunNode{},
+		&ansible.AnsibleModule{},
+		&ansible.ModuleGrant{},
+		&ansible.PolicyRule{},
+		&ansible.PolicyAudit{},
+		&ansible.ExecutionApproval{},
 		&ansible.Inventory{},
+		&ansible.InventoryVars{},
 		&ansible.Playbook{},
+		&ansible.PlaybookRevision{},
+		&ansible.Project{},
+		&ansible.VaultSecret{},
 	); err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
@@ -180,8 +210,22 @@ func (s *Server) setupRoutes() {
 
 	// Ansible服务
 	ansibleExecutor := ansible.NewCommandExecutorWithConfig(s.config)
-	ansibleService := ansible.NewAnsibleService(s.db, ansibleExecutor)
-	ansibleHandler := ansible.NewHandler(ansibleService)
+	s.ansibleService = ansible.NewAnsibleService(s.db, ansibleExecutor)
+	s.ansibleService.SetProjectsDir(filepath.Join(s.config.Ansible.WorkDir, "projects"))
+	// vault密码使用独立密钥加密保存，未配置时回退到JWT密钥，修改密钥后已保存的密码无法解密
+	vaultKey := s.config.Ansible.VaultKey
+	if vaultKey == "" {
+		vaultKey = s.config.Auth.JWTSecret
+	}
+	s.ansibleService.SetVaultKey(vaultKey)
+	s.ansibleService.SetApprovalTimeout(time.Duration(s.config.Ansible.ApprovalTimeout) * time.Second)
+	if err := s.ansibleService.EnsureDefaultModules(); err != nil {
+		log.Printf("Warning: Failed to create default ansible modules: %v", err)
+	}
+	if err := s.ansibleService.EnsurePlaybookRevisions(); err != nil {
+		log.Printf("Warning: Failed to create initial playbook revisions: %v", err)
+	}
+	ansibleHandler := ansible.NewHandler(s.ansibleService)
 
 	// API v1 routes
 	v1 := s.router.Group("/api/v1")
@@ -218,6 +262,8 @@ func (s *Server) setupRoutes() {
 			}
 
 			c.Set("user", claims)
+			c.Set("user_id", claims.UserID)
+			c.Set("role", claims.Role)
 			c.Next()
 		})
 		{
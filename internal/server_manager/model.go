@@ -30,6 +30,7 @@ type ServerGroup struct {
 	Name        string    `gorm:"size:100;not null;uniqueIndex" json:"name" binding:"required"`
 	Description string    `gorm:"size:500" json:"description"`
 	Color       string    `gorm:"size:7;default:#3b82f6" json:"color"` // 组颜色
+	Protected   bool      `gorm:"default:false" json:"protected"` // 受保护，涉及组内服务器的ansible执行需要审批
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	DeletedAt   *time.Time `gorm:"index" json:"deleted_at,omitempty"`
@@ -103,6 +104,7 @@ type ServerGroupResponse struct {
 	Name        string           `json:"name"`
 	Description string           `json:"description"`
 	Color       string           `json:"color"`
+	Protected   bool             `json:"protected"`
 	ServerCount int              `json:"server_count,omitempty"`
 	Servers     []ServerResponse `json:"servers,omitempty"`
 	CreatedAt   time.Time        `json:"created_at"`
@@ -158,6 +160,7 @@ func (sg *ServerGroup) ToResponse() *ServerGroupResponse {
 		Name:        sg.Name,
 		Description: sg.Description,
 		Color:       sg.Color,
+		Protected:   sg.Protected,
 		CreatedAt:   sg.CreatedAt,
 		UpdatedAt:   sg.UpdatedAt,
 	}